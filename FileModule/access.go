@@ -0,0 +1,114 @@
+package FileModule
+
+import (
+	"context"
+
+	"github.com/yyboo586/common/MiddleWare"
+)
+
+// AccessRequest 下载授权请求
+type AccessRequest struct {
+	// User 上下文中的当前用户，未登录时为空
+	User *MiddleWare.ContextUser
+	// File 请求下载的文件，Module/CustomID/Type 为其主关联
+	File *FileInfo
+	// Variant 请求的衍生图规格，原文件为空
+	Variant string
+}
+
+// AccessPolicy 下载授权策略，返回 nil 表示允许，返回错误(通常为 ErrAccessDenied)表示拒绝
+type AccessPolicy func(ctx context.Context, req *AccessRequest) error
+
+// DefaultAccessPolicy 默认授权策略：上传者本人或同组织用户可下载，未登录用户一律拒绝
+// 未记录上传者与组织的文件(历史数据、系统文件)无法判断归属，一律拒绝；
+// 历史文件需要保持可下载时开启 Config.AllowOwnerlessAccess，需要开放的模块通过 RegisterAccessPolicy 注册策略
+func DefaultAccessPolicy(ctx context.Context, req *AccessRequest) error {
+	if req.User == nil || req.User.UserID == "" {
+		return ErrAccessDenied
+	}
+
+	file := req.File
+	switch {
+	case file.OwnerID != "" && file.OwnerID == req.User.UserID:
+		return nil
+	case file.OrgID != "" && file.OrgID == req.User.OrgID:
+		return nil
+	default:
+		return ErrAccessDenied
+	}
+}
+
+// RegisterAccessPolicy 注册下载授权策略，module/typ 为 0 表示匹配任意值
+// 按文件主关联的模块/类型匹配，未匹配到时使用 DefaultAccessPolicy；仅在开启 EnableAccessControl 时生效
+func (m *FileManager) RegisterAccessPolicy(module FileModule, typ FileType, policy AccessPolicy) {
+	m.accessMutex.Lock()
+	defer m.accessMutex.Unlock()
+
+	if m.accessPolicies == nil {
+		m.accessPolicies = make(map[moduleTypeKey]AccessPolicy)
+	}
+	m.accessPolicies[moduleTypeKey{module: module, typ: typ}] = policy
+}
+
+// getAccessPolicy 返回 module/typ 注册的授权策略，未注册时返回 DefaultAccessPolicy，registered 为 false
+func (m *FileManager) getAccessPolicy(module FileModule, typ FileType) (policy AccessPolicy, registered bool) {
+	m.accessMutex.RLock()
+	defer m.accessMutex.RUnlock()
+
+	for _, key := range lookupKeys(module, typ) {
+		if policy := m.accessPolicies[key]; policy != nil {
+			return policy, true
+		}
+	}
+	return DefaultAccessPolicy, false
+}
+
+// ownerlessAccessPolicy 开启 AllowOwnerlessAccess 时未记录上传者与组织的文件的授权策略：登录用户均可下载
+func ownerlessAccessPolicy(ctx context.Context, req *AccessRequest) error {
+	if req.User == nil || req.User.UserID == "" {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// checkAccess 签发下载链接或读取内容前校验当前用户是否有权访问文件
+func (m *FileManager) checkAccess(ctx context.Context, info *FileInfo, variant string) error {
+	err := m.authorize(ctx, info, variant)
+	if err != nil {
+		m.logger.Infof(ctx, "download access denied, fileID: %s, err: %v", info.FileID, err)
+		return err
+	}
+	return nil
+}
+
+// authorize 按授权策略校验当前用户，未开启 EnableAccessControl 时不校验
+func (m *FileManager) authorize(ctx context.Context, info *FileInfo, variant string) error {
+	if !m.config.EnableAccessControl {
+		return nil
+	}
+
+	req := &AccessRequest{File: info, Variant: variant}
+	if user, err := MiddleWare.GetContextUser(ctx); err == nil {
+		req.User = user
+	}
+
+	policy, registered := m.getAccessPolicy(info.Module, info.Type)
+	if !registered && m.config.AllowOwnerlessAccess && info.OwnerID == "" && info.OrgID == "" {
+		policy = ownerlessAccessPolicy
+	}
+	return policy(ctx, req)
+}
+
+// fillOwner 未指定上传者时使用上下文中的当前用户，并记录其所属组织
+func fillOwner(ctx context.Context, info *FileInfo) {
+	user, err := MiddleWare.GetContextUser(ctx)
+	if err != nil || user == nil {
+		return
+	}
+	if info.OwnerID == "" {
+		info.OwnerID = user.UserID
+	}
+	if info.OrgID == "" && info.OwnerID == user.UserID {
+		info.OrgID = user.OrgID
+	}
+}
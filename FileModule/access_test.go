@@ -0,0 +1,129 @@
+package FileModule
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/yyboo586/common/MiddleWare"
+)
+
+func Test_DefaultAccessPolicy(t *testing.T) {
+	ctx := context.Background()
+	file := &FileInfo{FileID: "f1", OwnerID: "u1", OrgID: "org1"}
+
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(DefaultAccessPolicy(ctx, &AccessRequest{File: file}), ErrAccessDenied)
+		t.AssertNil(DefaultAccessPolicy(ctx, &AccessRequest{File: file, User: &MiddleWare.ContextUser{UserID: "u1"}}))
+		t.AssertNil(DefaultAccessPolicy(ctx, &AccessRequest{File: file, User: &MiddleWare.ContextUser{UserID: "u2", OrgID: "org1"}}))
+		t.Assert(DefaultAccessPolicy(ctx, &AccessRequest{File: file, User: &MiddleWare.ContextUser{UserID: "u3", OrgID: "org2"}}), ErrAccessDenied)
+
+		// 未记录上传者与组织的文件无法判断归属，默认拒绝
+		t.Assert(DefaultAccessPolicy(ctx, &AccessRequest{File: &FileInfo{FileID: "f2"}, User: &MiddleWare.ContextUser{UserID: "u3"}}), ErrAccessDenied)
+	})
+}
+
+func Test_CheckAccess(t *testing.T) {
+	m := &FileManager{logger: glog.New(), config: &Config{EnableAccessControl: true}}
+	file := &FileInfo{FileID: "f1", Module: 1, Type: 2, OwnerID: "u1"}
+	userCtx := context.WithValue(context.Background(), MiddleWare.CustomCtxKey, &MiddleWare.ContextUser{UserID: "u2"})
+
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(m.checkAccess(userCtx, file, ""), ErrAccessDenied)
+
+		// 按模块注册的策略优先于默认策略
+		m.RegisterAccessPolicy(1, 0, func(ctx context.Context, req *AccessRequest) error {
+			if req.User != nil && req.User.UserID == "u2" {
+				return nil
+			}
+			return ErrAccessDenied
+		})
+		t.AssertNil(m.checkAccess(userCtx, file, ""))
+		t.Assert(m.checkAccess(context.Background(), file, ""), ErrAccessDenied)
+
+		m.config.EnableAccessControl = false
+		t.AssertNil(m.checkAccess(context.Background(), file, ""))
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 未记录上传者与组织的历史文件默认拒绝，开启 AllowOwnerlessAccess 后登录用户可下载
+		m := &FileManager{logger: glog.New(), config: &Config{EnableAccessControl: true}}
+		legacy := &FileInfo{FileID: "f2", Module: 1, Type: 2}
+		t.Assert(m.checkAccess(userCtx, legacy, ""), ErrAccessDenied)
+
+		m.config.AllowOwnerlessAccess = true
+		t.AssertNil(m.checkAccess(userCtx, legacy, ""))
+		t.Assert(m.checkAccess(context.Background(), legacy, ""), ErrAccessDenied)
+		t.Assert(m.checkAccess(userCtx, file, ""), ErrAccessDenied)
+
+		// 注册了访问策略的模块由策略自行决定
+		m.RegisterAccessPolicy(1, 0, func(ctx context.Context, req *AccessRequest) error { return ErrAccessDenied })
+		t.Assert(m.checkAccess(userCtx, legacy, ""), ErrAccessDenied)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// DefaultConfig 与零值配置的访问控制默认值一致
+		t.Assert(DefaultConfig().EnableAccessControl, (&Config{}).EnableAccessControl)
+	})
+}
+
+func Test_Download_Access(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), MiddleWare.CustomCtxKey, &MiddleWare.ContextUser{UserID: "u2"})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 服务端下载同样校验访问权限，无权访问时不读取存储
+		m, mock := newMockFileManager(t.T, &Config{EnableAccessControl: true}, nil)
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusUploadSuccess), OwnerID: "u1"}))
+
+		_, _, err := m.Download(userCtx, "f1")
+		t.Assert(err, ErrAccessDenied)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+func Test_Search_Access(t *testing.T) {
+	userCtx := context.WithValue(context.Background(), MiddleWare.CustomCtxKey, &MiddleWare.ContextUser{UserID: "u1"})
+	owners := []string{"u1", "u2", "u1", "", "u1", "u2", "u1"}
+
+	expectSearch := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery("SELECT COUNT").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(len(owners)))
+		files := make([]*FileInfoEntity, 0, len(owners))
+		for i, owner := range owners {
+			files = append(files, &FileInfoEntity{ID: int64(i + 1), FileID: fmt.Sprintf("f%d", i+1), Status: int(FileStatusUploadSuccess), OwnerID: owner, IsCurrent: 1})
+		}
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows(files...))
+	}
+
+	cases := []struct {
+		name    string
+		page    int
+		size    int
+		fileIDs []string
+	}{
+		{name: "first page", page: 1, size: 2, fileIDs: []string{"f1", "f3"}},
+		{name: "second page", page: 2, size: 2, fileIDs: []string{"f5", "f7"}},
+		{name: "past the end", page: 3, size: 2, fileIDs: []string{}},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			// 只返回有权访问的文件，总数同样只统计有权访问的文件
+			m, mock := newMockFileManager(t.T, &Config{EnableAccessControl: true}, nil)
+			expectSearch(mock)
+
+			out, total, err := m.Search(userCtx, &FileSearchFilter{Page: c.page, Size: c.size})
+			t.AssertNil(err)
+			t.Assert(total, 4)
+			fileIDs := make([]string, 0, len(out))
+			for _, info := range out {
+				fileIDs = append(fileIDs, info.FileID)
+			}
+			t.Assert(fileIDs, c.fileIDs)
+			t.AssertNil(mock.ExpectationsWereMet())
+		}
+	})
+}
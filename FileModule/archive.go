@@ -0,0 +1,217 @@
+package FileModule
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+const archiveManifestName = "manifest.json"
+
+// RegisterArchiveFolder 注册文件类型在归档导出中的目录名，未注册的类型使用 type_<类型值>
+func (m *FileManager) RegisterArchiveFolder(typ FileType, folder string) error {
+	if folder == "" || sanitizeArchiveName(folder) != folder {
+		return gerror.Newf("invalid archive folder name: %q", folder)
+	}
+
+	m.archiveMutex.Lock()
+	defer m.archiveMutex.Unlock()
+
+	if m.archiveFolders == nil {
+		m.archiveFolders = make(map[FileType]string)
+	}
+	m.archiveFolders[typ] = folder
+	return nil
+}
+
+func (m *FileManager) archiveFolder(typ FileType) string {
+	m.archiveMutex.RLock()
+	defer m.archiveMutex.RUnlock()
+
+	if folder, ok := m.archiveFolders[typ]; ok {
+		return folder
+	}
+	return fmt.Sprintf("type_%d", typ)
+}
+
+// ExportArchive 将业务实体关联的全部上传成功的文件(当前版本)打包为 zip 写入 w
+// 目录结构为 <CustomID>/<类型目录>/<文件名>，同名文件追加序号；根目录的 manifest.json 记录导出与跳过的文件
+// 文件内容从存储流式写入，不在内存中缓存；开启 EnableAccessControl 时跳过当前用户无权访问的文件
+// 写入过程中出错时返回错误，已写入 w 的内容不是完整的压缩包
+func (m *FileManager) ExportArchive(ctx context.Context, module FileModule, customIDs []string, w io.Writer) (err error) {
+	customIDs = dedupeStrings(customIDs)
+
+	var files map[string][]*FileInfo
+	if len(customIDs) > 0 {
+		files, err = m.dao.ListByModuleAndCustomIDs(ctx, module, customIDs, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	manifest := &ArchiveManifest{
+		Module:     module,
+		CustomIDs:  customIDs,
+		ExportTime: time.Now(),
+		Files:      make([]*ArchiveEntry, 0),
+		Skipped:    make([]*ArchiveEntry, 0),
+	}
+
+	allowed := make([]*FileInfo, 0)
+	storageIDs := make([]string, 0)
+	for _, customID := range customIDs {
+		for _, info := range files[customID] {
+			if info.Status != FileStatusUploadSuccess {
+				continue
+			}
+			if m.checkAccess(ctx, info, "") != nil {
+				manifest.Skipped = append(manifest.Skipped, newArchiveEntry(info, "", "access denied"))
+				continue
+			}
+			allowed = append(allowed, info)
+			storageIDs = append(storageIDs, info.StorageFileID())
+		}
+	}
+
+	urls, err := m.issueDownloadURLs(ctx, storageIDs)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	used := map[string]bool{archiveManifestName: true}
+	for _, info := range allowed {
+		dir := path.Join(sanitizeArchiveName(info.CustomID), m.archiveFolder(info.Type))
+		name := uniqueArchivePath(used, dir, sanitizeArchiveName(info.FileName), info.FileID)
+
+		body, err := m.openArchiveContent(ctx, info, urls[info.StorageFileID()])
+		if err != nil {
+			m.logger.Warningf(ctx, "export archive skip file, fileID: %s, err: %v", info.FileID, err)
+			manifest.Skipped = append(manifest.Skipped, newArchiveEntry(info, "", err.Error()))
+			continue
+		}
+
+		err = writeArchiveFile(zw, name, info.UpdateTime, body)
+		body.Close()
+		if err != nil {
+			return gerror.Wrapf(err, "export archive write file %s failed", info.FileID)
+		}
+		manifest.Files = append(manifest.Files, newArchiveEntry(info, name, ""))
+	}
+
+	manifestWriter, err := zw.CreateHeader(&zip.FileHeader{Name: archiveManifestName, Method: zip.Deflate, Modified: manifest.ExportTime})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(manifestWriter)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(manifest)
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// openArchiveContent 打开文件内容，预签发的下载链接缺失或已过期时重新获取
+func (m *FileManager) openArchiveContent(ctx context.Context, info *FileInfo, preDownload *PreDownloadRes) (body io.ReadCloser, err error) {
+	if preDownload != nil {
+		body, _, err = m.fileEngine.GetContent(ctx, preDownload.DownloadURL)
+		if err == nil || !errors.Is(err, ErrFileExpired) {
+			return body, err
+		}
+		m.evictDownloadURL(ctx, info.StorageFileID())
+	}
+
+	preDownload, err = m.fileEngine.PreDownload(ctx, info.StorageFileID())
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err = m.fileEngine.GetContent(ctx, preDownload.DownloadURL)
+	return body, err
+}
+
+func writeArchiveFile(zw *zip.Writer, name string, modified time.Time, body io.Reader) error {
+	writer, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, body)
+	return err
+}
+
+func newArchiveEntry(info *FileInfo, name string, reason string) *ArchiveEntry {
+	return &ArchiveEntry{
+		Path:        name,
+		CustomID:    info.CustomID,
+		Type:        info.Type,
+		FileID:      info.FileID,
+		FileName:    info.FileName,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		SHA256:      info.SHA256,
+		Version:     info.Version,
+		Reason:      reason,
+	}
+}
+
+// sanitizeArchiveName 将文件名或目录名转换为压缩包内安全的单级名称(去除路径分隔符与控制字符)
+func sanitizeArchiveName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':':
+			return '_'
+		case unicode.IsControl(r):
+			return -1
+		default:
+			return r
+		}
+	}, name)
+
+	name = strings.TrimSpace(name)
+	if name == "." || name == ".." {
+		return ""
+	}
+	return name
+}
+
+// uniqueArchivePath 返回目录下未使用的路径，名称为空时使用 fallback，重名时追加序号，如 a (2).txt
+// 按不区分大小写判断重名，避免在大小写不敏感的文件系统中解压时相互覆盖
+func uniqueArchivePath(used map[string]bool, dir string, name string, fallback string) string {
+	if name == "" {
+		name = fallback
+	}
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+
+	candidate := path.Join(dir, name)
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = path.Join(dir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+// dedupeStrings 去除重复项，保持原有顺序
+func dedupeStrings(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := make([]string, 0, len(in))
+	for _, s := range in {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
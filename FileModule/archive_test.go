@@ -0,0 +1,156 @@
+package FileModule
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+// newArchiveHandler 模拟存储引擎: 批量签发下载链接，按存储文件ID返回内容，不在 contents 中的文件返回 404
+func newArchiveHandler(t *testing.T, contents map[string]string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		baseURL := "http://" + r.Host
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/download-tokens"):
+			var req struct {
+				FileIDs []string `json:"file_ids"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&req)
+			files := make([]string, 0, len(req.FileIDs))
+			for _, id := range req.FileIDs {
+				files = append(files, fmt.Sprintf(`{"id":"%s","download_url":"%s/storage/%s","expires_in":600}`, id, baseURL, id))
+			}
+			_, _ = fmt.Fprintf(w, `{"files":[%s]}`, strings.Join(files, ","))
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/storage/"):
+			content, ok := contents[strings.TrimPrefix(r.URL.Path, "/storage/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = io.WriteString(w, content)
+		default:
+			t.Errorf("unexpected engine request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func Test_ArchivePath(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(sanitizeArchiveName("../../etc/passwd"), ".._.._etc_passwd")
+		t.Assert(sanitizeArchiveName("a\\b\x00.txt"), "a_b.txt")
+		t.Assert(sanitizeArchiveName(".."), "")
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		used := map[string]bool{archiveManifestName: true}
+		t.Assert(uniqueArchivePath(used, "c1/license", "a.pdf", "f1"), "c1/license/a.pdf")
+		t.Assert(uniqueArchivePath(used, "c1/license", "A.pdf", "f2"), "c1/license/A (2).pdf")
+		t.Assert(uniqueArchivePath(used, "c1/license", "a.pdf", "f3"), "c1/license/a (3).pdf")
+		t.Assert(uniqueArchivePath(used, "c1/license", "", "f4"), "c1/license/f4")
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		m := &FileManager{}
+		t.Assert(m.archiveFolder(3), "type_3")
+		t.AssertNil(m.RegisterArchiveFolder(3, "license"))
+		t.Assert(m.archiveFolder(3), "license")
+		t.AssertNE(m.RegisterArchiveFolder(4, "a/b"), nil)
+	})
+}
+
+func Test_ExportArchive(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		// 同名文件追加序号，未上传成功的文件不导出，读取内容失败的文件记入 Skipped
+		m, mock := newMockFileManager(t.T, nil, newArchiveHandler(t.T, map[string]string{"f1": "one", "f2": "two"}))
+		success := int(FileStatusUploadSuccess)
+		mock.ExpectQuery("SELECT .* FROM t_file f INNER JOIN").WillReturnRows(fileRows(
+			&FileInfoEntity{ID: 1, Module: 1, CustomID: "c1", FileID: "f1", FileName: "a.txt", Status: success, Size: 3},
+			&FileInfoEntity{ID: 2, Module: 1, CustomID: "c1", FileID: "f2", FileName: "a.txt", Status: success, Size: 3},
+			&FileInfoEntity{ID: 3, Module: 1, CustomID: "c1", FileID: "f3", FileName: "b.txt", Status: int(FileStatusInit)},
+			&FileInfoEntity{ID: 4, Module: 1, CustomID: "c2", Type: 2, FileID: "f4", FileName: "c.txt", Status: success},
+		))
+
+		var buf bytes.Buffer
+		t.AssertNil(m.ExportArchive(ctx, 1, []string{"c1", "c2", "c1"}, &buf))
+		t.AssertNil(mock.ExpectationsWereMet())
+
+		reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		t.AssertNil(err)
+		entries := make(map[string]string, len(reader.File))
+		names := make([]string, 0, len(reader.File))
+		for _, file := range reader.File {
+			body, err := file.Open()
+			t.AssertNil(err)
+			content, err := io.ReadAll(body)
+			t.AssertNil(err)
+			body.Close()
+			entries[file.Name] = string(content)
+			names = append(names, file.Name)
+		}
+		t.Assert(names, []string{"c1/type_0/a.txt", "c1/type_0/a (2).txt", archiveManifestName})
+		t.Assert(entries["c1/type_0/a.txt"], "one")
+		t.Assert(entries["c1/type_0/a (2).txt"], "two")
+
+		var manifest ArchiveManifest
+		t.AssertNil(json.Unmarshal([]byte(entries[archiveManifestName]), &manifest))
+		t.Assert(manifest.Module, 1)
+		t.Assert(manifest.CustomIDs, []string{"c1", "c2"})
+		t.Assert(len(manifest.Files), 2)
+		t.Assert(manifest.Files[0].FileID, "f1")
+		t.Assert(manifest.Files[0].Path, "c1/type_0/a.txt")
+		t.Assert(manifest.Files[1].FileID, "f2")
+		t.Assert(manifest.Files[1].Path, "c1/type_0/a (2).txt")
+		t.Assert(len(manifest.Skipped), 1)
+		t.Assert(manifest.Skipped[0].FileID, "f4")
+		t.Assert(manifest.Skipped[0].Path, "")
+		t.AssertNE(manifest.Skipped[0].Reason, "")
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 开启访问控制时跳过无权访问的文件，不向存储签发其下载链接
+		m, mock := newMockFileManager(t.T, &Config{EnableAccessControl: true}, newArchiveHandler(t.T, nil))
+		mock.ExpectQuery("SELECT .* FROM t_file f INNER JOIN").WillReturnRows(fileRows(
+			&FileInfoEntity{ID: 1, Module: 1, CustomID: "c1", FileID: "f1", FileName: "a.txt", Status: int(FileStatusUploadSuccess), OwnerID: "u1"},
+		))
+
+		var buf bytes.Buffer
+		t.AssertNil(m.ExportArchive(ctx, 1, []string{"c1"}, &buf))
+
+		reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		t.AssertNil(err)
+		t.Assert(len(reader.File), 1)
+		body, err := reader.File[0].Open()
+		t.AssertNil(err)
+		var manifest ArchiveManifest
+		t.AssertNil(json.NewDecoder(body).Decode(&manifest))
+		body.Close()
+		t.Assert(len(manifest.Files), 0)
+		t.Assert(len(manifest.Skipped), 1)
+		t.Assert(manifest.Skipped[0].Reason, "access denied")
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 不传业务实体时不查询数据库，只写入空清单
+		m, mock := newMockFileManager(t.T, nil, nil)
+
+		var buf bytes.Buffer
+		t.AssertNil(m.ExportArchive(ctx, 1, nil, &buf))
+		t.AssertNil(mock.ExpectationsWereMet())
+
+		reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		t.AssertNil(err)
+		t.Assert(len(reader.File), 1)
+		t.Assert(reader.File[0].Name, archiveManifestName)
+	})
+}
@@ -0,0 +1,76 @@
+package FileModule
+
+import (
+	"context"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// AttachFile 关联文件与业务实体，引用数加一；重复关联不做处理
+// 关联建立在逻辑文件上，文件的新版本自动继承
+// 目标模块/类型注册了上传策略时，按策略校验文件声明信息、存储中的实际内容及上传者配额
+func (m *FileManager) AttachFile(ctx context.Context, tx gdb.TX, fileID string, module FileModule, customID string, typ FileType) (err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	// 关联到的模块/类型可能与上传时声明的不同，按目标的上传策略重新校验
+	err = m.checkDeclared(module, typ, info.FileName, info.ContentType, info.Size)
+	if err != nil {
+		return err
+	}
+	err = m.checkStoredContent(ctx, info, module, typ)
+	if err != nil {
+		return err
+	}
+
+	attach := func(ctx context.Context, tx gdb.TX) error {
+		// 文件已计入的用量不重复统计
+		err := m.checkQuota(ctx, tx, module, typ, info.Size, info.OwnerID, info.LogicalFileID())
+		if err != nil {
+			return err
+		}
+
+		attached, err := m.dao.AttachFile(ctx, tx, info.LogicalFileID(), module, customID, typ)
+		if err != nil || !attached {
+			return err
+		}
+
+		// 已上传成功的文件关联类型后，按类型生成衍生图
+		info.Module, info.CustomID, info.Type = module, customID, typ
+		return m.enqueueVariants(ctx, tx, info)
+	}
+	if tx != nil {
+		return attach(ctx, tx)
+	}
+	return m.dao.Transaction(ctx, attach)
+}
+
+// DetachFile 解除文件与业务实体的关联，引用数减一
+// 引用数降为 0 的文件在宽限期后由垃圾回收删除，仍被其他业务实体引用的文件不会被删除
+func (m *FileManager) DetachFile(ctx context.Context, tx gdb.TX, fileID string, module FileModule, customID string, typ FileType) (err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	detached, err := m.dao.DetachFile(ctx, tx, info.LogicalFileID(), module, customID, typ)
+	if err != nil {
+		return err
+	}
+	if !detached {
+		return ErrFileNotFound
+	}
+	return nil
+}
+
+// ListAssociations 获取文件的全部关联
+func (m *FileManager) ListAssociations(ctx context.Context, fileID string) (out []*FileAssociation, err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.dao.ListAssociations(ctx, info.LogicalFileID())
+}
@@ -0,0 +1,170 @@
+package FileModule
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+// fileCompatColumns t_file 兼容旧表需要补齐的字段，顺序与 EnsureTable 一致
+var fileCompatColumns = []string{
+	"storage_id", "content_type", "size", "sha256", "expected_sha256", "owner_id", "org_id",
+	"ref_count", "require_ref", "logical_id", "version", "is_current", "delete_time", "scan_attempts",
+}
+
+func Test_EnsureTable_Backfill(t *testing.T) {
+	cases := []struct {
+		name          string
+		missing       []string
+		backfill      []string
+		uniqueVersion int
+	}{
+		{name: "up to date", missing: nil, backfill: nil, uniqueVersion: 1},
+		{name: "before unique version", missing: nil, backfill: nil},
+		{
+			name:     "before associations",
+			missing:  []string{"ref_count", "require_ref"},
+			backfill: []string{"INSERT IGNORE INTO t_file_association", "UPDATE t_file SET ref_count = 1", "UPDATE t_file SET require_ref = 1"},
+		},
+		{
+			name:     "before require_ref",
+			missing:  []string{"require_ref"},
+			backfill: []string{"UPDATE t_file SET require_ref = 1"},
+		},
+		{
+			name:     "before versions",
+			missing:  []string{"logical_id", "version", "is_current"},
+			backfill: []string{"UPDATE t_file SET logical_id = file_id"},
+		},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			m, mock := newMockFileManager(t.T, nil, nil)
+
+			mock.ExpectExec("CREATE TABLE IF NOT EXISTS t_file ").WillReturnResult(sqlmock.NewResult(0, 0))
+			for _, column := range fileCompatColumns {
+				count := 1
+				if slices.Contains(c.missing, column) {
+					count = 0
+				}
+				mock.ExpectQuery("information_schema.COLUMNS").WithArgs("t_file", column).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+				if count == 0 {
+					mock.ExpectExec("ALTER TABLE t_file ADD COLUMN " + column).WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+			}
+			backfill := c.backfill
+			expectBackfill := func(prefix string) {
+				if len(backfill) > 0 && backfill[0] == prefix {
+					mock.ExpectExec(backfill[0]).WillReturnResult(sqlmock.NewResult(0, 1))
+					backfill = backfill[1:]
+				}
+			}
+			expectBackfill("UPDATE t_file SET logical_id = file_id")
+			// 版本号唯一索引替换旧的普通索引
+			mock.ExpectQuery("information_schema.STATISTICS").WithArgs("t_file", "idx_logical_id_version").
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(c.uniqueVersion))
+			if c.uniqueVersion == 0 {
+				mock.ExpectQuery("information_schema.STATISTICS").WithArgs("t_file", "idx_logical_id").
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectExec("ALTER TABLE t_file DROP INDEX idx_logical_id, ADD UNIQUE KEY idx_logical_id_version").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+			mock.ExpectExec("CREATE TABLE IF NOT EXISTS t_file_association").WillReturnResult(sqlmock.NewResult(0, 0))
+			expectBackfill("INSERT IGNORE INTO t_file_association")
+			expectBackfill("UPDATE t_file SET ref_count = 1")
+			expectBackfill("UPDATE t_file SET require_ref = 1")
+			t.Assert(len(backfill), 0)
+			for _, table := range []string{"t_file_multipart", "t_file_multipart_part", "t_file_variant", "t_file_meta", "t_file_tag", "t_file_outbox"} {
+				mock.ExpectExec("CREATE TABLE IF NOT EXISTS " + table).WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			t.AssertNil(m.EnsureTable())
+			t.AssertNil(mock.ExpectationsWereMet())
+		}
+	})
+}
+
+func Test_AttachFile_RefCount(t *testing.T) {
+	cases := []struct {
+		name     string
+		inserted int64
+		updated  int64
+		attached bool
+		err      error
+	}{
+		{name: "new association", inserted: 1, updated: 2, attached: true},
+		{name: "duplicate association", inserted: 0, attached: false},
+		{name: "file not found", inserted: 1, updated: 0, err: ErrFileNotFound},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			m, mock := newMockFileManager(t.T, nil, nil)
+			mock.ExpectExec("INSERT IGNORE INTO t_file_association").WillReturnResult(sqlmock.NewResult(1, c.inserted))
+			if c.inserted > 0 {
+				// 逻辑文件的全部版本引用数加一
+				mock.ExpectExec("UPDATE t_file SET .*ref_count=ref_count \\+ 1.*WHERE logical_id = \\?$").
+					WillReturnResult(sqlmock.NewResult(0, c.updated))
+			}
+			if c.attached {
+				mock.ExpectExec("UPDATE t_file SET .*WHERE \\(logical_id = \\?\\) AND \\(custom_id = ''\\)").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			attached, err := m.dao.AttachFile(context.Background(), nil, "l1", 1, "c1", 2)
+			t.Assert(attached, c.attached)
+			t.Assert(err, c.err)
+			t.AssertNil(mock.ExpectationsWereMet())
+		}
+	})
+}
+
+func Test_DetachFile_RefCount(t *testing.T) {
+	cases := []struct {
+		name      string
+		deleted   int64
+		remaining *FileAssociationEntity
+		detached  bool
+		primary   []any
+	}{
+		{name: "not associated", deleted: 0, detached: false},
+		{name: "last association", deleted: 1, detached: true, primary: []any{0, "", 0}},
+		{
+			name:      "other association remains",
+			deleted:   1,
+			remaining: &FileAssociationEntity{FileID: "l1", Module: 3, CustomID: "c2", Type: 4},
+			detached:  true,
+			primary:   []any{3, "c2", 4},
+		},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			m, mock := newMockFileManager(t.T, nil, nil)
+			mock.ExpectExec("DELETE FROM t_file_association").WillReturnResult(sqlmock.NewResult(0, c.deleted))
+			if c.deleted > 0 {
+				// 引用数减一且不小于 0
+				mock.ExpectExec("UPDATE t_file SET .*GREATEST\\(ref_count - 1, 0\\)").WillReturnResult(sqlmock.NewResult(0, 2))
+				rows := sqlmock.NewRows([]string{"id", "file_id", "module", "custom_id", "type", "create_time"})
+				if c.remaining != nil {
+					rows.AddRow(1, c.remaining.FileID, c.remaining.Module, c.remaining.CustomID, c.remaining.Type, 0)
+				}
+				mock.ExpectQuery("SELECT .* FROM t_file_association").WillReturnRows(rows)
+				// 主关联改为剩余的关联
+				mock.ExpectExec("UPDATE t_file SET").
+					WithArgs(c.primary[0], c.primary[1], c.primary[2], "l1", 1, "c1", 2).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			detached, err := m.dao.DetachFile(context.Background(), nil, "l1", 1, "c1", 2)
+			t.AssertNil(err)
+			t.Assert(detached, c.detached)
+			t.AssertNil(mock.ExpectationsWereMet())
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package FileModule
+
+import (
+	"context"
+)
+
+// moduleTypeKey 按业务模块/文件类型注册的键(回调、上传策略)，Module/Type 为 0 时表示匹配任意值
+type moduleTypeKey struct {
+	module FileModule
+	typ    FileType
+}
+
+// lookupKeys 按优先级返回匹配的注册键：精确匹配、模块通配类型、全局通配，重复的键只保留一个
+func lookupKeys(module FileModule, typ FileType) []moduleTypeKey {
+	keys := make([]moduleTypeKey, 0, 3)
+	for _, key := range []moduleTypeKey{{module: module, typ: typ}, {module: module, typ: 0}, {module: 0, typ: 0}} {
+		if len(keys) > 0 && keys[len(keys)-1] == key {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// RegisterUploadCallback 注册上传完成回调
+// module/typ 传 0 表示匹配任意模块/类型；回调在状态落库后同步执行，耗时操作请自行异步处理
+func (m *FileManager) RegisterUploadCallback(module FileModule, typ FileType, callback UploadCallback) {
+	m.callbackMutex.Lock()
+	defer m.callbackMutex.Unlock()
+
+	if m.callbacks == nil {
+		m.callbacks = make(map[moduleTypeKey][]UploadCallback)
+	}
+	key := moduleTypeKey{module: module, typ: typ}
+	m.callbacks[key] = append(m.callbacks[key], callback)
+}
+
+// fireUploadCallbacks 触发与文件模块/类型匹配的回调
+func (m *FileManager) fireUploadCallbacks(ctx context.Context, fileID string, success bool) {
+	m.callbackMutex.RLock()
+	empty := len(m.callbacks) == 0
+	m.callbackMutex.RUnlock()
+	if empty {
+		return
+	}
+
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		m.logger.Errorf(ctx, "get file for upload callback failed, fileID: %s, err: %v", fileID, err)
+		return
+	}
+
+	m.callbackMutex.RLock()
+	var callbacks []UploadCallback
+	for _, key := range lookupKeys(info.Module, info.Type) {
+		callbacks = append(callbacks, m.callbacks[key]...)
+	}
+	m.callbackMutex.RUnlock()
+
+	event := &UploadEvent{File: info, Success: success}
+	for _, callback := range callbacks {
+		m.runUploadCallback(ctx, callback, event)
+	}
+}
+
+func (m *FileManager) runUploadCallback(ctx context.Context, callback UploadCallback, event *UploadEvent) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.logger.Errorf(ctx, "upload callback panic, fileID: %s, err: %v", event.File.FileID, r)
+		}
+	}()
+
+	callback(ctx, event)
+}
@@ -1,19 +1,126 @@
 package FileModule
 
+import (
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/yyboo586/common/cacheUtils"
+)
+
 // Config FileModule配置
 type Config struct {
+	// 已有的数据库连接，设置后忽略 DSN/Group
+	DB gdb.DB
+
 	// 数据库DSN，格式: mysql:user:password@tcp(host:port)/database?parseTime=true
+	// 每个管理器独立创建连接，不修改全局数据库配置
 	DSN string
 
 	// 数据库名称
 	Database string
 
-	// 数据库分组名，默认为"default"
+	// 数据库分组名，DB 与 DSN 均为空时使用框架配置中的该分组，默认为"default"
 	Group string
 
+	// 文件表名，默认为"t_file"；分片、衍生图、关联等附属表以其为前缀
+	TableName string
+
 	// 文件引擎服务地址
 	FileEngineAddr string
 
+	// 文件引擎幂等请求(查询、删除、获取下载链接等)失败时的最大重试次数，默认2次，小于0表示不重试
+	EngineMaxRetry int
+
+	// 文件引擎请求重试的初始退避时间，每次重试翻倍，默认200毫秒
+	EngineRetryBackoff time.Duration
+
+	// 服务端直传(Upload)允许的最大文件大小(字节)，0 表示不限制
+	MaxUploadSize int64
+
+	// 服务端直传/下载时访问存储的超时时间，0 表示仅受 ctx 控制
+	TransferTimeout time.Duration
+
+	// 内容扫描器，设置后上传成功的文件需扫描通过才标记为上传成功，发现威胁的文件被隔离
+	Scanner Scanner
+
+	// 内容扫描任务间隔，默认10秒，小于0表示不启动；未设置扫描器时任务不访问数据库
+	ScanInterval time.Duration
+
+	// 单个文件内容扫描的最大尝试次数，默认8次；扫描失败后按间隔指数退避重试，仍失败时标记上传失败
+	ScanMaxAttempts int
+
+	// 是否在签发下载链接前校验访问权限(按 RegisterAccessPolicy 注册的策略，默认上传者/同组织可访问)
+	// 默认关闭(DefaultConfig 与零值一致)，开启前确认历史文件的访问方式，见 AllowOwnerlessAccess
+	EnableAccessControl bool
+
+	// 开启访问控制时，是否允许登录用户下载未记录上传者与组织的文件(开启访问控制之前上传的历史文件)
+	// 默认拒绝；升级后需要历史文件保持可下载时开启，或为历史文件补录 owner_id/org_id；对注册了访问策略的模块不生效
+	AllowOwnerlessAccess bool
+
+	// 下载链接缓存，为空时不缓存，每次向文件引擎申请
+	DownloadURLCache cacheUtils.ICache
+
+	// 下载链接在过期前多久失效缓存，默认1分钟
+	DownloadURLCacheMargin time.Duration
+
+	// 分片上传默认分片大小(字节)，默认8MB
+	MultipartPartSize int64
+
+	// 分片上传会话有效期，超过后视为废弃并被清理，默认24小时
+	MultipartExpire time.Duration
+
+	// 废弃分片上传清理间隔，默认1小时，小于0表示不启动清理任务
+	MultipartCleanupInterval time.Duration
+
+	// 孤儿文件垃圾回收间隔，0 表示不启动回收任务
+	GCInterval time.Duration
+
+	// 孤儿文件宽限期，未关联/初始化/上传失败且超过该时长未更新的文件会被回收，默认24小时
+	GCGracePeriod time.Duration
+
+	// 垃圾回收是否仅生成报告不删除
+	GCDryRun bool
+
+	// 垃圾回收时保留墓碑记录(状态置为Deleted)而不是删除 t_file 记录
+	GCTombstone bool
+
+	// 回收站保留时长，删除的文件超过该时长后彻底删除，默认7天
+	TrashRetention time.Duration
+
+	// 回收站清理间隔，默认1小时，小于0表示不启动清理任务
+	TrashPurgeInterval time.Duration
+
+	// 上传状态对账间隔，定期向文件引擎确认处于初始化状态的文件，0 表示不启动对账任务
+	ReconcileInterval time.Duration
+
+	// 对账延迟，创建超过该时长的初始化文件才参与对账，默认1分钟
+	ReconcileDelay time.Duration
+
+	// 上传完成回调(webhook)签名密钥，为空时不校验签名
+	WebhookSecret string
+
+	// 衍生图生成任务间隔，默认10秒，小于0表示不启动；未注册规格时任务不访问数据库
+	VariantInterval time.Duration
+
+	// 衍生图源文件最大大小(字节)，默认20MB
+	VariantMaxSourceSize int64
+
+	// 衍生图源图片最大像素数，用于防止解压炸弹，默认4000万
+	VariantMaxPixels int
+
+	// 衍生图生成最大重试次数，默认3次
+	VariantMaxRetry int
+
+	// 存储副作用事件(删除存储文件、上报上传结果)的执行任务间隔，默认5秒，小于0表示不启动
+	OutboxInterval time.Duration
+
+	// 存储副作用事件最大执行次数，超过后标记为放弃，默认10次
+	OutboxMaxAttempts int
+
+	// 存储副作用事件重试的初始退避时间，每次失败翻倍，最长1小时，默认10秒
+	OutboxRetryBackoff time.Duration
+
 	// 是否开启调试模式
 	EnableDebug bool
 }
@@ -21,6 +128,98 @@ type Config struct {
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
-		Group: "default",
+		Group:                    "default",
+		TableName:                "t_file",
+		EngineMaxRetry:           2,
+		EngineRetryBackoff:       200 * time.Millisecond,
+		DownloadURLCacheMargin:   time.Minute,
+		MultipartPartSize:        8 << 20,
+		MultipartExpire:          24 * time.Hour,
+		MultipartCleanupInterval: time.Hour,
+		GCGracePeriod:            24 * time.Hour,
+		TrashRetention:           7 * 24 * time.Hour,
+		TrashPurgeInterval:       time.Hour,
+		ReconcileDelay:           time.Minute,
+		ScanInterval:             10 * time.Second,
+		ScanMaxAttempts:          8,
+		VariantInterval:          10 * time.Second,
+		VariantMaxSourceSize:     20 << 20,
+		VariantMaxPixels:         40000000,
+		VariantMaxRetry:          3,
+		OutboxInterval:           5 * time.Second,
+		OutboxMaxAttempts:        10,
+		OutboxRetryBackoff:       10 * time.Second,
+	}
+}
+
+// Validate 校验配置并补全未设置的配置项
+func (c *Config) Validate() error {
+	if c.FileEngineAddr == "" {
+		return gerror.Wrap(ErrInvalidConfig, "FileEngineAddr is required")
+	}
+	if c.Group == "" {
+		c.Group = "default"
+	}
+	if c.TableName == "" {
+		c.TableName = "t_file"
+	}
+	if c.EngineMaxRetry == 0 {
+		c.EngineMaxRetry = 2
+	}
+	if c.EngineRetryBackoff <= 0 {
+		c.EngineRetryBackoff = 200 * time.Millisecond
+	}
+	if c.DownloadURLCacheMargin <= 0 {
+		c.DownloadURLCacheMargin = time.Minute
+	}
+	if c.MultipartPartSize <= 0 {
+		c.MultipartPartSize = 8 << 20
+	}
+	if c.MultipartExpire <= 0 {
+		c.MultipartExpire = 24 * time.Hour
+	}
+	if c.MultipartCleanupInterval == 0 {
+		c.MultipartCleanupInterval = time.Hour
 	}
+	if c.GCGracePeriod <= 0 {
+		c.GCGracePeriod = 24 * time.Hour
+	}
+	if c.TrashRetention <= 0 {
+		c.TrashRetention = 7 * 24 * time.Hour
+	}
+	if c.TrashPurgeInterval == 0 {
+		c.TrashPurgeInterval = time.Hour
+	}
+	if c.ReconcileDelay <= 0 {
+		c.ReconcileDelay = time.Minute
+	}
+	if c.ScanInterval == 0 {
+		c.ScanInterval = 10 * time.Second
+	}
+	if c.ScanMaxAttempts <= 0 {
+		c.ScanMaxAttempts = 8
+	}
+	if c.VariantInterval == 0 {
+		c.VariantInterval = 10 * time.Second
+	}
+	if c.VariantMaxSourceSize <= 0 {
+		c.VariantMaxSourceSize = 20 << 20
+	}
+	if c.VariantMaxPixels <= 0 {
+		c.VariantMaxPixels = 40000000
+	}
+	if c.VariantMaxRetry <= 0 {
+		c.VariantMaxRetry = 3
+	}
+	if c.OutboxInterval == 0 {
+		c.OutboxInterval = 5 * time.Second
+	}
+	if c.OutboxMaxAttempts <= 0 {
+		c.OutboxMaxAttempts = 10
+	}
+	if c.OutboxRetryBackoff <= 0 {
+		c.OutboxRetryBackoff = 10 * time.Second
+	}
+
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/gogf/gf/v2/database/gdb"
@@ -13,26 +14,33 @@ import (
 
 // fileManagerDAO 数据访问对象
 type fileManagerDAO struct {
-	group     string
-	tableName string
-	db        gdb.DB
-	ctx       context.Context
+	group              string
+	tableName          string
+	multipartTableName string
+	partTableName      string
+	variantTableName   string
+	assocTableName     string
+	metaTableName      string
+	tagTableName       string
+	outboxTableName    string
+	db                 gdb.DB
+	ctx                context.Context
 }
 
-// newDAO 创建DAO实例
+// newFileManagerDAO 创建DAO实例
+// 优先使用配置中的数据库连接，其次按 DSN 创建独立连接，最后使用框架配置中的分组
 func newFileManagerDAO(ctx context.Context, config *Config) (*fileManagerDAO, error) {
-	// 设置数据库配置
-	gdb.SetConfig(gdb.Config{
-		config.Group: gdb.ConfigGroup{
-			gdb.ConfigNode{
-				Link: config.DSN,
-			},
-		},
-	})
-
-	db := g.DB(config.Group)
+	db := config.DB
 	if db == nil {
-		return nil, gerror.Newf("failed to get database instance for group: %s", config.Group)
+		var err error
+		if config.DSN != "" {
+			db, err = gdb.New(gdb.ConfigNode{Link: config.DSN})
+		} else {
+			db, err = gdb.NewByGroup(config.Group)
+		}
+		if err != nil {
+			return nil, gerror.Wrapf(err, "failed to get database instance for group: %s", config.Group)
+		}
 	}
 
 	if config.EnableDebug {
@@ -40,62 +48,279 @@ func newFileManagerDAO(ctx context.Context, config *Config) (*fileManagerDAO, er
 	}
 
 	dao := &fileManagerDAO{
-		group:     config.Group,
-		tableName: "t_file",
-		db:        db,
-		ctx:       ctx,
+		group:              config.Group,
+		tableName:          config.TableName,
+		multipartTableName: config.TableName + "_multipart",
+		partTableName:      config.TableName + "_multipart_part",
+		variantTableName:   config.TableName + "_variant",
+		assocTableName:     config.TableName + "_association",
+		metaTableName:      config.TableName + "_meta",
+		tagTableName:       config.TableName + "_tag",
+		outboxTableName:    config.TableName + "_outbox",
+		db:                 db,
+		ctx:                ctx,
 	}
 
 	return dao, nil
 }
 
+// model 返回文件表的 Model，tx 为空时不使用事务
+func (d *fileManagerDAO) model(ctx context.Context, tx gdb.TX) *gdb.Model {
+	model := d.db.Model(d.tableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	return model
+}
+
+// Transaction 在事务中执行 f，f 返回错误时回滚
+func (d *fileManagerDAO) Transaction(ctx context.Context, f func(ctx context.Context, tx gdb.TX) error) error {
+	return d.db.Transaction(ctx, f)
+}
+
 // EnsureTable 确保表存在，不存在则创建
 func (d *fileManagerDAO) EnsureTable() error {
 	// 创建文件表
-	createTableSQL :=
-		`
-CREATE TABLE IF NOT EXISTS t_file (
+	createTableSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
     id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
-    module TINYINT(1) DEFAULT 0 COMMENT '业务模块',
-    custom_id VARCHAR(40) DEFAULT '' COMMENT '业务自定义ID',
-    type TINYINT(1) DEFAULT 0 COMMENT '文件类型',
+    module TINYINT(1) DEFAULT 0 COMMENT '业务模块(主关联)',
+    custom_id VARCHAR(40) DEFAULT '' COMMENT '业务自定义ID(主关联)',
+    type TINYINT(1) DEFAULT 0 COMMENT '文件类型(主关联)',
 
     file_id VARCHAR(40) NOT NULL COMMENT '文件ID',
     file_orininal_name VARCHAR(255) NOT NULL COMMENT '文件原始名称',
     file_link TEXT COMMENT '文件链接',
-    status TINYINT(1) DEFAULT 0 COMMENT '文件状态(0:初始化,1:上传成功,2:上传失败)',
+    status TINYINT(1) DEFAULT 0 COMMENT '文件状态(0:初始化,1:上传成功,2:上传失败,3:已删除,4:已隔离,5:扫描中)',
+
+    storage_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT '存储文件ID(秒传时指向已存在的文件)',
+    content_type VARCHAR(255) NOT NULL DEFAULT '' COMMENT '文件MIME类型',
+    size BIGINT(20) NOT NULL DEFAULT 0 COMMENT '文件大小(字节)',
+    sha256 CHAR(64) NOT NULL DEFAULT '' COMMENT '文件内容SHA-256(已校验)',
+    expected_sha256 CHAR(64) NOT NULL DEFAULT '' COMMENT '上传方声明的SHA-256',
+    owner_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT '上传者ID',
+    org_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT '上传者所属组织ID',
+    ref_count INT(11) NOT NULL DEFAULT 0 COMMENT '业务关联引用数',
+    require_ref TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否由业务关联管理生命周期(1:引用数为0时回收)',
+    logical_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT '逻辑文件ID(同一文件的各版本共享)',
+    version INT(11) NOT NULL DEFAULT 1 COMMENT '版本号',
+    is_current TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否为当前版本',
+    delete_time BIGINT(20) NOT NULL DEFAULT 0 COMMENT '移入回收站时间(0:未删除)',
+    scan_attempts INT(11) NOT NULL DEFAULT 0 COMMENT '内容扫描失败次数',
     
 	create_time BIGINT(20) NOT NULL COMMENT '创建时间',
     update_time BIGINT(20) COMMENT '更新时间',
     PRIMARY KEY (id),
     KEY idx_module_id_type (module, custom_id, type),
+    KEY idx_sha256_size (sha256, size),
+    KEY idx_storage_id (storage_id),
+    KEY idx_owner_id (owner_id),
+    UNIQUE KEY idx_logical_id_version (logical_id, version),
+    KEY idx_delete_time (delete_time),
     UNIQUE KEY idx_file_id_status (file_id, status)
 ) ENGINE=InnoDB COMMENT='文件信息表';
-`
+`, d.tableName)
 
 	_, err := d.db.Exec(d.ctx, createTableSQL)
 	if err != nil {
 		return fmt.Errorf("failed to create table: %w", err)
 	}
 
+	// 兼容已存在的旧表
+	columns := []tableColumn{
+		{name: "storage_id", definition: "VARCHAR(40) NOT NULL DEFAULT '' COMMENT '存储文件ID(秒传时指向已存在的文件)'", index: "KEY idx_storage_id (storage_id)"},
+		{name: "content_type", definition: "VARCHAR(255) NOT NULL DEFAULT '' COMMENT '文件MIME类型'"},
+		{name: "size", definition: "BIGINT(20) NOT NULL DEFAULT 0 COMMENT '文件大小(字节)'"},
+		{name: "sha256", definition: "CHAR(64) NOT NULL DEFAULT '' COMMENT '文件内容SHA-256(已校验)'", index: "KEY idx_sha256_size (sha256, size)"},
+		{name: "expected_sha256", definition: "CHAR(64) NOT NULL DEFAULT '' COMMENT '上传方声明的SHA-256'"},
+		{name: "owner_id", definition: "VARCHAR(40) NOT NULL DEFAULT '' COMMENT '上传者ID'", index: "KEY idx_owner_id (owner_id)"},
+		{name: "org_id", definition: "VARCHAR(40) NOT NULL DEFAULT '' COMMENT '上传者所属组织ID'"},
+		{name: "ref_count", definition: "INT(11) NOT NULL DEFAULT 0 COMMENT '业务关联引用数'"},
+		{name: "require_ref", definition: "TINYINT(1) NOT NULL DEFAULT 0 COMMENT '是否由业务关联管理生命周期(1:引用数为0时回收)'"},
+		{name: "logical_id", definition: "VARCHAR(40) NOT NULL DEFAULT '' COMMENT '逻辑文件ID(同一文件的各版本共享)'", index: "KEY idx_logical_id (logical_id)"},
+		{name: "version", definition: "INT(11) NOT NULL DEFAULT 1 COMMENT '版本号'"},
+		{name: "is_current", definition: "TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否为当前版本'"},
+		{name: "delete_time", definition: "BIGINT(20) NOT NULL DEFAULT 0 COMMENT '移入回收站时间(0:未删除)'", index: "KEY idx_delete_time (delete_time)"},
+		{name: "scan_attempts", definition: "INT(11) NOT NULL DEFAULT 0 COMMENT '内容扫描失败次数'"},
+	}
+	added, err := d.ensureColumns(d.tableName, columns)
+	if err != nil {
+		return err
+	}
+
+	// 旧数据每个文件即一个逻辑文件
+	if slices.Contains(added, "logical_id") {
+		_, err = d.db.Exec(d.ctx, fmt.Sprintf("UPDATE %s SET logical_id = file_id WHERE logical_id = ''", d.tableName))
+		if err != nil {
+			return fmt.Errorf("failed to backfill logical_id: %w", err)
+		}
+	}
+
+	// 同一逻辑文件的版本号唯一，替代旧表上的普通索引
+	err = d.ensureIndex(d.tableName, tableIndex{
+		name:       "idx_logical_id_version",
+		definition: "UNIQUE KEY idx_logical_id_version (logical_id, version)",
+		replaces:   "idx_logical_id",
+	})
+	if err != nil {
+		return err
+	}
+
+	err = d.ensureAssociationTable(slices.Contains(added, "ref_count"))
+	if err != nil {
+		return err
+	}
+
+	// 旧数据中声明了业务模块或已关联的文件由业务关联管理
+	if slices.Contains(added, "require_ref") {
+		_, err = d.db.Exec(d.ctx, fmt.Sprintf("UPDATE %s SET require_ref = 1 WHERE module != 0 OR custom_id != '' OR ref_count > 0", d.tableName))
+		if err != nil {
+			return fmt.Errorf("failed to backfill require_ref: %w", err)
+		}
+	}
+
+	err = d.ensureMultipartTables()
+	if err != nil {
+		return err
+	}
+
+	err = d.ensureVariantTable()
+	if err != nil {
+		return err
+	}
+
+	err = d.ensureMetaTables()
+	if err != nil {
+		return err
+	}
+
+	return d.ensureOutboxTable()
+}
+
+// tableColumn 需要补齐的表字段，index 不为空时随字段一起创建索引
+type tableColumn struct {
+	name       string
+	definition string
+	index      string
+}
+
+// ensureColumns 为已存在的表补齐缺失字段，返回本次新增的字段
+func (d *fileManagerDAO) ensureColumns(table string, columns []tableColumn) (added []string, err error) {
+	for _, column := range columns {
+		count, err := d.db.GetCount(d.ctx,
+			"SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+			table, column.name)
+		if err != nil {
+			return added, fmt.Errorf("failed to check column %s.%s: %w", table, column.name, err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		alterSQL := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column.name, column.definition)
+		if column.index != "" {
+			alterSQL += ", ADD " + column.index
+		}
+		_, err = d.db.Exec(d.ctx, alterSQL)
+		if err != nil {
+			return added, fmt.Errorf("failed to add column %s.%s: %w", table, column.name, err)
+		}
+		added = append(added, column.name)
+	}
+
+	return added, nil
+}
+
+// tableIndex 需要补齐的表索引，replaces 不为空时同时删除被替代的旧索引
+type tableIndex struct {
+	name       string
+	definition string
+	replaces   string
+}
+
+// ensureIndex 为已存在的表补齐缺失索引
+func (d *fileManagerDAO) ensureIndex(table string, index tableIndex) error {
+	exists, err := d.indexExists(table, index.name)
+	if err != nil || exists {
+		return err
+	}
+
+	alterSQL := fmt.Sprintf("ALTER TABLE %s ADD %s", table, index.definition)
+	if index.replaces != "" {
+		replaced, err := d.indexExists(table, index.replaces)
+		if err != nil {
+			return err
+		}
+		if replaced {
+			alterSQL = fmt.Sprintf("ALTER TABLE %s DROP INDEX %s, ADD %s", table, index.replaces, index.definition)
+		}
+	}
+
+	_, err = d.db.Exec(d.ctx, alterSQL)
+	if err != nil {
+		return fmt.Errorf("failed to add index %s.%s: %w", table, index.name, err)
+	}
 	return nil
 }
 
+func (d *fileManagerDAO) indexExists(table string, name string) (bool, error) {
+	count, err := d.db.GetCount(d.ctx,
+		"SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?",
+		table, name)
+	if err != nil {
+		return false, fmt.Errorf("failed to check index %s.%s: %w", table, name, err)
+	}
+	return count > 0, nil
+}
+
 func (d *fileManagerDAO) Columns() string {
-	return "id, module, custom_id, type, file_id, file_orininal_name, file_link, status, create_time, update_time"
+	return "id, module, custom_id, type, file_id, file_orininal_name, file_link, status, storage_id, content_type, size, sha256, expected_sha256, owner_id, org_id, ref_count, require_ref, logical_id, version, is_current, delete_time, scan_attempts, create_time, update_time"
 }
 
-func (d *fileManagerDAO) Create(ctx context.Context, fileID string, fileName string, fileLink string) (err error) {
+func (d *fileManagerDAO) Create(ctx context.Context, tx gdb.TX, in *FileInfo) (err error) {
+	storageID := in.StorageID
+	if storageID == "" {
+		storageID = in.FileID
+	}
+	// 新版本在上传成功后才成为当前版本
+	version, isCurrent := in.Version, 0
+	if version <= 0 {
+		version = 1
+	}
+	if in.IsCurrent || in.LogicalFileID() == in.FileID {
+		isCurrent = 1
+	}
+	// 上传时声明了业务模块的文件需要建立关联，否则由垃圾回收删除
+	requireRef := 0
+	if in.RequireRef || in.Module != 0 {
+		requireRef = 1
+	}
+
 	dataInsert := g.Map{
-		"file_id":            fileID,
-		"file_orininal_name": fileName,
-		"file_link":          fileLink,
-		"status":             FileStatusInit,
+		"module":             in.Module,
+		"custom_id":          in.CustomID,
+		"type":               in.Type,
+		"file_id":            in.FileID,
+		"file_orininal_name": in.FileName,
+		"file_link":          in.FileLink,
+		"status":             in.Status,
+		"storage_id":         storageID,
+		"content_type":       in.ContentType,
+		"size":               in.Size,
+		"sha256":             in.SHA256,
+		"expected_sha256":    in.ExpectedSHA256,
+		"owner_id":           in.OwnerID,
+		"org_id":             in.OrgID,
+		"ref_count":          in.RefCount,
+		"require_ref":        requireRef,
+		"logical_id":         in.LogicalFileID(),
+		"version":            version,
+		"is_current":         isCurrent,
 		"create_time":        time.Now().Unix(),
 		"update_time":        time.Now().Unix(),
 	}
 
-	_, err = d.db.Model(d.tableName).Ctx(ctx).Data(dataInsert).Insert()
+	_, err = d.model(ctx, tx).Data(dataInsert).Insert()
 	if err != nil {
 		return err
 	}
@@ -103,37 +328,85 @@ func (d *fileManagerDAO) Create(ctx context.Context, fileID string, fileName str
 	return nil
 }
 
-func (d *fileManagerDAO) UpdateStatus(ctx context.Context, fileID string, status FileStatus) (err error) {
+// UpdateContentType 记录嗅探出的实际MIME类型
+func (d *fileManagerDAO) UpdateContentType(ctx context.Context, fileID string, contentType string) (err error) {
 	dataUpdate := g.Map{
-		"status":      status,
-		"update_time": time.Now().Unix(),
+		"content_type": contentType,
+		"update_time":  time.Now().Unix(),
 	}
 
-	result, err := d.db.Model(d.tableName).Ctx(ctx).Data(dataUpdate).Where("file_id = ?", fileID).Update()
-	if err != nil {
-		return err
+	_, err = d.db.Model(d.tableName).Ctx(ctx).Data(dataUpdate).Where("file_id = ?", fileID).Update()
+	return err
+}
+
+// SumOwnerSize 统计用户有效文件(初始化、扫描中或上传成功)的总大小，module/typ 为 0 时不限制，excludeLogicalID 不为空时不统计该逻辑文件
+// tx 不为空时锁定统计到的记录，同一用户的并发统计在事务提交前等待
+func (d *fileManagerDAO) SumOwnerSize(ctx context.Context, tx gdb.TX, ownerID string, module FileModule, typ FileType, excludeLogicalID string) (size int64, err error) {
+	model := d.model(ctx, tx).
+		Where("owner_id = ?", ownerID).
+		Where("status IN (?)", []FileStatus{FileStatusInit, FileStatusScanning, FileStatusUploadSuccess})
+	if module != 0 {
+		model = model.Where("module = ?", module)
+	}
+	if typ != 0 {
+		model = model.Where("type = ?", typ)
+	}
+	if excludeLogicalID != "" {
+		model = model.Where("logical_id != ?", excludeLogicalID)
+	}
+	if tx != nil {
+		model = model.LockUpdate()
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	value, err := model.Sum("size")
 	if err != nil {
-		return err
+		return 0, err
 	}
-	if rowsAffected == 0 {
-		return ErrFileNotFound
+	return int64(value), nil
+}
+
+// UpdateContentInfo 记录校验后的文件大小与哈希
+func (d *fileManagerDAO) UpdateContentInfo(ctx context.Context, fileID string, size int64, sha256 string) (err error) {
+	dataUpdate := g.Map{
+		"size":        size,
+		"sha256":      sha256,
+		"update_time": time.Now().Unix(),
 	}
 
-	return nil
+	_, err = d.db.Model(d.tableName).Ctx(ctx).Data(dataUpdate).Where("file_id = ?", fileID).Update()
+	return err
+}
+
+// GetBySHA256 查找上传者本人内容相同且已上传成功的文件
+func (d *fileManagerDAO) GetBySHA256(ctx context.Context, sha256 string, size int64, ownerID string) (out *FileInfo, err error) {
+	var entity *FileInfoEntity
+
+	err = d.db.Model(d.tableName).Ctx(ctx).
+		Where("sha256 = ?", sha256).
+		Where("size = ?", size).
+		Where("owner_id = ?", ownerID).
+		Where("status = ?", FileStatusUploadSuccess).
+		Where("delete_time = 0").
+		OrderAsc("id").
+		Limit(1).
+		Scan(&entity)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, ErrFileNotFound
+	}
+
+	return ConvertFileModel(entity), nil
 }
 
-func (d *fileManagerDAO) CreateAssociation(ctx context.Context, tx gdb.TX, fileID string, module FileModule, customID string, typ FileType) (err error) {
+func (d *fileManagerDAO) UpdateStatus(ctx context.Context, tx gdb.TX, fileID string, status FileStatus) (err error) {
 	dataUpdate := g.Map{
-		"module":      module,
-		"custom_id":   customID,
-		"type":        typ,
+		"status":      status,
 		"update_time": time.Now().Unix(),
 	}
 
-	result, err := d.db.Model(d.tableName).Ctx(ctx).TX(tx).Data(dataUpdate).Where("file_id = ?", fileID).Update()
+	result, err := d.model(ctx, tx).Data(dataUpdate).Where("file_id = ?", fileID).Update()
 	if err != nil {
 		return err
 	}
@@ -163,10 +436,11 @@ func (d *fileManagerDAO) Get(ctx context.Context, fileID string) (out *FileInfo,
 	return ConvertFileModel(&entity), nil
 }
 
-func (d *fileManagerDAO) ListByModuleAndCustomID(ctx context.Context, module FileModule, customID string) (out []*FileInfo, err error) {
+// ListByFileIDs 批量获取文件
+func (d *fileManagerDAO) ListByFileIDs(ctx context.Context, fileIDs []string) (out []*FileInfo, err error) {
 	var entities []FileInfoEntity
 
-	err = d.db.Model(d.tableName).Ctx(ctx).Where("module = ?", module).Where("custom_id = ?", customID).Scan(&entities)
+	err = d.db.Model(d.tableName).Ctx(ctx).Where("file_id IN (?)", fileIDs).Scan(&entities)
 	if err != nil {
 		return nil, err
 	}
@@ -178,79 +452,31 @@ func (d *fileManagerDAO) ListByModuleAndCustomID(ctx context.Context, module Fil
 	return out, nil
 }
 
-func (d *fileManagerDAO) ListByModuleAndCustomIDs(ctx context.Context, module FileModule, customIDs []string) (out map[string][]*FileInfo, err error) {
-	var entities []FileInfoEntity
-
-	err = d.db.Model(d.tableName).Ctx(ctx).Where("module = ?", module).Where("custom_id IN (?)", customIDs).Scan(&entities)
-	if err != nil {
-		return nil, err
-	}
-
-	out = make(map[string][]*FileInfo, len(entities))
-	for _, entity := range entities {
-		out[entity.CustomID] = append(out[entity.CustomID], ConvertFileModel(&entity))
-	}
-	return out, nil
-}
-
-func (d *fileManagerDAO) ClearCustomInfo(ctx context.Context, tx gdb.TX, module FileModule, customID string) (err error) {
-	dataUpdate := g.Map{
-		"module":      0,
-		"custom_id":   "",
-		"type":        0,
-		"update_time": time.Now().Unix(),
-	}
-
-	result, err := d.db.Model(d.tableName).Ctx(ctx).TX(tx).
-		Data(dataUpdate).
-		Where("module = ?", module).
-		Where("custom_id = ?", customID).
-		Update()
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rowsAffected == 0 {
-		return ErrFileNotFound
-	}
-
-	return nil
-}
-
+// CheckFileUploadSuccess 返回未上传成功的文件ID：不存在、未上传成功或已移入回收站，按传入顺序去重返回
 func (d *fileManagerDAO) CheckFileUploadSuccess(ctx context.Context, fileIDs []string) (notSuccessFileIDs []string, err error) {
 	if len(fileIDs) == 0 {
 		return nil, nil
 	}
 
-	// 使用一次SQL查询检查所有文件是否都上传成功
-	// 查询状态为UploadSuccess的文件数量
-	successCount, err := d.db.Model(d.tableName).Ctx(ctx).
+	// 一次查询取出上传成功且未删除的文件，其余(包括不存在的)均为未上传成功
+	successFileIDs, err := d.db.Model(d.tableName).Ctx(ctx).
+		Fields("file_id").
 		Where("file_id IN (?)", fileIDs).
 		Where("status = ?", FileStatusUploadSuccess).
-		Count()
+		Where("delete_time = 0").
+		Array()
 	if err != nil {
 		return nil, err
 	}
 
-	// 如果成功上传的文件数量不等于传入的fileIDs数量，说明有问题
-	if successCount != len(fileIDs) {
-		var notSuccessRecords []gdb.Record
-		// 检查是否有文件不存在
-		err := d.db.Model(d.tableName).Ctx(ctx).
-			Fields("file_id").
-			Where("file_id IN (?)", fileIDs).
-			Where("status != ?", FileStatusUploadSuccess).
-			Scan(&notSuccessRecords)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, record := range notSuccessRecords {
-			notSuccessFileIDs = append(notSuccessFileIDs, record["file_id"].String())
+	success := make(map[string]bool, len(successFileIDs))
+	for _, fileID := range successFileIDs {
+		success[fileID.String()] = true
+	}
+	for _, fileID := range fileIDs {
+		if !success[fileID] {
+			notSuccessFileIDs = append(notSuccessFileIDs, fileID)
+			success[fileID] = true
 		}
 	}
 
@@ -0,0 +1,266 @@
+package FileModule
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ensureAssociationTable 创建文件关联表，backfill 为 true 时将旧版 t_file 上的单一关联迁移到关联表
+func (d *fileManagerDAO) ensureAssociationTable(backfill bool) error {
+	createTableSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
+    file_id VARCHAR(40) NOT NULL COMMENT '逻辑文件ID',
+    module TINYINT(1) NOT NULL DEFAULT 0 COMMENT '业务模块',
+    custom_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT '业务自定义ID',
+    type TINYINT(1) NOT NULL DEFAULT 0 COMMENT '文件类型',
+    create_time BIGINT(20) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_file_id_module_id_type (file_id, module, custom_id, type),
+    KEY idx_module_id_type (module, custom_id, type)
+) ENGINE=InnoDB COMMENT='文件关联表';
+`, d.assocTableName)
+
+	_, err := d.db.Exec(d.ctx, createTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create association table: %w", err)
+	}
+
+	if !backfill {
+		return nil
+	}
+
+	_, err = d.db.Exec(d.ctx, fmt.Sprintf(`
+INSERT IGNORE INTO %s (file_id, module, custom_id, type, create_time)
+SELECT file_id, module, custom_id, type, update_time FROM %s WHERE custom_id != ''`, d.assocTableName, d.tableName))
+	if err != nil {
+		return fmt.Errorf("failed to backfill association table: %w", err)
+	}
+
+	_, err = d.db.Exec(d.ctx, fmt.Sprintf(`UPDATE %s SET ref_count = 1 WHERE custom_id != ''`, d.tableName))
+	if err != nil {
+		return fmt.Errorf("failed to backfill ref_count: %w", err)
+	}
+
+	return nil
+}
+
+func (d *fileManagerDAO) assocModel(ctx context.Context, tx gdb.TX) *gdb.Model {
+	model := d.db.Model(d.assocTableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	return model
+}
+
+// AttachFile 创建逻辑文件与业务实体的关联并增加各版本的引用数，关联已存在时不做处理
+// 建立过关联的文件改由业务关联管理生命周期；文件尚无主关联时，将本次关联设为主关联
+func (d *fileManagerDAO) AttachFile(ctx context.Context, tx gdb.TX, logicalID string, module FileModule, customID string, typ FileType) (attached bool, err error) {
+	dataInsert := g.Map{
+		"file_id":     logicalID,
+		"module":      module,
+		"custom_id":   customID,
+		"type":        typ,
+		"create_time": time.Now().Unix(),
+	}
+
+	result, err := d.assocModel(ctx, tx).Data(dataInsert).InsertIgnore()
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	dataUpdate := g.Map{
+		"ref_count":   gdb.Raw("ref_count + 1"),
+		"require_ref": 1,
+		"update_time": time.Now().Unix(),
+	}
+	result, err = d.model(ctx, tx).Data(dataUpdate).Where("logical_id = ?", logicalID).Update()
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err = result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, ErrFileNotFound
+	}
+
+	dataPrimary := g.Map{
+		"module":    module,
+		"custom_id": customID,
+		"type":      typ,
+	}
+	_, err = d.model(ctx, tx).Data(dataPrimary).Where("logical_id = ?", logicalID).Where("custom_id = ''").Update()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// DetachFile 删除逻辑文件与业务实体的关联并减少各版本的引用数，关联不存在时不做处理
+// 删除的是主关联时，主关联改为剩余的最早关联；没有剩余关联时清空
+func (d *fileManagerDAO) DetachFile(ctx context.Context, tx gdb.TX, logicalID string, module FileModule, customID string, typ FileType) (detached bool, err error) {
+	result, err := d.assocModel(ctx, tx).
+		Where("file_id = ?", logicalID).
+		Where("module = ?", module).
+		Where("custom_id = ?", customID).
+		Where("type = ?", typ).
+		Delete()
+	if err != nil {
+		return false, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	dataUpdate := g.Map{
+		"ref_count":   gdb.Raw("GREATEST(ref_count - 1, 0)"),
+		"update_time": time.Now().Unix(),
+	}
+	_, err = d.model(ctx, tx).Data(dataUpdate).Where("logical_id = ?", logicalID).Update()
+	if err != nil {
+		return false, err
+	}
+
+	var remaining *FileAssociationEntity
+	err = d.assocModel(ctx, tx).Where("file_id = ?", logicalID).OrderAsc("id").Limit(1).Scan(&remaining)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+
+	dataPrimary := g.Map{
+		"module":    0,
+		"custom_id": "",
+		"type":      0,
+	}
+	if remaining != nil {
+		dataPrimary = g.Map{
+			"module":    remaining.Module,
+			"custom_id": remaining.CustomID,
+			"type":      remaining.Type,
+		}
+	}
+	_, err = d.model(ctx, tx).
+		Data(dataPrimary).
+		Where("logical_id = ?", logicalID).
+		Where("module = ?", module).
+		Where("custom_id = ?", customID).
+		Where("type = ?", typ).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ListAssociations 获取逻辑文件的全部关联
+func (d *fileManagerDAO) ListAssociations(ctx context.Context, logicalID string) (out []*FileAssociation, err error) {
+	var entities []FileAssociationEntity
+
+	err = d.assocModel(ctx, nil).Where("file_id = ?", logicalID).OrderAsc("id").Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileAssociation, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileAssociationModel(&entity))
+	}
+	return out, nil
+}
+
+// ListEntityAssociations 获取业务实体的全部关联
+func (d *fileManagerDAO) ListEntityAssociations(ctx context.Context, tx gdb.TX, module FileModule, customID string) (out []*FileAssociation, err error) {
+	var entities []FileAssociationEntity
+
+	err = d.assocModel(ctx, tx).Where("module = ?", module).Where("custom_id = ?", customID).OrderAsc("id").Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileAssociation, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileAssociationModel(&entity))
+	}
+	return out, nil
+}
+
+// DeleteAssociations 删除逻辑文件的全部关联
+func (d *fileManagerDAO) DeleteAssociations(ctx context.Context, tx gdb.TX, logicalID string) (err error) {
+	_, err = d.assocModel(ctx, tx).Where("file_id = ?", logicalID).Delete()
+	return err
+}
+
+// associatedFileModel 关联表联查文件表，返回未删除文件的字段及关联的模块/自定义ID/类型
+// history 为 false 时只返回当前版本，否则返回全部版本(同一关联下按版本号降序)
+func (d *fileManagerDAO) associatedFileModel(ctx context.Context, history bool) *gdb.Model {
+	fields := make([]string, 0)
+	for _, column := range strings.Split(d.Columns(), ",") {
+		column = strings.TrimSpace(column)
+		switch column {
+		case "module", "custom_id", "type":
+			fields = append(fields, "a."+column)
+		default:
+			fields = append(fields, "f."+column)
+		}
+	}
+
+	model := d.db.Model(d.tableName+" f").Ctx(ctx).
+		InnerJoin(d.assocTableName+" a", "a.file_id = f.logical_id").
+		Fields(strings.Join(fields, ", ")).
+		Where("f.delete_time = 0")
+	if !history {
+		model = model.Where("f.is_current = 1")
+	}
+	return model.OrderAsc("a.id").OrderDesc("f.version")
+}
+
+func (d *fileManagerDAO) ListByModuleAndCustomID(ctx context.Context, module FileModule, customID string, history bool) (out []*FileInfo, err error) {
+	var entities []FileInfoEntity
+
+	err = d.associatedFileModel(ctx, history).Where("a.module = ?", module).Where("a.custom_id = ?", customID).Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileInfo, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileModel(&entity))
+	}
+	return out, nil
+}
+
+func (d *fileManagerDAO) ListByModuleAndCustomIDs(ctx context.Context, module FileModule, customIDs []string, history bool) (out map[string][]*FileInfo, err error) {
+	var entities []FileInfoEntity
+
+	err = d.associatedFileModel(ctx, history).Where("a.module = ?", module).Where("a.custom_id IN (?)", customIDs).Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make(map[string][]*FileInfo, len(entities))
+	for _, entity := range entities {
+		out[entity.CustomID] = append(out[entity.CustomID], ConvertFileModel(&entity))
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package FileModule
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// ListGCCandidates 查询可回收的孤儿文件(按ID升序分批)
+// 孤儿文件：最后更新时间早于 cutoff，且处于初始化/上传失败/已隔离状态，或由业务关联管理但已没有任何关联(引用数为0)；
+// 未声明业务模块且从未关联的文件(如导出文件)、进行中的分片上传及回收站中的文件不在此列
+func (d *fileManagerDAO) ListGCCandidates(ctx context.Context, cutoff time.Time, afterID int64, limit int) (out []*FileInfo, err error) {
+	var entities []FileInfoEntity
+
+	err = d.db.Model(d.tableName).Ctx(ctx).
+		Where("id > ?", afterID).
+		Where("update_time < ?", cutoff.Unix()).
+		Where("status != ?", FileStatusDeleted).
+		Where("delete_time = 0").
+		Where(
+			d.db.Model(d.tableName).Builder().
+				Where("status IN (?)", []FileStatus{FileStatusInit, FileStatusUploadFailed, FileStatusQuarantined}).
+				WhereOr("ref_count = 0 AND require_ref = 1"),
+		).
+		WhereNotIn("file_id", d.db.Model(d.multipartTableName).Fields("file_id").Where("status = ?", MultipartStatusUploading)).
+		OrderAsc("id").
+		Limit(limit).
+		Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileInfo, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileModel(&entity))
+	}
+	return out, nil
+}
+
+// CountStorageReferences 统计除 fileID 外仍引用同一存储文件的有效记录数，tx 不为空时锁定这些记录
+func (d *fileManagerDAO) CountStorageReferences(ctx context.Context, tx gdb.TX, storageID string, fileID string) (count int, err error) {
+	model := d.model(ctx, tx)
+	if tx != nil {
+		model = model.LockUpdate()
+	}
+	return model.
+		Where("file_id != ?", fileID).
+		Where("status != ?", FileStatusDeleted).
+		Where(
+			d.db.Model(d.tableName).Builder().
+				Where("storage_id = ?", storageID).
+				WhereOr("storage_id = '' AND file_id = ?", storageID),
+		).
+		Count()
+}
+
+// LockFile 在事务中锁定并返回文件记录
+func (d *fileManagerDAO) LockFile(ctx context.Context, tx gdb.TX, fileID string) (out *FileInfo, err error) {
+	var entity *FileInfoEntity
+
+	err = d.model(ctx, tx).Where("file_id = ?", fileID).LockUpdate().Scan(&entity)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, ErrFileNotFound
+	}
+
+	return ConvertFileModel(entity), nil
+}
+
+// Delete 删除文件记录
+func (d *fileManagerDAO) Delete(ctx context.Context, tx gdb.TX, fileID string) (err error) {
+	result, err := d.model(ctx, tx).Where("file_id = ?", fileID).Delete()
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrFileNotFound
+	}
+
+	return nil
+}
+
+// ListPendingUploads 查询创建时间早于 createdBefore 的初始化文件(按ID升序分批)，进行中的分片上传不在此列
+func (d *fileManagerDAO) ListPendingUploads(ctx context.Context, createdBefore time.Time, afterID int64, limit int) (out []*FileInfo, err error) {
+	var entities []FileInfoEntity
+
+	err = d.db.Model(d.tableName).Ctx(ctx).
+		Where("id > ?", afterID).
+		Where("status = ?", FileStatusInit).
+		Where("create_time < ?", createdBefore.Unix()).
+		WhereNotIn("file_id", d.db.Model(d.multipartTableName).Fields("file_id").Where("status = ?", MultipartStatusUploading)).
+		OrderAsc("id").
+		Limit(limit).
+		Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileInfo, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileModel(&entity))
+	}
+	return out, nil
+}
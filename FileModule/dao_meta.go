@@ -0,0 +1,242 @@
+package FileModule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ensureMetaTables 创建文件元数据表与标签表
+func (d *fileManagerDAO) ensureMetaTables() error {
+	createMetaTableSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
+    file_id VARCHAR(40) NOT NULL COMMENT '逻辑文件ID',
+    meta_key VARCHAR(64) NOT NULL COMMENT '元数据键',
+    meta_value TEXT COMMENT '元数据值',
+    create_time BIGINT(20) NOT NULL COMMENT '创建时间',
+    update_time BIGINT(20) NOT NULL COMMENT '更新时间',
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_file_id_key (file_id, meta_key)
+) ENGINE=InnoDB COMMENT='文件元数据表';
+`, d.metaTableName)
+
+	_, err := d.db.Exec(d.ctx, createMetaTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create meta table: %w", err)
+	}
+
+	createTagTableSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
+    file_id VARCHAR(40) NOT NULL COMMENT '逻辑文件ID',
+    tag VARCHAR(64) NOT NULL COMMENT '标签',
+    create_time BIGINT(20) NOT NULL COMMENT '创建时间',
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_file_id_tag (file_id, tag),
+    KEY idx_tag (tag)
+) ENGINE=InnoDB COMMENT='文件标签表';
+`, d.tagTableName)
+
+	_, err = d.db.Exec(d.ctx, createTagTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create tag table: %w", err)
+	}
+
+	return nil
+}
+
+// SetMetadata 写入逻辑文件的元数据，已存在的键覆盖
+func (d *fileManagerDAO) SetMetadata(ctx context.Context, tx gdb.TX, logicalID string, metadata map[string]string) (err error) {
+	data := make([]g.Map, 0, len(metadata))
+	for key, value := range metadata {
+		data = append(data, g.Map{
+			"file_id":     logicalID,
+			"meta_key":    key,
+			"meta_value":  value,
+			"create_time": time.Now().Unix(),
+			"update_time": time.Now().Unix(),
+		})
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	model := d.db.Model(d.metaTableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	_, err = model.Data(data).OnDuplicate("meta_value", "update_time").Save()
+	return err
+}
+
+// GetMetadata 获取逻辑文件的全部元数据
+func (d *fileManagerDAO) GetMetadata(ctx context.Context, logicalID string) (out map[string]string, err error) {
+	var records []gdb.Record
+
+	err = d.db.Model(d.metaTableName).Ctx(ctx).
+		Fields("meta_key, meta_value").
+		Where("file_id = ?", logicalID).
+		Scan(&records)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make(map[string]string, len(records))
+	for _, record := range records {
+		out[record["meta_key"].String()] = record["meta_value"].String()
+	}
+	return out, nil
+}
+
+// DeleteMetadata 删除逻辑文件的元数据，keys 为空时删除全部
+func (d *fileManagerDAO) DeleteMetadata(ctx context.Context, tx gdb.TX, logicalID string, keys []string) (err error) {
+	model := d.db.Model(d.metaTableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	model = model.Where("file_id = ?", logicalID)
+	if len(keys) > 0 {
+		model = model.Where("meta_key IN (?)", keys)
+	}
+
+	_, err = model.Delete()
+	return err
+}
+
+// AddTags 为逻辑文件添加标签，已存在的标签忽略
+func (d *fileManagerDAO) AddTags(ctx context.Context, tx gdb.TX, logicalID string, tags []string) (err error) {
+	data := make([]g.Map, 0, len(tags))
+	for _, tag := range tags {
+		data = append(data, g.Map{
+			"file_id":     logicalID,
+			"tag":         tag,
+			"create_time": time.Now().Unix(),
+		})
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	model := d.db.Model(d.tagTableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	_, err = model.Data(data).InsertIgnore()
+	return err
+}
+
+// RemoveTags 删除逻辑文件的标签，tags 为空时删除全部
+func (d *fileManagerDAO) RemoveTags(ctx context.Context, tx gdb.TX, logicalID string, tags []string) (err error) {
+	model := d.db.Model(d.tagTableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	model = model.Where("file_id = ?", logicalID)
+	if len(tags) > 0 {
+		model = model.Where("tag IN (?)", tags)
+	}
+
+	_, err = model.Delete()
+	return err
+}
+
+// ListTags 获取逻辑文件的标签
+func (d *fileManagerDAO) ListTags(ctx context.Context, logicalID string) (out []string, err error) {
+	values, err := d.db.Model(d.tagTableName).Ctx(ctx).
+		Fields("tag").
+		Where("file_id = ?", logicalID).
+		OrderAsc("id").
+		Array()
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]string, 0, len(values))
+	for _, value := range values {
+		out = append(out, value.String())
+	}
+	return out, nil
+}
+
+// Search 按条件分页搜索文件，返回当前页及总数
+func (d *fileManagerDAO) Search(ctx context.Context, filter *FileSearchFilter) (out []*FileInfo, total int, err error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Size <= 0 {
+		filter.Size = 10
+	}
+
+	total, err = d.buildSearchModel(ctx, filter).Count()
+	if err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []*FileInfo{}, 0, nil
+	}
+
+	var entities []FileInfoEntity
+	err = d.buildSearchModel(ctx, filter).
+		Page(filter.Page, filter.Size).
+		OrderDesc("create_time").
+		OrderDesc("id").
+		Scan(&entities)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out = make([]*FileInfo, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileModel(&entity))
+	}
+	return out, total, nil
+}
+
+func (d *fileManagerDAO) buildSearchModel(ctx context.Context, filter *FileSearchFilter) *gdb.Model {
+	model := d.db.Model(d.tableName).Ctx(ctx).
+		Where("delete_time = 0").
+		Where("is_current = 1")
+
+	if len(filter.Statuses) > 0 {
+		model = model.Where("status IN (?)", filter.Statuses)
+	} else {
+		model = model.Where("status != ?", FileStatusDeleted)
+	}
+	if filter.Module != 0 || filter.Type != 0 {
+		associated := d.db.Model(d.assocTableName).Fields("file_id")
+		if filter.Module != 0 {
+			associated = associated.Where("module = ?", filter.Module)
+		}
+		if filter.Type != 0 {
+			associated = associated.Where("type = ?", filter.Type)
+		}
+		model = model.WhereIn("logical_id", associated)
+	}
+	if filter.OwnerID != "" {
+		model = model.Where("owner_id = ?", filter.OwnerID)
+	}
+	if !filter.StartTime.IsZero() {
+		model = model.Where("create_time >= ?", filter.StartTime.Unix())
+	}
+	if !filter.EndTime.IsZero() {
+		model = model.Where("create_time <= ?", filter.EndTime.Unix())
+	}
+	if filter.NamePrefix != "" {
+		model = model.Where("file_orininal_name LIKE ?", escapeLike(filter.NamePrefix)+"%")
+	}
+	for _, tag := range filter.Tags {
+		model = model.WhereIn("logical_id", d.db.Model(d.tagTableName).Fields("file_id").Where("tag = ?", tag))
+	}
+
+	return model
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
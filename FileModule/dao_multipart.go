@@ -0,0 +1,196 @@
+package FileModule
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ensureMultipartTables 创建分片上传会话表与分片表
+func (d *fileManagerDAO) ensureMultipartTables() error {
+	createMultipartSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
+    file_id VARCHAR(40) NOT NULL COMMENT '文件ID',
+    upload_id VARCHAR(255) NOT NULL COMMENT '存储分片上传ID',
+    part_size BIGINT(20) NOT NULL DEFAULT 0 COMMENT '分片大小',
+    part_count INT(11) NOT NULL DEFAULT 0 COMMENT '分片数量(0:未知)',
+    status TINYINT(1) NOT NULL DEFAULT 0 COMMENT '状态(0:上传中,1:已完成,2:已取消)',
+    expire_time BIGINT(20) NOT NULL COMMENT '过期时间',
+    create_time BIGINT(20) NOT NULL COMMENT '创建时间',
+    update_time BIGINT(20) NOT NULL COMMENT '更新时间',
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_file_id (file_id),
+    KEY idx_status_expire_time (status, expire_time)
+) ENGINE=InnoDB COMMENT='文件分片上传表';
+`, d.multipartTableName)
+
+	_, err := d.db.Exec(d.ctx, createMultipartSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create multipart table: %w", err)
+	}
+
+	createPartSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
+    upload_id VARCHAR(255) NOT NULL COMMENT '存储分片上传ID',
+    part_number INT(11) NOT NULL COMMENT '分片序号',
+    etag VARCHAR(255) NOT NULL DEFAULT '' COMMENT '分片ETag',
+    size BIGINT(20) NOT NULL DEFAULT 0 COMMENT '分片大小',
+    create_time BIGINT(20) NOT NULL COMMENT '创建时间',
+    update_time BIGINT(20) NOT NULL COMMENT '更新时间',
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_upload_id_part_number (upload_id, part_number)
+) ENGINE=InnoDB COMMENT='文件分片表';
+`, d.partTableName)
+
+	_, err = d.db.Exec(d.ctx, createPartSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create multipart part table: %w", err)
+	}
+
+	return nil
+}
+
+func (d *fileManagerDAO) CreateMultipartUpload(ctx context.Context, tx gdb.TX, fileID string, uploadID string, partSize int64, partCount int, expireTime time.Time) (err error) {
+	dataInsert := g.Map{
+		"file_id":     fileID,
+		"upload_id":   uploadID,
+		"part_size":   partSize,
+		"part_count":  partCount,
+		"status":      MultipartStatusUploading,
+		"expire_time": expireTime.Unix(),
+		"create_time": time.Now().Unix(),
+		"update_time": time.Now().Unix(),
+	}
+
+	model := d.db.Model(d.multipartTableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	_, err = model.Data(dataInsert).Insert()
+	return err
+}
+
+func (d *fileManagerDAO) GetMultipartUpload(ctx context.Context, fileID string) (out *MultipartUploadInfo, err error) {
+	var entity MultipartUploadEntity
+
+	err = d.db.Model(d.multipartTableName).Ctx(ctx).Where("file_id = ?", fileID).Scan(&entity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrMultipartUploadNotFound
+		}
+		return nil, err
+	}
+
+	return ConvertMultipartUploadModel(&entity), nil
+}
+
+// UpdateMultipartStatus 仅更新处于上传中的会话，避免覆盖已完成/已取消的状态
+func (d *fileManagerDAO) UpdateMultipartStatus(ctx context.Context, fileID string, status MultipartStatus) (err error) {
+	dataUpdate := g.Map{
+		"status":      status,
+		"update_time": time.Now().Unix(),
+	}
+
+	result, err := d.db.Model(d.multipartTableName).Ctx(ctx).
+		Data(dataUpdate).
+		Where("file_id = ?", fileID).
+		Where("status = ?", MultipartStatusUploading).
+		Update()
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrMultipartUploadClosed
+	}
+
+	return nil
+}
+
+// ExtendMultipartExpire 更新上传中会话的过期时间
+func (d *fileManagerDAO) ExtendMultipartExpire(ctx context.Context, fileID string, expireTime time.Time) (err error) {
+	dataUpdate := g.Map{
+		"expire_time": expireTime.Unix(),
+		"update_time": time.Now().Unix(),
+	}
+
+	_, err = d.db.Model(d.multipartTableName).Ctx(ctx).
+		Data(dataUpdate).
+		Where("file_id = ?", fileID).
+		Where("status = ?", MultipartStatusUploading).
+		Update()
+	return err
+}
+
+// ListExpiredMultipartUploads 查询已过期但仍处于上传中的会话
+func (d *fileManagerDAO) ListExpiredMultipartUploads(ctx context.Context, before time.Time, limit int) (out []*MultipartUploadInfo, err error) {
+	var entities []MultipartUploadEntity
+
+	err = d.db.Model(d.multipartTableName).Ctx(ctx).
+		Where("status = ?", MultipartStatusUploading).
+		Where("expire_time < ?", before.Unix()).
+		OrderAsc("id").
+		Limit(limit).
+		Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*MultipartUploadInfo, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertMultipartUploadModel(&entity))
+	}
+	return out, nil
+}
+
+// SaveMultipartPart 记录分片，重复上报同一分片时覆盖
+func (d *fileManagerDAO) SaveMultipartPart(ctx context.Context, uploadID string, part *MultipartPart) (err error) {
+	dataSave := g.Map{
+		"upload_id":   uploadID,
+		"part_number": part.PartNumber,
+		"etag":        part.ETag,
+		"size":        part.Size,
+		"create_time": time.Now().Unix(),
+		"update_time": time.Now().Unix(),
+	}
+
+	_, err = d.db.Model(d.partTableName).Ctx(ctx).
+		Data(dataSave).
+		OnDuplicate("etag", "size", "update_time").
+		Save()
+	return err
+}
+
+func (d *fileManagerDAO) ListMultipartParts(ctx context.Context, uploadID string) (out []*MultipartPart, err error) {
+	var entities []MultipartPartEntity
+
+	err = d.db.Model(d.partTableName).Ctx(ctx).Where("upload_id = ?", uploadID).OrderAsc("part_number").Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*MultipartPart, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, &MultipartPart{
+			PartNumber: entity.PartNumber,
+			ETag:       entity.ETag,
+			Size:       entity.Size,
+		})
+	}
+	return out, nil
+}
+
+func (d *fileManagerDAO) DeleteMultipartParts(ctx context.Context, uploadID string) (err error) {
+	_, err = d.db.Model(d.partTableName).Ctx(ctx).Where("upload_id = ?", uploadID).Delete()
+	return err
+}
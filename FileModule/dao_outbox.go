@@ -0,0 +1,145 @@
+package FileModule
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ensureOutboxTable 创建存储副作用事件表
+func (d *fileManagerDAO) ensureOutboxTable() error {
+	createTableSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
+    op VARCHAR(32) NOT NULL COMMENT '操作类型(delete_file:删除存储文件,report_upload:上报上传结果)',
+    target_id VARCHAR(40) NOT NULL COMMENT '操作对象(存储文件ID)',
+    payload TEXT COMMENT '操作参数(JSON)',
+    status TINYINT(1) NOT NULL DEFAULT 0 COMMENT '状态(0:待执行,1:放弃)',
+    attempts INT(11) NOT NULL DEFAULT 0 COMMENT '已执行次数',
+    next_retry_time BIGINT(20) NOT NULL DEFAULT 0 COMMENT '下次执行时间',
+    last_error TEXT COMMENT '上次执行失败的原因',
+    create_time BIGINT(20) NOT NULL COMMENT '创建时间',
+    update_time BIGINT(20) NOT NULL COMMENT '更新时间',
+    PRIMARY KEY (id),
+    KEY idx_status_next_retry_time (status, next_retry_time)
+) ENGINE=InnoDB COMMENT='文件存储副作用事件表';
+`, d.outboxTableName)
+
+	_, err := d.db.Exec(d.ctx, createTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create outbox table: %w", err)
+	}
+
+	return nil
+}
+
+func (d *fileManagerDAO) outboxModel(ctx context.Context, tx gdb.TX) *gdb.Model {
+	model := d.db.Model(d.outboxTableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	return model
+}
+
+// CreateOutboxEvent 写入待执行的存储副作用事件，应与对应的 t_file 变更处于同一事务
+func (d *fileManagerDAO) CreateOutboxEvent(ctx context.Context, tx gdb.TX, event *OutboxEvent) (err error) {
+	payload, err := json.Marshal(event.Payload)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	id, err := d.outboxModel(ctx, tx).Data(g.Map{
+		"op":              string(event.Op),
+		"target_id":       event.TargetID,
+		"payload":         string(payload),
+		"status":          OutboxStatusPending,
+		"next_retry_time": now.Unix(),
+		"create_time":     now.Unix(),
+		"update_time":     now.Unix(),
+	}).InsertAndGetId()
+	if err != nil {
+		return err
+	}
+
+	event.ID = id
+	event.Status = OutboxStatusPending
+	event.NextRetryTime = time.Unix(now.Unix(), 0)
+	event.CreateTime = time.Unix(now.Unix(), 0)
+	event.UpdateTime = time.Unix(now.Unix(), 0)
+	return nil
+}
+
+// ListDueOutboxEvents 查询到达执行时间的待执行事件(按ID升序)
+func (d *fileManagerDAO) ListDueOutboxEvents(ctx context.Context, now time.Time, afterID int64, limit int) (out []*OutboxEvent, err error) {
+	var entities []OutboxEventEntity
+
+	err = d.outboxModel(ctx, nil).
+		Where("id > ?", afterID).
+		Where("status = ?", OutboxStatusPending).
+		Where("next_retry_time <= ?", now.Unix()).
+		OrderAsc("id").
+		Limit(limit).
+		Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*OutboxEvent, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertOutboxEventModel(&entity))
+	}
+	return out, nil
+}
+
+// ClaimOutboxEvent 抢占事件并将下次执行时间推迟到 leaseUntil，多实例部署时只有一个实例能抢占成功
+// 执行过程中进程退出的事件在 leaseUntil 之后被重新执行
+func (d *fileManagerDAO) ClaimOutboxEvent(ctx context.Context, event *OutboxEvent, leaseUntil time.Time) (ok bool, err error) {
+	dataUpdate := g.Map{
+		"attempts":        event.Attempts + 1,
+		"next_retry_time": leaseUntil.Unix(),
+		"update_time":     time.Now().Unix(),
+	}
+
+	result, err := d.outboxModel(ctx, nil).
+		Data(dataUpdate).
+		Where("id = ?", event.ID).
+		Where("status = ?", OutboxStatusPending).
+		Where("attempts = ?", event.Attempts).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rowsAffected == 1 {
+		event.Attempts++
+	}
+	return rowsAffected == 1, nil
+}
+
+// UpdateOutboxFailure 记录事件执行失败，status 为 OutboxStatusDead 时不再重试
+func (d *fileManagerDAO) UpdateOutboxFailure(ctx context.Context, id int64, status OutboxStatus, nextRetryTime time.Time, lastError string) (err error) {
+	dataUpdate := g.Map{
+		"status":          status,
+		"next_retry_time": nextRetryTime.Unix(),
+		"last_error":      lastError,
+		"update_time":     time.Now().Unix(),
+	}
+
+	_, err = d.outboxModel(ctx, nil).Data(dataUpdate).Where("id = ?", id).Update()
+	return err
+}
+
+// DeleteOutboxEvent 删除执行成功的事件
+func (d *fileManagerDAO) DeleteOutboxEvent(ctx context.Context, id int64) (err error) {
+	_, err = d.outboxModel(ctx, nil).Where("id = ?", id).Delete()
+	return err
+}
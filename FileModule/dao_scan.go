@@ -0,0 +1,69 @@
+package FileModule
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ListScanningFiles 查询等待内容扫描的文件(按ID升序分批)
+func (d *fileManagerDAO) ListScanningFiles(ctx context.Context, afterID int64, limit int) (out []*FileInfo, err error) {
+	var entities []FileInfoEntity
+
+	err = d.db.Model(d.tableName).Ctx(ctx).
+		Where("id > ?", afterID).
+		Where("status = ?", FileStatusScanning).
+		OrderAsc("id").
+		Limit(limit).
+		Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileInfo, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileModel(&entity))
+	}
+	return out, nil
+}
+
+// TransitStatus 仅当文件处于 from 状态时更新为 to，返回是否更新成功
+func (d *fileManagerDAO) TransitStatus(ctx context.Context, tx gdb.TX, fileID string, from FileStatus, to FileStatus) (ok bool, err error) {
+	dataUpdate := g.Map{
+		"status":      to,
+		"update_time": time.Now().Unix(),
+	}
+
+	result, err := d.model(ctx, tx).
+		Data(dataUpdate).
+		Where("file_id = ?", fileID).
+		Where("status = ?", from).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected > 0, nil
+}
+
+// UpdateScanAttempts 记录扫描中文件的扫描失败次数
+func (d *fileManagerDAO) UpdateScanAttempts(ctx context.Context, fileID string, attempts int) (err error) {
+	dataUpdate := g.Map{
+		"scan_attempts": attempts,
+		"update_time":   time.Now().Unix(),
+	}
+
+	_, err = d.model(ctx, nil).
+		Data(dataUpdate).
+		Where("file_id = ?", fileID).
+		Where("status = ?", FileStatusScanning).
+		Update()
+	return err
+}
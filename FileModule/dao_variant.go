@@ -0,0 +1,194 @@
+package FileModule
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ensureVariantTable 创建衍生图表
+func (d *fileManagerDAO) ensureVariantTable() error {
+	createTableSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
+    file_id VARCHAR(40) NOT NULL COMMENT '原图文件ID',
+    name VARCHAR(64) NOT NULL COMMENT '规格名称',
+    status TINYINT(1) NOT NULL DEFAULT 0 COMMENT '状态(0:待生成,1:生成中,2:成功,3:失败)',
+    storage_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT '存储文件ID',
+    file_link TEXT COMMENT '文件链接',
+    content_type VARCHAR(255) NOT NULL DEFAULT '' COMMENT '文件MIME类型',
+    size BIGINT(20) NOT NULL DEFAULT 0 COMMENT '文件大小(字节)',
+    width INT(11) NOT NULL DEFAULT 0 COMMENT '宽度',
+    height INT(11) NOT NULL DEFAULT 0 COMMENT '高度',
+    retry_count INT(11) NOT NULL DEFAULT 0 COMMENT '重试次数',
+    last_error TEXT COMMENT '上次生成失败的原因',
+    create_time BIGINT(20) NOT NULL COMMENT '创建时间',
+    update_time BIGINT(20) NOT NULL COMMENT '更新时间',
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_file_id_name (file_id, name),
+    KEY idx_status_update_time (status, update_time)
+) ENGINE=InnoDB COMMENT='文件衍生图表';
+`, d.variantTableName)
+
+	_, err := d.db.Exec(d.ctx, createTableSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create variant table: %w", err)
+	}
+
+	return nil
+}
+
+func (d *fileManagerDAO) variantModel(ctx context.Context, tx gdb.TX) *gdb.Model {
+	model := d.db.Model(d.variantTableName).Ctx(ctx)
+	if tx != nil {
+		model = model.TX(tx)
+	}
+	return model
+}
+
+// CreateVariants 为原图创建待生成的衍生图记录，已存在的规格忽略
+func (d *fileManagerDAO) CreateVariants(ctx context.Context, tx gdb.TX, fileID string, names []string) (err error) {
+	if len(names) == 0 {
+		return nil
+	}
+
+	data := make([]g.Map, 0, len(names))
+	for _, name := range names {
+		data = append(data, g.Map{
+			"file_id":     fileID,
+			"name":        name,
+			"status":      VariantStatusPending,
+			"create_time": time.Now().Unix(),
+			"update_time": time.Now().Unix(),
+		})
+	}
+
+	_, err = d.variantModel(ctx, tx).Data(data).InsertIgnore()
+	return err
+}
+
+func (d *fileManagerDAO) GetVariant(ctx context.Context, fileID string, name string) (out *FileVariant, err error) {
+	var entity FileVariantEntity
+
+	err = d.variantModel(ctx, nil).Where("file_id = ?", fileID).Where("name = ?", name).Scan(&entity)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	return ConvertFileVariantModel(&entity), nil
+}
+
+func (d *fileManagerDAO) ListVariants(ctx context.Context, fileID string) (out []*FileVariant, err error) {
+	var entities []FileVariantEntity
+
+	err = d.variantModel(ctx, nil).Where("file_id = ?", fileID).OrderAsc("id").Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileVariant, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileVariantModel(&entity))
+	}
+	return out, nil
+}
+
+// ListPendingVariants 查询待生成的衍生图，以及生成中但超过 stuckBefore 未更新(进程异常退出)的衍生图
+func (d *fileManagerDAO) ListPendingVariants(ctx context.Context, stuckBefore time.Time, limit int) (out []*FileVariant, err error) {
+	var entities []FileVariantEntity
+
+	err = d.variantModel(ctx, nil).
+		Where(
+			d.db.Model(d.variantTableName).Builder().
+				Where("status = ?", VariantStatusPending).
+				WhereOr("status = ? AND update_time < ?", VariantStatusProcessing, stuckBefore.Unix()),
+		).
+		OrderAsc("id").
+		Limit(limit).
+		Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileVariant, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileVariantModel(&entity))
+	}
+	return out, nil
+}
+
+// ClaimVariant 抢占衍生图生成任务，多实例部署时只有一个实例能抢占成功
+func (d *fileManagerDAO) ClaimVariant(ctx context.Context, variant *FileVariant) (ok bool, err error) {
+	dataUpdate := g.Map{
+		"status":      VariantStatusProcessing,
+		"update_time": time.Now().Unix(),
+	}
+
+	result, err := d.variantModel(ctx, nil).
+		Data(dataUpdate).
+		Where("id = ?", variant.ID).
+		Where("status = ?", variant.Status).
+		Where("update_time = ?", variant.UpdateTime.Unix()).
+		Update()
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rowsAffected == 1, nil
+}
+
+// UpdateVariantStorage 记录衍生图正在写入的存储文件ID
+func (d *fileManagerDAO) UpdateVariantStorage(ctx context.Context, tx gdb.TX, id int64, storageID string) (err error) {
+	dataUpdate := g.Map{
+		"storage_id":  storageID,
+		"update_time": time.Now().Unix(),
+	}
+
+	_, err = d.variantModel(ctx, tx).Data(dataUpdate).Where("id = ?", id).Update()
+	return err
+}
+
+func (d *fileManagerDAO) UpdateVariantSuccess(ctx context.Context, id int64, variant *FileVariant) (err error) {
+	dataUpdate := g.Map{
+		"status":       VariantStatusSuccess,
+		"storage_id":   variant.StorageID,
+		"file_link":    variant.FileLink,
+		"content_type": variant.ContentType,
+		"size":         variant.Size,
+		"width":        variant.Width,
+		"height":       variant.Height,
+		"last_error":   "",
+		"update_time":  time.Now().Unix(),
+	}
+
+	_, err = d.variantModel(ctx, nil).Data(dataUpdate).Where("id = ?", id).Update()
+	return err
+}
+
+func (d *fileManagerDAO) UpdateVariantFailure(ctx context.Context, id int64, status VariantStatus, retryCount int, lastError string) (err error) {
+	dataUpdate := g.Map{
+		"status":      status,
+		"retry_count": retryCount,
+		"last_error":  lastError,
+		"update_time": time.Now().Unix(),
+	}
+
+	_, err = d.variantModel(ctx, nil).Data(dataUpdate).Where("id = ?", id).Update()
+	return err
+}
+
+func (d *fileManagerDAO) DeleteVariants(ctx context.Context, tx gdb.TX, fileID string) (err error) {
+	_, err = d.variantModel(ctx, tx).Where("file_id = ?", fileID).Delete()
+	return err
+}
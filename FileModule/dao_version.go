@@ -0,0 +1,189 @@
+package FileModule
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// GetCurrentVersion 获取逻辑文件的当前版本(包括回收站中的文件)
+// tx 不为空时锁定当前版本，同一逻辑文件并发创建新版本时在事务提交前等待
+func (d *fileManagerDAO) GetCurrentVersion(ctx context.Context, tx gdb.TX, logicalID string) (out *FileInfo, err error) {
+	var entity *FileInfoEntity
+
+	model := d.model(ctx, tx).
+		Where("logical_id = ?", logicalID).
+		Where("is_current = 1").
+		Limit(1)
+	if tx != nil {
+		model = model.LockUpdate()
+	}
+	err = model.Scan(&entity)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, ErrFileNotFound
+	}
+
+	return ConvertFileModel(entity), nil
+}
+
+// MaxVersion 获取逻辑文件的最大版本号
+func (d *fileManagerDAO) MaxVersion(ctx context.Context, tx gdb.TX, logicalID string) (version int, err error) {
+	value, err := d.model(ctx, tx).Where("logical_id = ?", logicalID).Max("version")
+	if err != nil {
+		return 0, err
+	}
+	return int(value), nil
+}
+
+// PromoteVersion 将上传成功的版本设为逻辑文件的当前版本
+// 当前版本的版本号更大时不做处理，避免较早发起的上传覆盖新版本
+func (d *fileManagerDAO) PromoteVersion(ctx context.Context, fileID string) (promoted bool, err error) {
+	err = d.db.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		var entity *FileInfoEntity
+		err := d.model(ctx, tx).Where("file_id = ?", fileID).LockUpdate().Scan(&entity)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if entity == nil {
+			return ErrFileNotFound
+		}
+		if entity.IsCurrent == 1 {
+			return nil
+		}
+
+		newer, err := d.model(ctx, tx).
+			Where("logical_id = ?", entity.LogicalID).
+			Where("is_current = 1").
+			Where("version > ?", entity.Version).
+			Exist()
+		if err != nil || newer {
+			return err
+		}
+
+		_, err = d.model(ctx, tx).
+			Data(g.Map{"is_current": 0}).
+			Where("logical_id = ?", entity.LogicalID).
+			Where("is_current = 1").
+			Update()
+		if err != nil {
+			return err
+		}
+
+		_, err = d.model(ctx, tx).
+			Data(g.Map{"is_current": 1, "update_time": time.Now().Unix()}).
+			Where("file_id = ?", fileID).
+			Update()
+		if err != nil {
+			return err
+		}
+
+		promoted = true
+		return nil
+	})
+	return promoted, err
+}
+
+// ListVersions 获取逻辑文件的全部版本(按版本号降序)
+func (d *fileManagerDAO) ListVersions(ctx context.Context, logicalID string) (out []*FileInfo, err error) {
+	var entities []FileInfoEntity
+
+	err = d.db.Model(d.tableName).Ctx(ctx).
+		Where("logical_id = ?", logicalID).
+		Where("status != ?", FileStatusDeleted).
+		OrderDesc("version").
+		Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileInfo, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileModel(&entity))
+	}
+	return out, nil
+}
+
+// MoveToTrash 将逻辑文件的全部版本移入回收站
+func (d *fileManagerDAO) MoveToTrash(ctx context.Context, tx gdb.TX, logicalID string) (err error) {
+	dataUpdate := g.Map{
+		"delete_time": time.Now().Unix(),
+		"update_time": time.Now().Unix(),
+	}
+
+	result, err := d.model(ctx, tx).
+		Data(dataUpdate).
+		Where("logical_id = ?", logicalID).
+		Where("delete_time = 0").
+		Where("status != ?", FileStatusDeleted).
+		Update()
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrFileNotFound
+	}
+
+	return nil
+}
+
+// RestoreFromTrash 从回收站恢复逻辑文件的全部版本
+func (d *fileManagerDAO) RestoreFromTrash(ctx context.Context, tx gdb.TX, logicalID string) (err error) {
+	dataUpdate := g.Map{
+		"delete_time": 0,
+		"update_time": time.Now().Unix(),
+	}
+
+	result, err := d.model(ctx, tx).
+		Data(dataUpdate).
+		Where("logical_id = ?", logicalID).
+		Where("delete_time > 0").
+		Where("status != ?", FileStatusDeleted).
+		Update()
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return ErrFileNotFound
+	}
+
+	return nil
+}
+
+// ListExpiredTrash 查询移入回收站时间早于 cutoff 的文件(按ID升序分批)
+func (d *fileManagerDAO) ListExpiredTrash(ctx context.Context, cutoff time.Time, afterID int64, limit int) (out []*FileInfo, err error) {
+	var entities []FileInfoEntity
+
+	err = d.db.Model(d.tableName).Ctx(ctx).
+		Where("id > ?", afterID).
+		Where("delete_time > 0").
+		Where("delete_time < ?", cutoff.Unix()).
+		Where("status != ?", FileStatusDeleted).
+		OrderAsc("id").
+		Limit(limit).
+		Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out = make([]*FileInfo, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertFileModel(&entity))
+	}
+	return out, nil
+}
@@ -0,0 +1,138 @@
+package FileModule
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+// mockDriverType 测试用数据库驱动，底层连接为 sqlmock，按 SQL 正则断言执行顺序
+const mockDriverType = "filemodule-sqlmock"
+
+var (
+	mockConns  sync.Map
+	mockConnID int64
+)
+
+type mockDriver struct {
+	*gdb.Core
+}
+
+func init() {
+	_ = gdb.Register(mockDriverType, &mockDriver{})
+}
+
+func (d *mockDriver) New(core *gdb.Core, node *gdb.ConfigNode) (gdb.DB, error) {
+	return &mockDriver{Core: core}, nil
+}
+
+func (d *mockDriver) Open(node *gdb.ConfigNode) (*sql.DB, error) {
+	conn, ok := mockConns.Load(node.Name)
+	if !ok {
+		return nil, fmt.Errorf("mock connection not found: %s", node.Name)
+	}
+	return conn.(*sql.DB), nil
+}
+
+// mockColumns 各表字段的并集，写入时按字段过滤
+var mockColumns = []string{
+	"id", "module", "custom_id", "type", "file_id", "file_orininal_name", "file_link", "status",
+	"storage_id", "content_type", "size", "sha256", "expected_sha256", "owner_id", "org_id",
+	"ref_count", "require_ref", "logical_id", "version", "is_current", "delete_time", "scan_attempts", "create_time", "update_time",
+	"upload_id", "part_size", "part_count", "part_number", "etag", "expire_time",
+	"name", "width", "height", "retry_count", "meta_key", "meta_value", "tag",
+	"op", "target_id", "payload", "attempts", "next_retry_time", "last_error",
+}
+
+func (d *mockDriver) TableFields(ctx context.Context, table string, schema ...string) (map[string]*gdb.TableField, error) {
+	fields := make(map[string]*gdb.TableField, len(mockColumns))
+	for i, name := range mockColumns {
+		fields[name] = &gdb.TableField{Index: i, Name: name}
+	}
+	return fields, nil
+}
+
+// newMockDB 创建以 sqlmock 为底层连接的数据库实例
+func newMockDB(t *testing.T) (gdb.DB, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("mock-%d", atomic.AddInt64(&mockConnID, 1))
+	mockConns.Store(name, conn)
+	t.Cleanup(func() {
+		mockConns.Delete(name)
+		_ = conn.Close()
+	})
+
+	db, err := gdb.New(gdb.ConfigNode{Type: mockDriverType, Name: name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, mock
+}
+
+// newMockFileManager 创建使用 sqlmock 数据库与 httptest 文件引擎的管理器，不执行建表
+func newMockFileManager(t *testing.T, config *Config, handler http.HandlerFunc) (*FileManager, sqlmock.Sqlmock) {
+	db, mock := newMockDB(t)
+
+	if config == nil {
+		config = &Config{}
+	}
+	config.DB = db
+	config.FileEngineAddr = "http://engine"
+	if handler != nil {
+		server := httptest.NewServer(handler)
+		t.Cleanup(server.Close)
+		config.FileEngineAddr = server.URL
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	dao, err := newFileManagerDAO(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine, err := NewFileEngine(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := glog.New()
+	logger.SetLevel(glog.LEVEL_CRIT)
+
+	m := &FileManager{
+		logger:     logger,
+		config:     config,
+		dao:        dao,
+		fileEngine: engine,
+	}
+	return m, mock
+}
+
+// fileRows 构造 t_file 查询结果
+func fileRows(files ...*FileInfoEntity) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{
+		"id", "module", "custom_id", "type", "file_id", "file_orininal_name", "file_link", "status",
+		"storage_id", "content_type", "size", "sha256", "expected_sha256", "owner_id", "org_id",
+		"ref_count", "require_ref", "logical_id", "version", "is_current", "delete_time", "scan_attempts", "create_time", "update_time",
+	})
+	for _, f := range files {
+		rows.AddRow(
+			f.ID, f.Module, f.CustomID, f.Type, f.FileID, f.FileName, f.FileLink, f.Status,
+			f.StorageID, f.ContentType, f.Size, f.SHA256, f.ExpectedSHA256, f.OwnerID, f.OrgID,
+			f.RefCount, f.RequireRef, f.LogicalID, f.Version, f.IsCurrent, f.DeleteTime, f.ScanAttempts, f.CreateTime, f.UpdateTime,
+		)
+	}
+	return rows
+}
@@ -0,0 +1,143 @@
+package FileModule
+
+import (
+	"context"
+	"time"
+)
+
+const downloadURLCacheKeyPrefix = "file:download-url:"
+
+// BatchPreDownload 批量获取文件下载链接，返回以文件ID为键的结果
+// 未命中缓存的文件合并为一次文件引擎请求；不存在、已删除或无权访问的文件不在返回结果中
+func (m *FileManager) BatchPreDownload(ctx context.Context, fileIDs []string) (out map[string]*PreDownloadRes, err error) {
+	out = make(map[string]*PreDownloadRes, len(fileIDs))
+	if len(fileIDs) == 0 {
+		return out, nil
+	}
+
+	infos, err := m.dao.ListByFileIDs(ctx, fileIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make([]*FileInfo, 0, len(infos))
+	storageIDs := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if info.IsDeleted() || m.checkAccess(ctx, info, "") != nil {
+			continue
+		}
+		allowed = append(allowed, info)
+		storageIDs = append(storageIDs, info.StorageFileID())
+	}
+
+	urls, err := m.issueDownloadURLs(ctx, storageIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range allowed {
+		if res, ok := urls[info.StorageFileID()]; ok {
+			out[info.FileID] = res
+		}
+	}
+	return out, nil
+}
+
+// issueDownloadURLs 获取存储文件的下载链接，优先使用缓存，未命中的合并为一次文件引擎请求
+func (m *FileManager) issueDownloadURLs(ctx context.Context, storageIDs []string) (out map[string]*PreDownloadRes, err error) {
+	out = make(map[string]*PreDownloadRes, len(storageIDs))
+
+	missing := make([]string, 0, len(storageIDs))
+	for _, storageID := range storageIDs {
+		if _, ok := out[storageID]; ok {
+			continue
+		}
+		if res := m.getCachedDownloadURL(ctx, storageID); res != nil {
+			out[storageID] = res
+			continue
+		}
+		out[storageID] = nil
+		missing = append(missing, storageID)
+	}
+
+	var issued map[string]*PreDownloadRes
+	switch len(missing) {
+	case 0:
+	case 1:
+		res, err := m.fileEngine.PreDownload(ctx, missing[0])
+		if err != nil {
+			return nil, err
+		}
+		issued = map[string]*PreDownloadRes{missing[0]: res}
+	default:
+		issued, err = m.fileEngine.BatchPreDownload(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, storageID := range missing {
+		res, ok := issued[storageID]
+		if !ok {
+			delete(out, storageID)
+			continue
+		}
+		out[storageID] = res
+		m.cacheDownloadURL(ctx, storageID, res)
+	}
+	return out, nil
+}
+
+func (m *FileManager) getCachedDownloadURL(ctx context.Context, storageID string) *PreDownloadRes {
+	if m.config.DownloadURLCache == nil {
+		return nil
+	}
+
+	value := m.config.DownloadURLCache.Get(ctx, downloadURLCacheKeyPrefix+storageID)
+	if value == nil || value.IsNil() {
+		return nil
+	}
+
+	var res *PreDownloadRes
+	if err := value.Scan(&res); err != nil || res == nil || res.DownloadURL == "" {
+		return nil
+	}
+
+	// 按剩余有效期返回，调用方看到的过期时间与缓存前一致
+	expiresAt, err := time.Parse(time.RFC3339, res.ExpiresAt)
+	if err == nil {
+		res.ExpiresIn = int64(time.Until(expiresAt).Seconds())
+	}
+	return res
+}
+
+// cacheDownloadURL 缓存下载链接，在过期前 DownloadURLCacheMargin 失效；无法确定过期时间的链接不缓存
+func (m *FileManager) cacheDownloadURL(ctx context.Context, storageID string, res *PreDownloadRes) {
+	if m.config.DownloadURLCache == nil {
+		return
+	}
+
+	cached := *res
+	expiresAt, err := time.Parse(time.RFC3339, cached.ExpiresAt)
+	if err != nil {
+		if cached.ExpiresIn <= 0 {
+			return
+		}
+		expiresAt = time.Now().Add(time.Duration(cached.ExpiresIn) * time.Second)
+		cached.ExpiresAt = expiresAt.Format(time.RFC3339)
+	}
+
+	ttl := time.Until(expiresAt) - m.config.DownloadURLCacheMargin
+	if ttl <= 0 {
+		return
+	}
+	m.config.DownloadURLCache.Set(ctx, downloadURLCacheKeyPrefix+storageID, &cached, ttl)
+}
+
+// evictDownloadURL 删除存储文件的下载链接缓存
+func (m *FileManager) evictDownloadURL(ctx context.Context, storageID string) {
+	if m.config.DownloadURLCache == nil {
+		return
+	}
+	m.config.DownloadURLCache.Remove(ctx, downloadURLCacheKeyPrefix+storageID)
+}
@@ -0,0 +1,47 @@
+package FileModule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/yyboo586/common/cacheUtils"
+)
+
+func Test_DownloadURLCache(t *testing.T) {
+	ctx := context.Background()
+	m := &FileManager{config: &Config{
+		DownloadURLCache:       cacheUtils.NewMemory("test:"),
+		DownloadURLCacheMargin: time.Minute,
+	}}
+
+	gtest.C(t, func(t *gtest.T) {
+		expiresAt := time.Now().Add(10 * time.Minute).Format(time.RFC3339)
+		m.cacheDownloadURL(ctx, "storage-1", &PreDownloadRes{DownloadURL: "http://example/1", ExpiresAt: expiresAt, ExpiresIn: 600})
+
+		res := m.getCachedDownloadURL(ctx, "storage-1")
+		t.AssertNE(res, nil)
+		t.Assert(res.DownloadURL, "http://example/1")
+		t.Assert(res.ExpiresAt, expiresAt)
+		t.Assert(res.ExpiresIn <= 600 && res.ExpiresIn > 590, true)
+
+		m.evictDownloadURL(ctx, "storage-1")
+		t.Assert(m.getCachedDownloadURL(ctx, "storage-1"), nil)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 剩余有效期不足 DownloadURLCacheMargin 的链接不缓存
+		expiresAt := time.Now().Add(30 * time.Second).Format(time.RFC3339)
+		m.cacheDownloadURL(ctx, "storage-2", &PreDownloadRes{DownloadURL: "http://example/2", ExpiresAt: expiresAt, ExpiresIn: 30})
+		t.Assert(m.getCachedDownloadURL(ctx, "storage-2"), nil)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 过期时间无法解析时按 ExpiresIn 计算
+		m.cacheDownloadURL(ctx, "storage-3", &PreDownloadRes{DownloadURL: "http://example/3", ExpiresAt: "unknown", ExpiresIn: 600})
+		res := m.getCachedDownloadURL(ctx, "storage-3")
+		t.AssertNE(res, nil)
+		t.Assert(res.DownloadURL, "http://example/3")
+	})
+}
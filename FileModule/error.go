@@ -3,6 +3,36 @@ package FileModule
 import "github.com/gogf/gf/v2/errors/gerror"
 
 var (
-	ErrFileNotFound     = gerror.New("文件不存在")
-	ErrFileUploadFailed = gerror.New("文件上传失败")
+	ErrInvalidConfig = gerror.New("配置不合法")
+
+	ErrFileNotFound       = gerror.New("文件不存在")
+	ErrFileDeleted        = gerror.New("文件已删除")
+	ErrFileChanged        = gerror.New("文件在处理期间已被修改")
+	ErrAccessDenied       = gerror.New("无权访问该文件")
+	ErrFileUploadFailed   = gerror.New("文件上传失败")
+	ErrFileTooLarge       = gerror.New("文件大小超出限制")
+	ErrFileSizeMismatch   = gerror.New("文件实际大小与声明大小不一致")
+	ErrFileHashMismatch   = gerror.New("文件内容与声明的SHA-256不一致")
+	ErrUploadNotConfirmed = gerror.New("存储尚未确认文件上传完成")
+	ErrInvalidWebhook     = gerror.New("非法的上传回调请求")
+
+	ErrStorageQuotaExceeded = gerror.New("存储空间不足")
+	ErrFileExpired          = gerror.New("文件或链接已过期")
+
+	ErrInvalidMetadata = gerror.New("元数据或标签不合法")
+
+	ErrUnknownOutboxOp = gerror.New("未知的存储副作用操作")
+
+	ErrFileTypeNotAllowed = gerror.New("文件类型不允许上传")
+	ErrImageDimensions    = gerror.New("图片尺寸不符合要求")
+	ErrQuotaExceeded      = gerror.New("超出用户存储配额")
+
+	ErrVariantNotFound = gerror.New("衍生图不存在")
+	ErrVariantNotReady = gerror.New("衍生图尚未生成")
+	ErrImageTooLarge   = gerror.New("图片尺寸超出限制")
+
+	ErrMultipartUploadNotFound  = gerror.New("分片上传不存在")
+	ErrMultipartUploadClosed    = gerror.New("分片上传已完成或已取消")
+	ErrMultipartPartsIncomplete = gerror.New("分片未全部上传")
+	ErrMultipartPartNumber      = gerror.New("分片序号不合法")
 )
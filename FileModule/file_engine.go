@@ -3,32 +3,160 @@ package FileModule
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/yyboo586/common/httpUtils"
 )
 
-var (
-	fileEngineOnce     sync.Once
-	fileEngineInstance *fileEngine
-)
+// 重试退避的上限
+const engineMaxBackoff = 5 * time.Second
 
 type fileEngine struct {
 	addr   string
 	client httpUtils.HTTPClient
+
+	// stream 用于直接读写存储(预签名URL)，不读取整个响应体，超时交由 ctx 控制
+	stream *http.Client
+
+	// 幂等请求的最大重试次数及初始退避时间
+	maxRetry     int
+	retryBackoff time.Duration
+}
+
+// NewFileEngine 创建文件引擎客户端，每次调用返回独立的实例
+func NewFileEngine(cfg *Config) (*fileEngine, error) {
+	if cfg == nil || cfg.FileEngineAddr == "" {
+		return nil, gerror.Wrap(ErrInvalidConfig, "FileEngineAddr is required")
+	}
+
+	engine := &fileEngine{
+		addr:         strings.TrimRight(cfg.FileEngineAddr, "/"),
+		client:       httpUtils.NewHTTPClientWithDebug(cfg.EnableDebug),
+		stream:       &http.Client{Timeout: cfg.TransferTimeout},
+		maxRetry:     cfg.EngineMaxRetry,
+		retryBackoff: cfg.EngineRetryBackoff,
+	}
+	return engine, nil
+}
+
+// EngineError 文件引擎返回的非成功响应
+// 可通过 errors.Is 判断 ErrFileNotFound、ErrStorageQuotaExceeded、ErrFileExpired 等类别
+type EngineError struct {
+	Op     string
+	Status int
+	Code   string
+	Body   string
+
+	err error
 }
 
-func NewFileEngine(cfg *Config) *fileEngine {
-	fileEngineOnce.Do(func() {
-		fileEngineInstance = &fileEngine{
-			addr:   cfg.FileEngineAddr,
-			client: httpUtils.NewHTTPClientWithDebug(cfg.EnableDebug),
+func (e *EngineError) Error() string {
+	return fmt.Sprintf("%s failed, status: %d, respBody: %s", e.Op, e.Status, e.Body)
+}
+
+func (e *EngineError) Unwrap() error {
+	return e.err
+}
+
+// Retryable 是否为可重试的临时错误(限流或服务端错误)
+func (e *EngineError) Retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= http.StatusInternalServerError
+}
+
+// newEngineError 按状态码及响应中的错误码归类文件引擎的错误响应
+func newEngineError(op string, status int, respBody []byte) *EngineError {
+	var resp struct {
+		Code string `json:"code"`
+	}
+	_ = json.Unmarshal(respBody, &resp)
+
+	if len(respBody) > 1024 {
+		respBody = respBody[:1024]
+	}
+	e := &EngineError{Op: op, Status: status, Code: resp.Code, Body: string(respBody)}
+
+	switch {
+	case resp.Code == "quota_exceeded" || status == http.StatusInsufficientStorage:
+		e.err = ErrStorageQuotaExceeded
+	case resp.Code == "expired" || status == http.StatusGone:
+		e.err = ErrFileExpired
+	case resp.Code == "not_found" || status == http.StatusNotFound:
+		e.err = ErrFileNotFound
+	}
+	return e
+}
+
+// request 调用文件引擎接口并将响应解析到 out
+// idempotent 为 true 时，网络错误及限流/服务端错误按指数退避重试
+func (f *fileEngine) request(ctx context.Context, op string, method string, url string, reqBody interface{}, idempotent bool, out interface{}) (err error) {
+	backoff := f.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err = f.requestOnce(ctx, op, method, url, reqBody, out)
+		if err == nil || !idempotent || attempt >= f.maxRetry || !isRetryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
 		}
-	})
-	return fileEngineInstance
+		backoff = min(backoff*2, engineMaxBackoff)
+	}
+}
+
+func (f *fileEngine) requestOnce(ctx context.Context, op string, method string, url string, reqBody interface{}, out interface{}) (err error) {
+	var (
+		status   int
+		respBody []byte
+	)
+	switch method {
+	case http.MethodGet:
+		status, respBody, err = f.client.GET(ctx, url, nil)
+	case http.MethodPost:
+		status, respBody, err = f.client.POST(ctx, url, nil, reqBody)
+	case http.MethodDelete:
+		status, respBody, err = f.client.DELETE(ctx, url, nil, reqBody)
+	default:
+		return gerror.Newf("%s failed, unsupported method: %s", op, method)
+	}
+	if err != nil {
+		return gerror.Wrapf(err, "%s failed", op)
+	}
+	if status != http.StatusOK {
+		return newEngineError(op, status, respBody)
+	}
+
+	if out == nil {
+		return nil
+	}
+	err = json.Unmarshal(respBody, out)
+	if err != nil {
+		return gerror.Wrapf(err, "%s failed, invalid respBody: %s", op, string(respBody))
+	}
+	return nil
+}
+
+// isRetryable 网络错误(连接失败、超时、响应中断)及限流、服务端错误可以重试
+// 归类明确的引擎错误(不存在、配额、过期)、响应解析失败及调用方取消不重试
+func isRetryable(err error) bool {
+	var engineErr *EngineError
+	if errors.As(err, &engineErr) {
+		return engineErr.Retryable()
+	}
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
 }
 
 func (f *fileEngine) PreUpload(ctx context.Context, in *PreUploadReq) (out *PreUploadRes, err error) {
@@ -43,52 +171,88 @@ func (f *fileEngine) PreUpload(ctx context.Context, in *PreUploadReq) (out *PreU
 		"file": fileInfo,
 	}
 
-	status, respBody, err := f.client.POST(ctx, url, nil, reqBody)
-	if err != nil {
-		return nil, gerror.Newf("pre upload file failed, err: %s", err.Error())
-	}
-	if status != http.StatusOK {
-		return nil, gerror.Newf("pre upload file failed, status: %d, respBody: %s", status, string(respBody))
+	var resp struct {
+		ID           string `json:"id"`
+		OriginalName string `json:"original_name"`
+		VisitURL     string `json:"visit_url"`
+		UploadURL    string `json:"upload_url"`
+		ExpiresAt    string `json:"expires_at"`
+		ExpiresIn    int64  `json:"expires_in"`
 	}
-
-	var resp map[string]interface{}
-	err = json.Unmarshal(respBody, &resp)
+	// 每次调用都会创建新文件，不重试
+	err = f.request(ctx, "pre upload file", http.MethodPost, url, reqBody, false, &resp)
 	if err != nil {
-		return nil, gerror.Newf("pre upload file failed, err: %s", err.Error())
+		return nil, err
+	}
+	if resp.ID == "" || resp.UploadURL == "" {
+		return nil, gerror.Newf("pre upload file failed, missing id or upload_url, file: %s", in.FileName)
 	}
 
 	out = &PreUploadRes{
-		FileID:       resp["id"].(string),
-		OriginalName: resp["original_name"].(string),
-		FileLink:     resp["visit_url"].(string),
-		UploadURL:    resp["upload_url"].(string),
-		ExpiresAt:    resp["expires_at"].(string),
-		ExpiresIn:    int64(resp["expires_in"].(float64)),
+		FileID:       resp.ID,
+		OriginalName: resp.OriginalName,
+		FileLink:     resp.VisitURL,
+		UploadURL:    resp.UploadURL,
+		ExpiresAt:    resp.ExpiresAt,
+		ExpiresIn:    resp.ExpiresIn,
 	}
-	return
+	return out, nil
 }
 
 func (f *fileEngine) PreDownload(ctx context.Context, fileID string) (out *PreDownloadRes, err error) {
 	url := fmt.Sprintf("%s/api/v1/file-engine/files/%s/download-tokens", f.addr, fileID)
 
-	status, respBody, err := f.client.GET(ctx, url, nil)
+	var resp struct {
+		DownloadURL string `json:"download_url"`
+		ExpiresAt   string `json:"expires_at"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	err = f.request(ctx, "pre download file", http.MethodGet, url, nil, true, &resp)
 	if err != nil {
-		return nil, gerror.Newf("pre download file failed, err: %s", err.Error())
+		return nil, err
 	}
-	if status != http.StatusOK {
-		return nil, gerror.Newf("pre download file failed, status: %d, respBody: %s", status, string(respBody))
+	if resp.DownloadURL == "" {
+		return nil, gerror.Newf("pre download file failed, missing download_url, fileID: %s", fileID)
 	}
 
-	var resp map[string]interface{}
-	err = json.Unmarshal(respBody, &resp)
+	out = &PreDownloadRes{
+		DownloadURL: resp.DownloadURL,
+		ExpiresAt:   resp.ExpiresAt,
+		ExpiresIn:   resp.ExpiresIn,
+	}
+	return out, nil
+}
+
+// BatchPreDownload 批量获取下载链接，返回以存储文件ID为键的结果，存储中不存在的文件不在结果中
+func (f *fileEngine) BatchPreDownload(ctx context.Context, fileIDs []string) (out map[string]*PreDownloadRes, err error) {
+	url := fmt.Sprintf("%s/api/v1/file-engine/files/download-tokens", f.addr)
+	reqBody := map[string]interface{}{
+		"file_ids": fileIDs,
+	}
+
+	var resp struct {
+		Files []struct {
+			ID          string `json:"id"`
+			DownloadURL string `json:"download_url"`
+			ExpiresAt   string `json:"expires_at"`
+			ExpiresIn   int64  `json:"expires_in"`
+		} `json:"files"`
+	}
+	err = f.request(ctx, "batch pre download file", http.MethodPost, url, reqBody, true, &resp)
 	if err != nil {
-		return nil, gerror.Newf("pre download file failed, err: %s", err.Error())
+		return nil, err
 	}
 
-	out = &PreDownloadRes{
-		DownloadURL: resp["download_url"].(string),
-		ExpiresAt:   resp["expires_at"].(string),
-		ExpiresIn:   int64(resp["expires_in"].(float64)),
+	out = make(map[string]*PreDownloadRes, len(resp.Files))
+	for _, file := range resp.Files {
+		if file.ID == "" || file.DownloadURL == "" {
+			continue
+		}
+		out[file.ID] = &PreDownloadRes{
+			DownloadURL: file.DownloadURL,
+			ExpiresAt:   file.ExpiresAt,
+			ExpiresIn:   file.ExpiresIn,
+		}
 	}
 	return out, nil
 }
@@ -96,31 +260,187 @@ func (f *fileEngine) PreDownload(ctx context.Context, fileID string) (out *PreDo
 func (f *fileEngine) Delete(ctx context.Context, fileID string) (err error) {
 	url := fmt.Sprintf("%s/api/v1/file-engine/files/%s", f.addr, fileID)
 
-	status, respBody, err := f.client.DELETE(ctx, url, nil, nil)
+	return f.request(ctx, "delete file", http.MethodDelete, url, nil, true, nil)
+}
+
+func (f *fileEngine) ReportUploadResult(ctx context.Context, fileID string, success bool) (err error) {
+	url := fmt.Sprintf("%s/api/v1/file-engine/files/%s/status", f.addr, fileID)
+
+	reqBody := map[string]interface{}{
+		"success": success,
+	}
+
+	// 上报结果为覆盖写，可以重试
+	return f.request(ctx, "report upload result", http.MethodPost, url, reqBody, true, nil)
+}
+
+// PutContent 将内容流式写入预签名上传URL
+// size > 0 时设置 Content-Length，否则使用分块传输
+func (f *fileEngine) PutContent(ctx context.Context, uploadURL string, body io.Reader, size int64, contentType string) (err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, uploadURL, body)
 	if err != nil {
-		return gerror.Newf("delete file failed, err: %s", err.Error())
+		return gerror.Wrap(err, "put content failed")
 	}
-	if status != http.StatusOK {
-		return gerror.Newf("delete file failed, status: %d, respBody: %s", status, string(respBody))
+	if size > 0 {
+		req.ContentLength = size
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := f.stream.Do(req)
+	if err != nil {
+		return gerror.Wrap(err, "put content failed")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return newPresignedError("put content", resp.StatusCode, respBody)
 	}
 
 	return nil
 }
 
-func (f *fileEngine) ReportUploadResult(ctx context.Context, fileID string, success bool) (err error) {
-	url := fmt.Sprintf("%s/api/v1/file-engine/files/%s/status", f.addr, fileID)
+// GetContent 从预签名下载URL流式读取内容，调用方负责关闭返回的 ReadCloser
+func (f *fileEngine) GetContent(ctx context.Context, downloadURL string) (body io.ReadCloser, size int64, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, 0, gerror.Wrap(err, "get content failed")
+	}
 
+	resp, err := f.stream.Do(req)
+	if err != nil {
+		return nil, 0, gerror.Wrap(err, "get content failed")
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, 0, newPresignedError("get content", resp.StatusCode, respBody)
+	}
+
+	return resp.Body, resp.ContentLength, nil
+}
+
+// newPresignedError 预签名URL的错误响应，签名过期时存储返回 403
+func newPresignedError(op string, status int, respBody []byte) *EngineError {
+	e := newEngineError(op, status, respBody)
+	if status == http.StatusForbidden {
+		e.err = ErrFileExpired
+	}
+	return e
+}
+
+func (f *fileEngine) InitMultipartUpload(ctx context.Context, in *InitMultipartUploadReq) (out *InitMultipartUploadRes, err error) {
+	url := fmt.Sprintf("%s/api/v1/file-engine/files/multipart-uploads", f.addr)
+	fileInfo := map[string]interface{}{
+		"filename":     in.FileName,
+		"content_type": in.ContentType,
+		"size":         in.Size,
+		"bucket_id":    in.BucketID,
+	}
 	reqBody := map[string]interface{}{
-		"success": success,
+		"file":      fileInfo,
+		"part_size": in.PartSize,
 	}
 
-	status, respBody, err := f.client.POST(ctx, url, nil, reqBody)
+	var resp struct {
+		ID           string `json:"id"`
+		OriginalName string `json:"original_name"`
+		VisitURL     string `json:"visit_url"`
+		UploadID     string `json:"upload_id"`
+		PartSize     int64  `json:"part_size"`
+		ExpiresAt    string `json:"expires_at"`
+	}
+	err = f.request(ctx, "init multipart upload", http.MethodPost, url, reqBody, false, &resp)
 	if err != nil {
-		return gerror.Newf("report upload result failed, err: %s", err.Error())
+		return nil, err
 	}
-	if status != http.StatusOK {
-		return gerror.Newf("report upload result failed, status: %d, respBody: %s", status, string(respBody))
+	if resp.ID == "" || resp.UploadID == "" {
+		return nil, gerror.Newf("init multipart upload failed, missing id or upload_id, file: %s", in.FileName)
 	}
 
-	return nil
+	out = &InitMultipartUploadRes{
+		FileID:       resp.ID,
+		OriginalName: resp.OriginalName,
+		FileLink:     resp.VisitURL,
+		UploadID:     resp.UploadID,
+		PartSize:     resp.PartSize,
+		ExpiresAt:    resp.ExpiresAt,
+	}
+	return out, nil
+}
+
+func (f *fileEngine) GetMultipartPartURLs(ctx context.Context, fileID string, uploadID string, partNumbers []int) (out []*MultipartPartURL, err error) {
+	url := fmt.Sprintf("%s/api/v1/file-engine/files/%s/multipart-uploads/%s/part-tokens", f.addr, fileID, uploadID)
+	reqBody := map[string]interface{}{
+		"part_numbers": partNumbers,
+	}
+
+	var resp struct {
+		Parts []*MultipartPartURL `json:"parts"`
+	}
+	err = f.request(ctx, "get multipart part urls", http.MethodPost, url, reqBody, true, &resp)
+	if err != nil {
+		return nil, err
+	}
+	for _, part := range resp.Parts {
+		if part == nil || part.UploadURL == "" {
+			return nil, gerror.Newf("get multipart part urls failed, missing upload_url, fileID: %s", fileID)
+		}
+	}
+
+	return resp.Parts, nil
+}
+
+func (f *fileEngine) CompleteMultipartUpload(ctx context.Context, fileID string, uploadID string, parts []*MultipartPart) (err error) {
+	url := fmt.Sprintf("%s/api/v1/file-engine/files/%s/multipart-uploads/%s/complete", f.addr, fileID, uploadID)
+	reqBody := map[string]interface{}{
+		"parts": parts,
+	}
+
+	return f.request(ctx, "complete multipart upload", http.MethodPost, url, reqBody, false, nil)
+}
+
+func (f *fileEngine) AbortMultipartUpload(ctx context.Context, fileID string, uploadID string) (err error) {
+	url := fmt.Sprintf("%s/api/v1/file-engine/files/%s/multipart-uploads/%s", f.addr, fileID, uploadID)
+
+	err = f.request(ctx, "abort multipart upload", http.MethodDelete, url, nil, true, nil)
+	var engineErr *EngineError
+	if errors.As(err, &engineErr) && engineErr.Status == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// 文件引擎中的文件状态
+const (
+	engineFileStatusPending  = "pending"
+	engineFileStatusUploaded = "uploaded"
+	engineFileStatusFailed   = "failed"
+)
+
+type engineFileInfo struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	// SHA256 存储计算的内容哈希，旧版本文件引擎不返回
+	SHA256 string `json:"sha256"`
+}
+
+// GetFile 查询文件在存储中的实际状态
+func (f *fileEngine) GetFile(ctx context.Context, fileID string) (out *engineFileInfo, err error) {
+	url := fmt.Sprintf("%s/api/v1/file-engine/files/%s", f.addr, fileID)
+
+	out = &engineFileInfo{}
+	err = f.request(ctx, "get file", http.MethodGet, url, nil, true, out)
+	if err != nil {
+		return nil, err
+	}
+	if out.Status == "" {
+		return nil, gerror.Newf("get file failed, missing status, fileID: %s", fileID)
+	}
+
+	return out, nil
 }
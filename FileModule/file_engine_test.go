@@ -0,0 +1,182 @@
+package FileModule
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func newTestFileEngine(handler http.HandlerFunc) (*fileEngine, *httptest.Server) {
+	server := httptest.NewServer(handler)
+	engine, _ := NewFileEngine(&Config{
+		FileEngineAddr:     server.URL,
+		EngineMaxRetry:     2,
+		EngineRetryBackoff: time.Millisecond,
+	})
+	return engine, server
+}
+
+func Test_FileEngine_Retry(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		var calls int32
+		engine, server := newTestFileEngine(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"f1","status":"uploaded","size":10}`))
+		})
+		defer server.Close()
+
+		info, err := engine.GetFile(ctx, "f1")
+		t.AssertNil(err)
+		t.Assert(info.Status, engineFileStatusUploaded)
+		t.Assert(atomic.LoadInt32(&calls), 3)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 非幂等请求不重试
+		var calls int32
+		engine, server := newTestFileEngine(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+		defer server.Close()
+
+		_, err := engine.PreUpload(ctx, &PreUploadReq{FileName: "a.txt"})
+		t.AssertNE(err, nil)
+		t.Assert(atomic.LoadInt32(&calls), 1)
+	})
+}
+
+func Test_NewFileEngine(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		_, err := NewFileEngine(&Config{})
+		t.Assert(errors.Is(err, ErrInvalidConfig), true)
+
+		a, err := NewFileEngine(&Config{FileEngineAddr: "http://engine-a/"})
+		t.AssertNil(err)
+		b, err := NewFileEngine(&Config{FileEngineAddr: "http://engine-b"})
+		t.AssertNil(err)
+		t.Assert(a.addr, "http://engine-a")
+		t.Assert(b.addr, "http://engine-b")
+	})
+}
+
+func Test_FileEngine_TypedErrors(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		var calls int32
+		engine, server := newTestFileEngine(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusNotFound)
+		})
+		defer server.Close()
+
+		_, err := engine.PreDownload(ctx, "missing")
+		t.Assert(errors.Is(err, ErrFileNotFound), true)
+		t.Assert(atomic.LoadInt32(&calls), 1)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		engine, server := newTestFileEngine(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"code":"quota_exceeded","message":"quota exceeded"}`))
+		})
+		defer server.Close()
+
+		_, err := engine.PreUpload(ctx, &PreUploadReq{FileName: "a.txt"})
+		t.Assert(errors.Is(err, ErrStorageQuotaExceeded), true)
+
+		var engineErr *EngineError
+		t.Assert(errors.As(err, &engineErr), true)
+		t.Assert(engineErr.Status, http.StatusForbidden)
+		t.Assert(engineErr.Code, "quota_exceeded")
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		engine, server := newTestFileEngine(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusGone)
+		})
+		defer server.Close()
+
+		_, err := engine.PreDownload(ctx, "f1")
+		t.Assert(errors.Is(err, ErrFileExpired), true)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 响应缺少字段或字段类型不符时返回错误而不是 panic
+		engine, server := newTestFileEngine(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"id":123,"upload_url":null}`))
+		})
+		defer server.Close()
+
+		_, err := engine.PreUpload(ctx, &PreUploadReq{FileName: "a.txt"})
+		t.AssertNE(err, nil)
+
+		_, err = engine.PreDownload(ctx, "f1")
+		t.AssertNE(err, nil)
+	})
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func Test_IsRetryable(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		ok   bool
+	}{
+		{name: "server error", err: newEngineError("op", http.StatusServiceUnavailable, nil), ok: true},
+		{name: "rate limited", err: newEngineError("op", http.StatusTooManyRequests, nil), ok: true},
+		{name: "not found", err: newEngineError("op", http.StatusNotFound, nil), ok: false},
+		{name: "bad request", err: newEngineError("op", http.StatusBadRequest, nil), ok: false},
+		{name: "wrapped engine error", err: gerror.Wrap(newEngineError("op", http.StatusBadGateway, nil), "get file"), ok: true},
+		{name: "network timeout", err: gerror.Wrap(&url.Error{Op: "Get", URL: "http://engine", Err: timeoutError{}}, "get file failed"), ok: true},
+		{name: "connection reset", err: gerror.Wrap(&net.OpError{Op: "read", Err: errors.New("connection reset by peer")}, "get file failed"), ok: true},
+		{name: "truncated response", err: gerror.Wrap(io.ErrUnexpectedEOF, "get file failed"), ok: true},
+		{name: "caller canceled", err: gerror.Wrap(&url.Error{Op: "Get", URL: "http://engine", Err: context.Canceled}, "get file failed"), ok: false},
+		{name: "invalid response body", err: gerror.Wrap(&json.SyntaxError{}, "get file failed"), ok: false},
+		{name: "plain error", err: gerror.New("get file failed, missing status"), ok: false},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			t.Assert(isRetryable(c.err), c.ok)
+		}
+	})
+}
+
+func Test_FileEngine_InvalidResponseNotRetried(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		var calls int32
+		engine, server := newTestFileEngine(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			_, _ = w.Write([]byte(`not json`))
+		})
+		defer server.Close()
+
+		_, err := engine.GetFile(context.Background(), "f1")
+		var syntaxErr *json.SyntaxError
+		t.Assert(errors.As(err, &syntaxErr), true)
+		t.Assert(atomic.LoadInt32(&calls), 1)
+	})
+}
@@ -1,62 +1,105 @@
 package FileModule
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/os/glog"
-)
-
-var (
-	fileManagerOnce     sync.Once
-	fileManagerInstance *FileManager
+	"github.com/google/uuid"
 )
 
 // FileManager 文件管理器
 type FileManager struct {
 	logger *glog.Logger
+	config *Config
 
 	dao *fileManagerDAO
 
 	fileEngine *fileEngine
+
+	// 上传完成回调
+	callbacks     map[moduleTypeKey][]UploadCallback
+	callbackMutex sync.RWMutex
+
+	// 上传策略
+	policies    map[moduleTypeKey]*UploadPolicy
+	policyMutex sync.RWMutex
+
+	// 下载授权策略
+	accessPolicies map[moduleTypeKey]AccessPolicy
+	accessMutex    sync.RWMutex
+
+	// 衍生图规格
+	variantProfiles map[FileType][]*VariantProfile
+	variantMutex    sync.RWMutex
+
+	// 归档导出的类型目录名
+	archiveFolders map[FileType]string
+	archiveMutex   sync.RWMutex
+
+	// 后台任务
+	mutex  sync.Mutex
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
-// newFileManager 创建文件管理器
+// NewFileManager 创建文件管理器
+// 每次调用创建独立的实例(数据库连接、表、文件引擎、日志)，可在同一服务中为不同存储租户创建多个管理器
 func NewFileManager(config *Config) (IFileManager, error) {
-	fileManagerOnce.Do(func() {
-		ctx := context.Background()
-		daoFileManager, err := newFileManagerDAO(ctx, config)
-		if err != nil {
-			panic(err)
-		}
+	if config == nil {
+		config = DefaultConfig()
+	}
 
-		logger := glog.New()
-		if config.EnableDebug {
-			logger.SetLevel(glog.LEVEL_ALL)
-		} else {
-			logger.SetLevel(glog.LEVEL_ERRO)
-		}
+	err := config.Validate()
+	if err != nil {
+		return nil, err
+	}
 
-		logger.SetPrefix("[FileManager]")
-		logger.SetTimeFormat(time.DateTime)
-		logger.SetWriter(os.Stdout)
+	ctx := context.Background()
+	daoFileManager, err := newFileManagerDAO(ctx, config)
+	if err != nil {
+		return nil, err
+	}
 
-		fileManagerInstance = &FileManager{
-			logger:     logger,
-			dao:        daoFileManager,
-			fileEngine: NewFileEngine(config),
-		}
-	})
+	fileEngine, err := NewFileEngine(config)
+	if err != nil {
+		return nil, err
+	}
 
-	err := fileManagerInstance.EnsureTable()
+	logger := glog.New()
+	if config.EnableDebug {
+		logger.SetLevel(glog.LEVEL_ALL)
+	} else {
+		logger.SetLevel(glog.LEVEL_ERRO)
+	}
+
+	logger.SetPrefix(fmt.Sprintf("[FileManager:%s]", config.TableName))
+	logger.SetTimeFormat(time.DateTime)
+	logger.SetWriter(os.Stdout)
+
+	m := &FileManager{
+		logger:     logger,
+		config:     config,
+		dao:        daoFileManager,
+		fileEngine: fileEngine,
+	}
+	m.RegisterUploadCallback(0, 0, m.variantUploadCallback)
+
+	err = m.EnsureTable()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return fileManagerInstance, nil
+	return m, nil
 }
 
 func (m *FileManager) EnsureTable() error {
@@ -64,39 +107,493 @@ func (m *FileManager) EnsureTable() error {
 }
 
 func (m *FileManager) PreUpload(ctx context.Context, in *PreUploadReq) (out *PreUploadRes, err error) {
+	return m.preUpload(ctx, in, true)
+}
+
+// preUpload 获取上传链接并创建文件记录，allowInstant 为 true 时尝试秒传
+// 配额先在申请上传链接前预检，创建文件记录时在事务中加锁重新校验，避免并发上传同时通过预检后超出配额
+func (m *FileManager) preUpload(ctx context.Context, in *PreUploadReq, allowInstant bool) (out *PreUploadRes, err error) {
+	err = m.checkDeclared(in.Module, in.Type, in.FileName, in.ContentType, in.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := &FileInfo{OwnerID: in.OwnerID}
+	fillOwner(ctx, owner)
+	err = m.checkQuota(ctx, nil, in.Module, in.Type, in.Size, owner.OwnerID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	expectedSHA256 := strings.ToLower(in.SHA256)
+	if allowInstant && expectedSHA256 != "" && in.Size > 0 && owner.OwnerID != "" {
+		out, err = m.instantUpload(ctx, in, owner.OwnerID, expectedSHA256)
+		if err == nil {
+			return out, nil
+		}
+		if err != ErrFileNotFound {
+			return nil, err
+		}
+	}
+
 	out, err = m.fileEngine.PreUpload(ctx, in)
 	if err != nil {
 		return nil, err
 	}
 
-	err = m.dao.Create(ctx, out.FileID, out.OriginalName, out.FileLink)
+	err = m.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		err := m.checkQuota(ctx, tx, in.Module, in.Type, in.Size, owner.OwnerID, "")
+		if err != nil {
+			return err
+		}
+
+		// ctx 携带事务，文件记录在同一事务中创建
+		return m.createFile(ctx, &FileInfo{
+			Module:         in.Module,
+			Type:           in.Type,
+			FileID:         out.FileID,
+			FileName:       out.OriginalName,
+			FileLink:       out.FileLink,
+			Status:         FileStatusInit,
+			ContentType:    in.ContentType,
+			Size:           in.Size,
+			ExpectedSHA256: expectedSHA256,
+			OwnerID:        owner.OwnerID,
+			LogicalID:      in.LogicalID,
+		})
+	})
 	if err != nil {
+		// 文件ID由文件引擎分配，无法与文件记录写入同一事务；记录写入失败时回收存储中的文件
+		m.deleteOrphanStorage(ctx, out.FileID)
 		return nil, err
 	}
 
 	return out, nil
 }
 
+// instantUpload 秒传：上传者本人已上传过内容相同的文件时不再上传，直接创建一条引用同一存储的文件记录
+// 客户端声明的哈希未经校验，只在上传者自己的文件中查找，避免凭哈希获取他人的文件
+func (m *FileManager) instantUpload(ctx context.Context, in *PreUploadReq, ownerID string, sha256 string) (out *PreUploadRes, err error) {
+	existing, err := m.dao.GetBySHA256(ctx, sha256, in.Size, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 锁定源记录后再创建引用，与垃圾回收删除该存储互斥；源记录已被回收时按普通上传处理
+	fileID := uuid.New().String()
+	err = m.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		source, err := m.dao.LockFile(ctx, tx, existing.FileID)
+		if err != nil {
+			return err
+		}
+		if source.Status != FileStatusUploadSuccess || source.IsDeleted() {
+			return ErrFileNotFound
+		}
+		err = m.checkQuota(ctx, tx, in.Module, in.Type, existing.Size, ownerID, "")
+		if err != nil {
+			return err
+		}
+
+		// ctx 携带事务，文件记录在同一事务中创建
+		return m.createFile(ctx, &FileInfo{
+			Module:         in.Module,
+			Type:           in.Type,
+			FileID:         fileID,
+			FileName:       in.FileName,
+			FileLink:       existing.FileLink,
+			Status:         FileStatusUploadSuccess,
+			StorageID:      existing.StorageFileID(),
+			ContentType:    in.ContentType,
+			Size:           existing.Size,
+			SHA256:         existing.SHA256,
+			ExpectedSHA256: sha256,
+			OwnerID:        ownerID,
+			LogicalID:      in.LogicalID,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	out = &PreUploadRes{
+		Instant:      true,
+		FileID:       fileID,
+		OriginalName: in.FileName,
+		FileLink:     existing.FileLink,
+	}
+	return out, nil
+}
+
+// PreDownload 获取文件下载链接；配置了 DownloadURLCache 时复用未过期的链接
+// 开启 EnableAccessControl 时，签发链接前按授权策略校验上下文中的当前用户
 func (m *FileManager) PreDownload(ctx context.Context, fileID string) (out *PreDownloadRes, err error) {
-	out, err = m.fileEngine.PreDownload(ctx, fileID)
+	return m.preDownload(ctx, fileID, "")
+}
+
+// PreDownloadVariant 获取文件衍生图的下载链接，衍生图尚未生成成功时返回 ErrVariantNotReady
+func (m *FileManager) PreDownloadVariant(ctx context.Context, fileID string, variant string) (out *PreDownloadRes, err error) {
+	if variant == "" {
+		return nil, ErrVariantNotFound
+	}
+	return m.preDownload(ctx, fileID, variant)
+}
+
+// preDownload 签发原文件(variantName 为空)或衍生图的下载链接
+func (m *FileManager) preDownload(ctx context.Context, fileID string, variantName string) (out *PreDownloadRes, err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDeleted() {
+		return nil, ErrFileDeleted
+	}
+
+	err = m.checkAccess(ctx, info, variantName)
+	if err != nil {
+		return nil, err
+	}
+
+	storageID := info.StorageFileID()
+	if variantName != "" {
+		fileVariant, err := m.dao.GetVariant(ctx, fileID, variantName)
+		if err != nil {
+			return nil, err
+		}
+		if fileVariant.Status != VariantStatusSuccess {
+			return nil, ErrVariantNotReady
+		}
+		storageID = fileVariant.StorageID
+	}
+
+	urls, err := m.issueDownloadURLs(ctx, []string{storageID})
 	if err != nil {
 		return nil, err
 	}
+	out, ok := urls[storageID]
+	if !ok {
+		return nil, ErrFileNotFound
+	}
 
 	return out, nil
 }
 
+// Upload 服务端直传文件
+// 内容以流的方式写入存储，不在内存中缓冲整个文件；上传过程中计算 SHA-256，结果同步到 t_file 与文件引擎
+// 写入后按实际大小重新校验上传策略的大小限制与用户配额
+func (m *FileManager) Upload(ctx context.Context, r io.Reader, meta *UploadMeta) (out *FileInfo, err error) {
+	if m.config.MaxUploadSize > 0 && meta.Size > m.config.MaxUploadSize {
+		return nil, ErrFileTooLarge
+	}
+
+	// 上传前按内容头部校验上传策略，避免不合规的内容写入存储
+	reader := bufio.NewReaderSize(r, sniffHeadSize)
+	head, err := reader.Peek(sniffHeadSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+	_, err = m.checkContent(meta.Module, meta.Type, head, meta.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	// 服务端持有内容，总是写入并校验实际内容，不按声明的哈希秒传
+	preUpload, err := m.preUpload(ctx, &PreUploadReq{
+		FileName:    meta.FileName,
+		ContentType: meta.ContentType,
+		Size:        meta.Size,
+		BucketID:    meta.BucketID,
+		SHA256:      meta.SHA256,
+		Module:      meta.Module,
+		Type:        meta.Type,
+		OwnerID:     meta.OwnerID,
+		LogicalID:   meta.LogicalID,
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	body := newSizeLimitReader(io.TeeReader(reader, hash), m.config.MaxUploadSize, meta.Size)
+	err = m.fileEngine.PutContent(ctx, preUpload.UploadURL, body, meta.Size, meta.ContentType)
+	if err != nil {
+		m.finishUpload(ctx, preUpload.FileID, false)
+		return nil, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if meta.SHA256 != "" && !strings.EqualFold(meta.SHA256, sum) {
+		m.finishUpload(ctx, preUpload.FileID, false)
+		return nil, ErrFileHashMismatch
+	}
+
+	err = m.dao.UpdateContentInfo(ctx, preUpload.FileID, body.Size(), sum)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := m.dao.Get(ctx, preUpload.FileID)
+	if err != nil {
+		return nil, err
+	}
+	err = m.confirmUpload(ctx, info, body.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	if meta.Module != 0 {
+		err = m.AttachFile(ctx, nil, preUpload.FileID, meta.Module, meta.CustomID, meta.Type)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m.dao.Get(ctx, preUpload.FileID)
+}
+
+// CompleteUpload 确认上传完成
+// 先向文件引擎确认文件已写入存储，记录文件引擎返回的 SHA-256(未返回时流式读回内容计算)，声明了 SHA-256 的文件同时校验；
+// 按实际大小重新校验上传策略的大小限制与用户配额，不满足时上传失败并删除存储中的文件；
+// 确认结果写入 t_file、上报文件引擎，并触发已注册的上传完成回调
+func (m *FileManager) CompleteUpload(ctx context.Context, fileID string) (err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	switch info.Status {
+	case FileStatusInit:
+	case FileStatusUploadSuccess, FileStatusScanning:
+		return nil
+	default:
+		return ErrFileUploadFailed
+	}
+
+	engineInfo, err := m.fileEngine.GetFile(ctx, info.StorageFileID())
+	if err != nil {
+		return err
+	}
+	switch engineInfo.Status {
+	case engineFileStatusUploaded:
+	case engineFileStatusFailed:
+		m.finishUpload(ctx, fileID, false)
+		return ErrFileUploadFailed
+	default:
+		return ErrUploadNotConfirmed
+	}
+	if info.Size > 0 && engineInfo.Size > 0 && info.Size != engineInfo.Size {
+		m.finishUpload(ctx, fileID, false)
+		return ErrFileSizeMismatch
+	}
+
+	policy, _ := m.getUploadPolicy(info.Module, info.Type)
+	if policy != nil {
+		head, err := m.readHead(ctx, info)
+		if err != nil {
+			return err
+		}
+		sniffed, err := m.checkContent(info.Module, info.Type, head, engineInfo.Size)
+		if err != nil {
+			m.rejectUpload(ctx, info)
+			return err
+		}
+		err = m.dao.UpdateContentType(ctx, fileID, sniffed)
+		if err != nil {
+			return err
+		}
+	}
+
+	// 优先使用文件引擎计算的哈希，避免在确认流程中重新下载整个文件
+	size, sum := engineInfo.Size, strings.ToLower(engineInfo.SHA256)
+	if sum == "" {
+		size, sum, err = m.hashContent(ctx, info)
+		if err != nil {
+			return err
+		}
+	}
+	if info.ExpectedSHA256 != "" && !strings.EqualFold(info.ExpectedSHA256, sum) {
+		m.finishUpload(ctx, fileID, false)
+		return ErrFileHashMismatch
+	}
+
+	if size != info.Size || sum != info.SHA256 {
+		err = m.dao.UpdateContentInfo(ctx, fileID, size, sum)
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.confirmUpload(ctx, info, size)
+}
+
+// hashContent 流式读取存储中的文件内容，返回实际大小与 SHA-256
+func (m *FileManager) hashContent(ctx context.Context, info *FileInfo) (size int64, sum string, err error) {
+	preDownload, err := m.fileEngine.PreDownload(ctx, info.StorageFileID())
+	if err != nil {
+		return 0, "", err
+	}
+
+	body, _, err := m.fileEngine.GetContent(ctx, preDownload.DownloadURL)
+	if err != nil {
+		return 0, "", err
+	}
+	defer body.Close()
+
+	hash := sha256.New()
+	size, err = io.Copy(hash, body)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// confirmUpload 按写入存储的实际大小 size 确认上传成功，调用前文件记录已更新为实际大小
+// 重新校验上传策略的大小限制，并在确认事务中锁定统计用户配额(声明的大小可能为 0 或小于实际大小)；
+// 不满足时上传失败，并通过事件删除存储中的文件
+func (m *FileManager) confirmUpload(ctx context.Context, info *FileInfo, size int64) (err error) {
+	err = m.checkSize(info.Module, info.Type, size)
+	if err != nil {
+		m.rejectUpload(ctx, info)
+		return err
+	}
+
+	var rejected error
+	err = m.transitUpload(ctx, info.FileID, true, "", func(ctx context.Context, tx gdb.TX) error {
+		// 统计结果已包含本次上传的实际大小
+		rejected = m.checkQuota(ctx, tx, info.Module, info.Type, 0, info.OwnerID, "")
+		return rejected
+	})
+	if rejected != nil {
+		m.rejectUpload(ctx, info)
+		return rejected
+	}
+	return err
+}
+
+// rejectUpload 上传内容不符合策略：标记上传失败，并在同一事务中记录删除存储中文件的事件
+func (m *FileManager) rejectUpload(ctx context.Context, info *FileInfo) {
+	err := m.transitUpload(ctx, info.FileID, false, info.StorageFileID(), nil)
+	if err != nil {
+		m.logger.Errorf(ctx, "reject upload failed, fileID: %s, err: %v", info.FileID, err)
+	}
+}
+
+// finishUpload 记录上传结果：文件仍处于初始化状态时更新 t_file 状态，并在同一事务中记录向文件引擎上报结果的事件
+// 配置了内容扫描器时，上传成功的文件先进入扫描中状态，由扫描任务确认后再标记成功或隔离
+// 文件已由其他请求(重复的回调、对账)完成时不做处理，上报与回调只执行一次
+func (m *FileManager) finishUpload(ctx context.Context, fileID string, success bool) (err error) {
+	return m.transitUpload(ctx, fileID, success, "", nil)
+}
+
+// transitUpload 同 finishUpload；check 不为空时在更新状态前于同一事务中执行，失败时回滚并返回其错误，
+// deleteStorageID 不为空时在同一事务中记录删除该存储文件的事件
+func (m *FileManager) transitUpload(ctx context.Context, fileID string, success bool, deleteStorageID string, check func(ctx context.Context, tx gdb.TX) error) (err error) {
+	status := FileStatusUploadSuccess
+	if !success {
+		status = FileStatusUploadFailed
+	} else if m.config.Scanner != nil {
+		status = FileStatusScanning
+	}
+
+	var (
+		transited bool
+		report    *OutboxEvent
+		deletion  *OutboxEvent
+	)
+	err = m.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if check != nil {
+			if err := check(ctx, tx); err != nil {
+				return err
+			}
+		}
+
+		ok, err := m.dao.TransitStatus(ctx, tx, fileID, FileStatusInit, status)
+		if err != nil || !ok {
+			return err
+		}
+		transited = true
+		if deleteStorageID != "" {
+			deletion, err = m.enqueueOutbox(ctx, tx, OutboxOpDeleteFile, deleteStorageID, nil)
+			if err != nil {
+				return err
+			}
+		}
+		if status == FileStatusScanning {
+			return nil
+		}
+
+		report, err = m.enqueueOutbox(ctx, tx, OutboxOpReportUpload, fileID, &OutboxPayload{Success: success})
+		return err
+	})
+	if err != nil {
+		m.logger.Errorf(ctx, "update file status failed, fileID: %s, status: %s, err: %v", fileID, GetFileStatusText(status), err)
+		return err
+	}
+	if !transited || status == FileStatusScanning {
+		return nil
+	}
+
+	if deletion != nil {
+		m.dispatchOutbox(ctx, deletion)
+	}
+	return m.settleUpload(ctx, fileID, success, report)
+}
+
+// settleUpload 上传结果确定后：成功的版本设为当前版本，执行上报事件并触发上传完成回调
+// 上报失败不影响上传结果，由后台任务重试
+func (m *FileManager) settleUpload(ctx context.Context, fileID string, success bool, report *OutboxEvent) (err error) {
+	if success {
+		_, err = m.dao.PromoteVersion(ctx, fileID)
+		if err != nil {
+			m.logger.Errorf(ctx, "promote file version failed, fileID: %s, err: %v", fileID, err)
+			return err
+		}
+	}
+
+	m.dispatchOutbox(ctx, report)
+
+	m.fireUploadCallbacks(ctx, fileID, success)
+	return nil
+}
+
+// Download 流式下载文件，调用方负责关闭返回的 ReadCloser
+// 开启 EnableAccessControl 时与 PreDownload 一样按授权策略校验上下文中的当前用户
+func (m *FileManager) Download(ctx context.Context, fileID string) (body io.ReadCloser, info *FileInfo, err error) {
+	info, err = m.dao.Get(ctx, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = m.IsUploadSuccess(ctx, info); err != nil {
+		return nil, nil, err
+	}
+	if err = m.checkAccess(ctx, info, ""); err != nil {
+		return nil, nil, err
+	}
+
+	preDownload, err := m.fileEngine.PreDownload(ctx, info.StorageFileID())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body, _, err = m.fileEngine.GetContent(ctx, preDownload.DownloadURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return body, info, nil
+}
+
 func (m *FileManager) UpdateStatus(ctx context.Context, fileID string, status FileStatus) (err error) {
-	return m.dao.UpdateStatus(ctx, fileID, status)
+	return m.dao.UpdateStatus(ctx, nil, fileID, status)
 }
 
+// CreateAssociation 创建文件与业务实体的关联，同一文件可关联多个业务实体
 func (m *FileManager) CreateAssociation(ctx context.Context, tx gdb.TX, fileID string, module FileModule, customID string, typ FileType) (err error) {
-	return m.dao.CreateAssociation(ctx, tx, fileID, module, customID, typ)
+	return m.AttachFile(ctx, tx, fileID, module, customID, typ)
 }
 
 func (m *FileManager) CreateAssociationBatch(ctx context.Context, tx gdb.TX, fileInfos []*FileInfo) (err error) {
 	for _, fileInfo := range fileInfos {
-		err = m.dao.CreateAssociation(ctx, tx, fileInfo.FileID, fileInfo.Module, fileInfo.CustomID, fileInfo.Type)
+		err = m.AttachFile(ctx, tx, fileInfo.FileID, fileInfo.Module, fileInfo.CustomID, fileInfo.Type)
 		if err != nil {
 			return err
 		}
@@ -104,23 +601,47 @@ func (m *FileManager) CreateAssociationBatch(ctx context.Context, tx gdb.TX, fil
 	return nil
 }
 
+// ClearAssociation 解除业务实体与其全部文件的关联，不影响这些文件与其他业务实体的关联
 func (m *FileManager) ClearAssociation(ctx context.Context, tx gdb.TX, module FileModule, customID string) (err error) {
-	return m.dao.ClearCustomInfo(ctx, tx, module, customID)
+	associations, err := m.dao.ListEntityAssociations(ctx, tx, module, customID)
+	if err != nil {
+		return err
+	}
+	if len(associations) == 0 {
+		return ErrFileNotFound
+	}
+
+	for _, association := range associations {
+		_, err = m.dao.DetachFile(ctx, tx, association.FileID, association.Module, association.CustomID, association.Type)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (m *FileManager) Get(ctx context.Context, fileID string) (out *FileInfo, err error) {
 	return m.dao.Get(ctx, fileID)
 }
 
-func (m *FileManager) ListByModuleAndCustomID(ctx context.Context, module FileModule, customID string) (out []*FileInfo, err error) {
-	return m.dao.ListByModuleAndCustomID(ctx, module, customID)
+// ListByModuleAndCustomID 获取业务实体关联的文件，默认只返回当前版本
+func (m *FileManager) ListByModuleAndCustomID(ctx context.Context, module FileModule, customID string, opts ...*ListOptions) (out []*FileInfo, err error) {
+	return m.dao.ListByModuleAndCustomID(ctx, module, customID, withHistory(opts))
 }
 
-func (m *FileManager) ListByModuleAndCustomIDs(ctx context.Context, module FileModule, customIDs []string) (out map[string][]*FileInfo, err error) {
-	return m.dao.ListByModuleAndCustomIDs(ctx, module, customIDs)
+// ListByModuleAndCustomIDs 批量获取业务实体关联的文件，默认只返回当前版本
+func (m *FileManager) ListByModuleAndCustomIDs(ctx context.Context, module FileModule, customIDs []string, opts ...*ListOptions) (out map[string][]*FileInfo, err error) {
+	return m.dao.ListByModuleAndCustomIDs(ctx, module, customIDs, withHistory(opts))
+}
+
+func withHistory(opts []*ListOptions) bool {
+	return len(opts) > 0 && opts[0] != nil && opts[0].WithHistory
 }
 
 func (m *FileManager) IsUploadSuccess(ctx context.Context, fileInfo *FileInfo) (err error) {
+	if fileInfo.IsDeleted() {
+		return ErrFileDeleted
+	}
 	if fileInfo.Status != FileStatusUploadSuccess {
 		return ErrFileUploadFailed
 	}
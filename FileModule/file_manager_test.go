@@ -0,0 +1,305 @@
+package FileModule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/yyboo586/common/MiddleWare"
+)
+
+func Test_NewFileManager_InvalidConfig(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		m, err := NewFileManager(&Config{})
+		t.Assert(m, nil)
+		t.Assert(errors.Is(err, ErrInvalidConfig), true)
+	})
+}
+
+func Test_Config_Validate(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		config := &Config{FileEngineAddr: "http://engine"}
+		t.AssertNil(config.Validate())
+		t.Assert(config.TableName, "t_file")
+		t.Assert(config.Group, "default")
+	})
+}
+
+// newTransferHandler 模拟文件引擎的预签名上传/下载，content 为存储中的文件内容
+func newTransferHandler(t *testing.T, content string) http.HandlerFunc {
+	var baseURL string
+	return func(w http.ResponseWriter, r *http.Request) {
+		if baseURL == "" {
+			baseURL = "http://" + r.Host
+		}
+		switch {
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/upload-tokens"):
+			_, _ = fmt.Fprintf(w, `{"id":"f1","original_name":"a.txt","visit_url":"http://link/f1","upload_url":"%s/storage/f1"}`, baseURL)
+		case r.Method == http.MethodPut && r.URL.Path == "/storage/f1":
+			_, _ = io.Copy(io.Discard, r.Body)
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/download-tokens"):
+			_, _ = fmt.Fprintf(w, `{"download_url":"%s/storage/f1","expires_in":600}`, baseURL)
+		case r.Method == http.MethodGet && r.URL.Path == "/storage/f1":
+			_, _ = io.WriteString(w, content)
+		case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/status"),
+			r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/api/v1/file-engine/files/"):
+			_, _ = io.WriteString(w, `{}`)
+		default:
+			t.Errorf("unexpected engine request: %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+// expectCreateFile 在事务中校验配额并创建文件记录(未注册上传策略时不统计用量)
+func expectCreateFile(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO t_file").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("RELEASE SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+}
+
+// expectFinishUpload 上传结果落库、成功时提升为当前版本，并执行上报事件
+func expectFinishUpload(mock sqlmock.Sqlmock, success bool) {
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE t_file SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if success {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", IsCurrent: 1}))
+		mock.ExpectCommit()
+	}
+	mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func Test_Upload_SizeLimit(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		// 超出 MaxUploadSize 的内容在传输中被拒绝，错误可通过 errors.Is 判断
+		m, mock := newMockFileManager(t.T, &Config{MaxUploadSize: 5}, newTransferHandler(t.T, ""))
+		expectCreateFile(mock)
+		expectFinishUpload(mock, false)
+
+		_, err := m.Upload(ctx, strings.NewReader("hello world"), &UploadMeta{FileName: "a.txt"})
+		t.Assert(errors.Is(err, ErrFileTooLarge), true)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 实际内容短于声明大小
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, ""))
+		expectCreateFile(mock)
+		expectFinishUpload(mock, false)
+
+		_, err := m.Upload(ctx, strings.NewReader("hello"), &UploadMeta{FileName: "a.txt", Size: 10})
+		t.Assert(errors.Is(err, ErrFileSizeMismatch), true)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 未声明大小时按写入的实际大小校验上传策略，超出时上传失败并删除存储中的文件
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, ""))
+		m.RegisterUploadPolicy(1, 0, &UploadPolicy{MaxSize: 4})
+		expectCreateFile(mock)
+		mock.ExpectExec("UPDATE t_file SET .*sha256").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Module: 1, Status: int(FileStatusInit), Size: 5}))
+		expectRejectUpload(mock)
+
+		_, err := m.Upload(ctx, strings.NewReader("hello"), &UploadMeta{FileName: "a.txt", Module: 1})
+		t.Assert(err, ErrFileTooLarge)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+func Test_Download(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, "hello"))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusUploadSuccess), Size: 5}))
+
+		body, info, err := m.Download(ctx, "f1")
+		t.AssertNil(err)
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		t.AssertNil(err)
+		t.Assert(string(data), "hello")
+		t.Assert(info.FileID, "f1")
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 未上传成功的文件不可下载
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, "hello"))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusInit)}))
+
+		_, _, err := m.Download(ctx, "f1")
+		t.Assert(errors.Is(err, ErrFileUploadFailed), true)
+	})
+}
+
+func Test_PreUpload_Instant(t *testing.T) {
+	const sum = "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"
+	userCtx := context.WithValue(context.Background(), MiddleWare.CustomCtxKey, &MiddleWare.ContextUser{UserID: "u1"})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 只在上传者本人的文件中秒传，不修改调用方的请求
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, ""))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .*owner_id").
+			WithArgs(strings.ToLower(sum), 5, "u1", FileStatusUploadSuccess).
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f0", FileLink: "http://link/f0", Status: int(FileStatusUploadSuccess), StorageID: "f0", Size: 5, SHA256: strings.ToLower(sum), OwnerID: "u1"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f0", Status: int(FileStatusUploadSuccess), OwnerID: "u1"}))
+		mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO t_file").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 2, FileID: "f2", IsCurrent: 1}))
+		mock.ExpectExec("RELEASE SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		in := &PreUploadReq{FileName: "a.txt", Size: 5, SHA256: sum}
+		out, err := m.PreUpload(userCtx, in)
+		t.AssertNil(err)
+		t.Assert(out.Instant, true)
+		t.Assert(out.FileLink, "http://link/f0")
+		t.Assert(in.SHA256, sum)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 源记录在加锁前已被垃圾回收时按普通上传处理
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, ""))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .*owner_id").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f0", Status: int(FileStatusUploadSuccess), Size: 5, OwnerID: "u1"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f0", Status: int(FileStatusDeleted), OwnerID: "u1"}))
+		mock.ExpectRollback()
+		expectCreateFile(mock)
+
+		out, err := m.PreUpload(userCtx, &PreUploadReq{FileName: "a.txt", Size: 5, SHA256: sum})
+		t.AssertNil(err)
+		t.Assert(out.Instant, false)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 上传者未知时不秒传
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, ""))
+		expectCreateFile(mock)
+
+		out, err := m.PreUpload(context.Background(), &PreUploadReq{FileName: "a.txt", Size: 5, SHA256: sum})
+		t.AssertNil(err)
+		t.Assert(out.Instant, false)
+		t.Assert(out.FileID, "f1")
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 服务端直传不按声明的哈希秒传，写入实际内容后校验
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, ""))
+		expectCreateFile(mock)
+		expectFinishUpload(mock, false)
+
+		_, err := m.Upload(userCtx, strings.NewReader("world"), &UploadMeta{FileName: "a.txt", Size: 5, SHA256: sum})
+		t.Assert(errors.Is(err, ErrFileHashMismatch), true)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+func Test_CompleteUpload_RecordsSHA256(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		// 未声明哈希的预签名上传同样计算并记录 SHA-256
+		handler := newTransferHandler(t.T, "hello")
+		m, mock := newMockFileManager(t.T, nil, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/files/f1") {
+				_, _ = io.WriteString(w, `{"id":"f1","status":"uploaded","size":5}`)
+				return
+			}
+			handler(w, r)
+		})
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusInit), Size: 5}))
+		mock.ExpectExec("UPDATE t_file SET .*sha256").WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFinishUpload(mock, true)
+
+		err := m.CompleteUpload(ctx, "f1")
+		t.AssertNil(err)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 文件引擎返回哈希时直接记录，不读回文件内容
+		const sum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+		m, mock := newMockFileManager(t.T, &Config{}, func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/files/f1"):
+				_, _ = fmt.Fprintf(w, `{"id":"f1","status":"uploaded","size":5,"sha256":"%s"}`, strings.ToUpper(sum))
+			case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/status"):
+				_, _ = io.WriteString(w, `{}`)
+			default:
+				t.Errorf("unexpected engine request: %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		})
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusInit), Size: 5, ExpectedSHA256: sum}))
+		update := &argRecorder{}
+		mock.ExpectExec("UPDATE t_file SET .*sha256").WithArgs(update, update, update, "f1").WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFinishUpload(mock, true)
+
+		err := m.CompleteUpload(ctx, "f1")
+		t.AssertNil(err)
+		t.AssertNil(mock.ExpectationsWereMet())
+		t.Assert(update.contains(sum), true)
+	})
+}
+
+func Test_BatchCheckUploadSuccess(t *testing.T) {
+	cases := []struct {
+		name       string
+		fileIDs    []string
+		success    []string
+		notSuccess []string
+	}{
+		{name: "all success", fileIDs: []string{"f1", "f2"}, success: []string{"f2", "f1"}, notSuccess: nil},
+		{name: "missing, failed or trashed", fileIDs: []string{"f1", "f2", "f3"}, success: []string{"f2"}, notSuccess: []string{"f1", "f3"}},
+		{name: "duplicate ids", fileIDs: []string{"f1", "f1", "f2"}, success: []string{"f2"}, notSuccess: []string{"f1"}},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			m, mock := newMockFileManager(t.T, nil, nil)
+			rows := sqlmock.NewRows([]string{"file_id"})
+			for _, fileID := range c.success {
+				rows.AddRow(fileID)
+			}
+			// 只统计上传成功且未移入回收站的文件
+			mock.ExpectQuery("SELECT file_id FROM t_file WHERE .*status = \\?.*delete_time = 0").WillReturnRows(rows)
+
+			notSuccess, err := m.BatchCheckUploadSuccess(context.Background(), c.fileIDs)
+			t.AssertNil(err)
+			t.Assert(notSuccess, c.notSuccess)
+			t.AssertNil(mock.ExpectationsWereMet())
+		}
+	})
+}
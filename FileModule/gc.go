@@ -0,0 +1,173 @@
+package FileModule
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+const gcBatchSize = 100
+
+// CollectGarbage 回收孤儿文件：删除存储中的文件，并删除或墓碑化 t_file 记录
+// 与其他记录共享存储(秒传)的文件只处理记录，不删除存储
+func (m *FileManager) CollectGarbage(ctx context.Context, opts *GCOptions) (report *GCReport, err error) {
+	if opts == nil {
+		opts = &GCOptions{DryRun: m.config.GCDryRun}
+	}
+	gracePeriod := opts.GracePeriod
+	if gracePeriod <= 0 {
+		gracePeriod = m.config.GCGracePeriod
+	}
+
+	report = &GCReport{
+		DryRun: opts.DryRun,
+		Cutoff: time.Now().Add(-gracePeriod),
+		Items:  make([]*GCItem, 0),
+	}
+
+	var afterID int64
+	for {
+		limit := gcBatchSize
+		if opts.Limit > 0 && opts.Limit-report.Scanned < limit {
+			limit = opts.Limit - report.Scanned
+		}
+		if limit <= 0 {
+			return report, nil
+		}
+
+		candidates, err := m.dao.ListGCCandidates(ctx, report.Cutoff, afterID, limit)
+		if err != nil {
+			return report, err
+		}
+
+		for _, info := range candidates {
+			afterID = info.ID
+			report.Scanned++
+
+			item := m.collectFile(ctx, info, opts.DryRun)
+			if item.Error != "" {
+				report.Failed++
+			} else {
+				report.Collected++
+			}
+			report.Items = append(report.Items, item)
+		}
+
+		if len(candidates) < limit {
+			return report, nil
+		}
+	}
+}
+
+func (m *FileManager) collectFile(ctx context.Context, info *FileInfo, dryRun bool) (item *GCItem) {
+	item = &GCItem{
+		FileID:    info.FileID,
+		StorageID: info.StorageFileID(),
+		Status:    info.Status,
+		Reason:    gcReason(info),
+	}
+
+	if dryRun {
+		refs, err := m.dao.CountStorageReferences(ctx, nil, item.StorageID, info.FileID)
+		if err != nil {
+			item.Error = err.Error()
+			return item
+		}
+		item.BlobDeleted = refs == 0
+		return item
+	}
+
+	// 文件记录与存储删除事件在同一事务中提交，存储删除由事件异步执行并重试
+	// 锁定文件记录后重新统计存储引用：秒传创建新引用前锁定同一源记录，两者互斥，避免删除刚被引用的存储
+	var events []*OutboxEvent
+	err := m.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		locked, err := m.dao.LockFile(ctx, tx, info.FileID)
+		if err != nil {
+			return err
+		}
+		// 筛选后被关联、恢复或更新过的文件不再回收
+		if !locked.UpdateTime.Equal(info.UpdateTime) {
+			return ErrFileChanged
+		}
+
+		refs, err := m.dao.CountStorageReferences(ctx, tx, item.StorageID, info.FileID)
+		if err != nil {
+			return err
+		}
+		item.BlobDeleted = refs == 0
+
+		variantEvents, err := m.deleteVariants(ctx, tx, info.FileID)
+		if err != nil {
+			return err
+		}
+		events = append(events, variantEvents...)
+
+		if m.config.GCTombstone {
+			err = m.dao.UpdateStatus(ctx, tx, info.FileID, FileStatusDeleted)
+		} else {
+			err = m.dao.Delete(ctx, tx, info.FileID)
+		}
+		if err != nil {
+			return err
+		}
+
+		if item.BlobDeleted {
+			event, err := m.enqueueOutbox(ctx, tx, OutboxOpDeleteFile, item.StorageID, nil)
+			if err != nil {
+				return err
+			}
+			events = append(events, event)
+		}
+		return nil
+	})
+	if err != nil {
+		item.BlobDeleted = false
+		item.Error = err.Error()
+		m.logger.Errorf(ctx, "gc remove file record failed, fileID: %s, err: %v", info.FileID, err)
+		return item
+	}
+	m.dispatchOutbox(ctx, events...)
+
+	err = m.cleanupLogicalFile(ctx, info.LogicalFileID())
+	if err != nil {
+		item.Error = err.Error()
+		m.logger.Errorf(ctx, "gc cleanup logical file failed, fileID: %s, err: %v", info.FileID, err)
+	}
+
+	return item
+}
+
+// cleanupLogicalFile 逻辑文件的全部版本都已删除时，删除其业务关联、元数据与标签
+func (m *FileManager) cleanupLogicalFile(ctx context.Context, logicalID string) (err error) {
+	remaining, err := m.dao.ListVersions(ctx, logicalID)
+	if err != nil || len(remaining) > 0 {
+		return err
+	}
+
+	err = m.dao.DeleteAssociations(ctx, nil, logicalID)
+	if err != nil {
+		return err
+	}
+	err = m.dao.DeleteMetadata(ctx, nil, logicalID, nil)
+	if err != nil {
+		return err
+	}
+	return m.dao.RemoveTags(ctx, nil, logicalID, nil)
+}
+
+// gcReason 孤儿文件的回收原因
+func gcReason(info *FileInfo) string {
+	switch {
+	case info.IsDeleted():
+		return "trash expired"
+	case info.Status == FileStatusInit:
+		return "stuck in init"
+	case info.Status == FileStatusUploadFailed:
+		return "upload failed"
+	case info.Status == FileStatusQuarantined:
+		return "quarantined"
+	default:
+		return "unassociated"
+	}
+}
@@ -0,0 +1,174 @@
+package FileModule
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+// insertValues 解析 INSERT 语句中各字段的值
+func insertValues(sql string) map[string]string {
+	open := strings.Index(sql, "(")
+	valuesAt := strings.Index(sql, " VALUES(")
+	columns := strings.Split(sql[open+1:valuesAt-1], ",")
+	values := strings.Split(strings.TrimSuffix(strings.TrimSpace(sql[valuesAt+len(" VALUES("):]), ")"), ",")
+
+	out := make(map[string]string, len(columns))
+	for i, column := range columns {
+		out[column] = values[i]
+	}
+	return out
+}
+
+func Test_Create_RequireRef(t *testing.T) {
+	ctx := context.Background()
+	cases := []struct {
+		name       string
+		info       *FileInfo
+		requireRef string
+	}{
+		{name: "no module", info: &FileInfo{FileID: "f1"}, requireRef: "0"},
+		{name: "declared module", info: &FileInfo{FileID: "f1", Module: 1}, requireRef: "1"},
+		{name: "inherited from current version", info: &FileInfo{FileID: "f2", LogicalID: "f1", RequireRef: true}, requireRef: "1"},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		m, _ := newMockFileManager(t.T, nil, nil)
+		for _, c := range cases {
+			sql, err := gdb.ToSQL(ctx, func(ctx context.Context) error {
+				return m.dao.Create(ctx, nil, c.info)
+			})
+			t.AssertNil(err)
+			t.Assert(insertValues(sql)["require_ref"], c.requireRef)
+		}
+	})
+}
+
+func Test_ListGCCandidates(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		m, _ := newMockFileManager(t.T, nil, nil)
+		sql, err := gdb.ToSQL(ctx, func(ctx context.Context) error {
+			_, err := m.dao.ListGCCandidates(ctx, time.Unix(1000, 0), 10, 100)
+			return err
+		})
+		t.AssertNil(err)
+
+		for _, condition := range []string{
+			"(id > 10)",
+			"(update_time < 1000)",
+			"(delete_time = 0)",
+			// 未声明业务模块且从未关联的文件不因引用数为0被回收
+			"((status IN (0,2,4)) OR (ref_count = 0 AND require_ref = 1))",
+			"file_id NOT IN ((SELECT file_id FROM t_file_multipart WHERE status = 0))",
+		} {
+			t.Assert(strings.Contains(sql, condition), true)
+		}
+	})
+}
+
+func Test_GCReason(t *testing.T) {
+	cases := []struct {
+		info   *FileInfo
+		reason string
+	}{
+		{info: &FileInfo{Status: FileStatusUploadSuccess, DeleteTime: time.Unix(1, 0)}, reason: "trash expired"},
+		{info: &FileInfo{Status: FileStatusInit}, reason: "stuck in init"},
+		{info: &FileInfo{Status: FileStatusUploadFailed}, reason: "upload failed"},
+		{info: &FileInfo{Status: FileStatusQuarantined}, reason: "quarantined"},
+		{info: &FileInfo{Status: FileStatusUploadSuccess}, reason: "unassociated"},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			t.Assert(gcReason(c.info), c.reason)
+		}
+	})
+}
+
+func Test_CollectGarbage(t *testing.T) {
+	ctx := context.Background()
+	updated := time.Now().Add(-48 * time.Hour).Unix()
+	candidate := &FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusUploadSuccess), RequireRef: 1, UpdateTime: updated}
+
+	gtest.C(t, func(t *gtest.T) {
+		// 没有其他引用时删除记录与存储，并清理逻辑文件的附属数据
+		m, mock := newMockFileManager(t.T, nil, newTransferHandler(t.T, ""))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows(candidate))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").WillReturnRows(fileRows(candidate))
+		mock.ExpectQuery("SELECT COUNT.* FROM t_file WHERE .* FOR UPDATE").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery("SELECT .* FROM t_file_variant WHERE").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("DELETE FROM t_file_variant").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM t_file WHERE").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows())
+		mock.ExpectExec("DELETE FROM t_file_association").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM t_file_meta").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM t_file_tag").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		report, err := m.CollectGarbage(ctx, &GCOptions{})
+		t.AssertNil(err)
+		t.Assert(report.Collected, 1)
+		t.Assert(report.Items[0].BlobDeleted, true)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 存储在删除事务中被新记录引用(秒传)时只删除记录
+		m, mock := newMockFileManager(t.T, nil, nil)
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows(candidate))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").WillReturnRows(fileRows(candidate))
+		mock.ExpectQuery("SELECT COUNT.* FROM t_file WHERE .* FOR UPDATE").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("SELECT .* FROM t_file_variant WHERE").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("DELETE FROM t_file_variant").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM t_file WHERE").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows(&FileInfoEntity{ID: 2, FileID: "f2", LogicalID: "f1"}))
+
+		report, err := m.CollectGarbage(ctx, &GCOptions{})
+		t.AssertNil(err)
+		t.Assert(report.Collected, 1)
+		t.Assert(report.Items[0].BlobDeleted, false)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 筛选后被关联的文件不再回收
+		m, mock := newMockFileManager(t.T, nil, nil)
+		attached := *candidate
+		attached.RefCount, attached.UpdateTime = 1, time.Now().Unix()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows(candidate))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").WillReturnRows(fileRows(&attached))
+		mock.ExpectRollback()
+
+		report, err := m.CollectGarbage(ctx, &GCOptions{})
+		t.AssertNil(err)
+		t.Assert(report.Failed, 1)
+		t.Assert(report.Items[0].Error, ErrFileChanged.Error())
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 仅生成报告时不删除
+		m, mock := newMockFileManager(t.T, nil, nil)
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows(candidate))
+		mock.ExpectQuery("SELECT COUNT.* FROM t_file WHERE").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		report, err := m.CollectGarbage(ctx, &GCOptions{DryRun: true})
+		t.AssertNil(err)
+		t.Assert(report.Items[0].BlobDeleted, true)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,123 @@
+package FileModule
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"path"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	variantFormatJPEG = "jpeg"
+	variantFormatPNG  = "png"
+
+	defaultVariantQuality = 85
+)
+
+// renderVariant 按规格解码、缩放并编码图片，返回编码后的内容及尺寸
+func renderVariant(src []byte, profile *VariantProfile, maxPixels int) (out []byte, contentType string, width int, height int, err error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(src))
+	if err != nil {
+		return nil, "", 0, 0, err
+	}
+	if maxPixels > 0 && cfg.Width*cfg.Height > maxPixels {
+		return nil, "", 0, 0, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, "", 0, 0, err
+	}
+
+	format := variantFormat(profile)
+	dst := resizeImage(img, profile, format == variantFormatJPEG)
+
+	var buf bytes.Buffer
+	switch format {
+	case variantFormatPNG:
+		contentType = "image/png"
+		err = png.Encode(&buf, dst)
+	default:
+		contentType = "image/jpeg"
+		quality := profile.Quality
+		if quality <= 0 || quality > 100 {
+			quality = defaultVariantQuality
+		}
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+	}
+	if err != nil {
+		return nil, "", 0, 0, err
+	}
+
+	return buf.Bytes(), contentType, dst.Bounds().Dx(), dst.Bounds().Dy(), nil
+}
+
+// resizeImage 按规格缩放图片，不放大原图；opaque 为 true 时以白色填充透明区域(JPEG 不支持透明)
+func resizeImage(src image.Image, profile *VariantProfile, opaque bool) image.Image {
+	srcRect := src.Bounds()
+	sw, sh := float64(srcRect.Dx()), float64(srcRect.Dy())
+	tw, th := float64(profile.Width), float64(profile.Height)
+	if tw <= 0 && th <= 0 {
+		tw, th = sw, sh
+	}
+	if tw <= 0 {
+		tw = sw * th / sh
+	}
+	if th <= 0 {
+		th = sh * tw / sw
+	}
+
+	var scale float64
+	if profile.Mode == VariantModeFill {
+		scale = math.Max(tw/sw, th/sh)
+		// 居中裁剪出与目标宽高比一致的区域
+		cw, ch := tw/scale, th/scale
+		x0 := srcRect.Min.X + int((sw-cw)/2)
+		y0 := srcRect.Min.Y + int((sh-ch)/2)
+		srcRect = image.Rect(x0, y0, x0+int(math.Round(cw)), y0+int(math.Round(ch)))
+	} else {
+		scale = math.Min(tw/sw, th/sh)
+	}
+	if scale > 1 {
+		scale = 1
+	}
+
+	dw := int(math.Max(1, math.Round(float64(srcRect.Dx())*scale)))
+	dh := int(math.Max(1, math.Round(float64(srcRect.Dy())*scale)))
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	if opaque {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	}
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, srcRect, draw.Over, nil)
+
+	return dst
+}
+
+func variantFormat(profile *VariantProfile) string {
+	if strings.EqualFold(profile.Format, variantFormatPNG) {
+		return variantFormatPNG
+	}
+	return variantFormatJPEG
+}
+
+// variantFileName 衍生图文件名：原文件名_规格名.扩展名
+func variantFileName(fileName string, profile *VariantProfile) string {
+	ext := ".jpg"
+	if variantFormat(profile) == variantFormatPNG {
+		ext = ".png"
+	}
+
+	base := strings.TrimSuffix(fileName, path.Ext(fileName))
+	if base == "" {
+		base = "image"
+	}
+	return base + "_" + profile.Name + ext
+}
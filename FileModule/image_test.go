@@ -0,0 +1,59 @@
+package FileModule
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func newTestPNG(width, height int) []byte {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height)))
+	return buf.Bytes()
+}
+
+func Test_RenderVariant(t *testing.T) {
+	src := newTestPNG(400, 200)
+
+	gtest.C(t, func(t *gtest.T) {
+		out, contentType, width, height, err := renderVariant(src, &VariantProfile{Name: "thumb", Width: 100, Height: 100}, 0)
+		t.AssertNil(err)
+		t.Assert(contentType, "image/jpeg")
+		t.Assert(width, 100)
+		t.Assert(height, 50)
+
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(out))
+		t.AssertNil(err)
+		t.Assert(format, "jpeg")
+		t.Assert(cfg.Width, 100)
+	})
+	gtest.C(t, func(t *gtest.T) {
+		_, contentType, width, height, err := renderVariant(src, &VariantProfile{Name: "square", Width: 100, Height: 100, Mode: VariantModeFill, Format: "png"}, 0)
+		t.AssertNil(err)
+		t.Assert(contentType, "image/png")
+		t.Assert(width, 100)
+		t.Assert(height, 100)
+	})
+	gtest.C(t, func(t *gtest.T) {
+		// 不放大原图
+		_, _, width, height, err := renderVariant(src, &VariantProfile{Name: "large", Width: 1000}, 0)
+		t.AssertNil(err)
+		t.Assert(width, 400)
+		t.Assert(height, 200)
+	})
+	gtest.C(t, func(t *gtest.T) {
+		_, _, _, _, err := renderVariant(src, &VariantProfile{Name: "thumb", Width: 100}, 1000)
+		t.Assert(err, ErrImageTooLarge)
+	})
+}
+
+func Test_VariantFileName(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(variantFileName("logo.png", &VariantProfile{Name: "thumb"}), "logo_thumb.jpg")
+		t.Assert(variantFileName("banner.final.jpeg", &VariantProfile{Name: "web", Format: "png"}), "banner.final_web.png")
+		t.Assert(variantFileName("", &VariantProfile{Name: "thumb"}), "image_thumb.jpg")
+	})
+}
@@ -2,31 +2,126 @@ package FileModule
 
 import (
 	"context"
+	"io"
 
 	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/net/ghttp"
 )
 
 type IFileManager interface {
+	// 启动后台任务(过期分片清理等)
+	Start() error
+	// 停止后台任务
+	Stop()
+
 	// 获取文件上传链接
 	PreUpload(ctx context.Context, in *PreUploadReq) (out *PreUploadRes, err error)
 	// 获取文件下载链接
 	PreDownload(ctx context.Context, fileID string) (out *PreDownloadRes, err error)
+	// 获取文件衍生图的下载链接
+	PreDownloadVariant(ctx context.Context, fileID string, variant string) (out *PreDownloadRes, err error)
+	// 批量获取文件下载链接(一次文件引擎请求，返回以文件ID为键的结果)
+	BatchPreDownload(ctx context.Context, fileIDs []string) (out map[string]*PreDownloadRes, err error)
+
+	// 服务端直传文件(流式上传，自动记录 t_file 及状态)
+	Upload(ctx context.Context, r io.Reader, meta *UploadMeta) (out *FileInfo, err error)
+	// 服务端流式下载文件，调用方负责关闭 body
+	Download(ctx context.Context, fileID string) (body io.ReadCloser, info *FileInfo, err error)
+
+	// 确认上传完成(向文件引擎确认，声明了SHA-256时校验文件内容)
+	CompleteUpload(ctx context.Context, fileID string) (err error)
+	// 执行到期的存储副作用事件(删除存储文件、上报上传结果)
+	DispatchOutbox(ctx context.Context) (count int, err error)
+	// 扫描等待内容扫描的文件(通过则标记上传成功，发现威胁则隔离)
+	ScanUploads(ctx context.Context) (count int, err error)
+	// 对账：批量确认处于初始化状态的文件
+	ReconcileUploads(ctx context.Context) (count int, err error)
+	// 接收文件引擎的上传完成回调(webhook)
+	HandleUploadWebhook(r *ghttp.Request)
+	// 注册上传完成回调，module/typ 为 0 表示匹配任意值
+	RegisterUploadCallback(module FileModule, typ FileType, callback UploadCallback)
+
+	// 初始化分片上传
+	InitMultipartUpload(ctx context.Context, in *InitMultipartUploadReq) (out *InitMultipartUploadRes, err error)
+	// 获取分片上传会话及已上传分片(断点续传)
+	GetMultipartUpload(ctx context.Context, fileID string) (out *MultipartUploadInfo, err error)
+	// 获取分片上传链接
+	GetMultipartPartURLs(ctx context.Context, fileID string, partNumbers []int) (out []*MultipartPartURL, err error)
+	// 上报已上传分片
+	ReportMultipartPart(ctx context.Context, fileID string, part *MultipartPart) (err error)
+	// 完成分片上传
+	CompleteMultipartUpload(ctx context.Context, fileID string) (err error)
+	// 取消分片上传
+	AbortMultipartUpload(ctx context.Context, fileID string) (err error)
+	// 清理过期的分片上传
+	CleanupMultipartUploads(ctx context.Context) (count int, err error)
+
+	// 注册上传校验策略，module/typ 为 0 表示匹配任意值
+	RegisterUploadPolicy(module FileModule, typ FileType, policy *UploadPolicy)
+
+	// 注册下载授权策略，module/typ 为 0 表示匹配任意值
+	RegisterAccessPolicy(module FileModule, typ FileType, policy AccessPolicy)
+
+	// 注册文件类型的衍生图规格(缩略图、Web优化图等)
+	RegisterVariantProfiles(typ FileType, profiles ...*VariantProfile) error
+	// 获取文件的衍生图
+	ListVariants(ctx context.Context, fileID string) (out []*FileVariant, err error)
+	// 生成待处理的衍生图
+	ProcessVariants(ctx context.Context) (count int, err error)
+
+	// 回收孤儿文件(未关联、初始化超时、上传失败)
+	CollectGarbage(ctx context.Context, opts *GCOptions) (report *GCReport, err error)
 
 	// 更新文件状态
 	UpdateStatus(ctx context.Context, fileID string, status FileStatus) (err error)
-	// 创建文件关联（部分场景下，预上传文件时，不会将文件与业务关联，需要后续创建关联）
+	// 创建文件关联（部分场景下，预上传文件时，不会将文件与业务关联，需要后续创建关联；同一文件可关联多个业务实体）
 	CreateAssociation(ctx context.Context, tx gdb.TX, fileID string, module FileModule, customID string, typ FileType) (err error)
 	// 批量创建文件关联
 	CreateAssociationBatch(ctx context.Context, tx gdb.TX, fileInfos []*FileInfo) (err error)
-	// 清除文件 自定义属性
+	// 解除业务实体与其全部文件的关联
 	ClearAssociation(ctx context.Context, tx gdb.TX, module FileModule, customID string) (err error)
+	// 关联文件与业务实体(引用数加一)
+	AttachFile(ctx context.Context, tx gdb.TX, fileID string, module FileModule, customID string, typ FileType) (err error)
+	// 解除文件与业务实体的关联(引用数减一，降为0后由垃圾回收删除)
+	DetachFile(ctx context.Context, tx gdb.TX, fileID string, module FileModule, customID string, typ FileType) (err error)
+	// 获取文件的全部关联
+	ListAssociations(ctx context.Context, fileID string) (out []*FileAssociation, err error)
+
+	// 删除文件(移入回收站，保留期内可恢复)
+	Delete(ctx context.Context, tx gdb.TX, fileID string) (err error)
+	// 从回收站恢复文件
+	Restore(ctx context.Context, tx gdb.TX, fileID string) (err error)
+	// 彻底删除回收站中超过保留期的文件
+	PurgeTrash(ctx context.Context) (count int, err error)
+	// 获取文件的全部版本(按版本号降序)
+	ListVersions(ctx context.Context, fileID string) (out []*FileInfo, err error)
+
+	// 写入文件元数据(已存在的键覆盖)
+	SetMetadata(ctx context.Context, tx gdb.TX, fileID string, metadata map[string]string) (err error)
+	// 获取文件元数据
+	GetMetadata(ctx context.Context, fileID string) (out map[string]string, err error)
+	// 删除文件元数据，未指定 keys 时删除全部
+	DeleteMetadata(ctx context.Context, tx gdb.TX, fileID string, keys ...string) (err error)
+	// 为文件添加标签
+	AddTags(ctx context.Context, tx gdb.TX, fileID string, tags ...string) (err error)
+	// 删除文件标签，未指定 tags 时删除全部
+	RemoveTags(ctx context.Context, tx gdb.TX, fileID string, tags ...string) (err error)
+	// 获取文件标签
+	ListTags(ctx context.Context, fileID string) (out []string, err error)
+	// 按条件分页搜索文件
+	Search(ctx context.Context, filter *FileSearchFilter) (out []*FileInfo, total int, err error)
 
 	// 获取文件
 	Get(ctx context.Context, fileID string) (out *FileInfo, err error)
-	// 按模块与自定义ID获取文件列表
-	ListByModuleAndCustomID(ctx context.Context, module FileModule, customID string) (out []*FileInfo, err error)
-	// 按模块与自定义IDs获取文件列表
-	ListByModuleAndCustomIDs(ctx context.Context, module FileModule, customIDs []string) (out map[string][]*FileInfo, err error)
+	// 按模块与自定义ID获取文件列表(默认只返回当前版本)
+	ListByModuleAndCustomID(ctx context.Context, module FileModule, customID string, opts ...*ListOptions) (out []*FileInfo, err error)
+	// 按模块与自定义IDs获取文件列表(默认只返回当前版本)
+	ListByModuleAndCustomIDs(ctx context.Context, module FileModule, customIDs []string, opts ...*ListOptions) (out map[string][]*FileInfo, err error)
+
+	// 注册文件类型在归档导出中的目录名
+	RegisterArchiveFolder(typ FileType, folder string) error
+	// 将业务实体关联的全部上传成功的文件打包为 zip 流式写入 w
+	ExportArchive(ctx context.Context, module FileModule, customIDs []string, w io.Writer) (err error)
 
 	// 检查文件是否上传成功
 	IsUploadSuccess(ctx context.Context, fileInfo *FileInfo) (err error)
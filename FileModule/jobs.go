@@ -0,0 +1,161 @@
+package FileModule
+
+import (
+	"context"
+	"time"
+)
+
+// backgroundJob 文件管理器后台周期任务
+type backgroundJob struct {
+	name     string
+	interval time.Duration
+	run      func(ctx context.Context) error
+}
+
+// backgroundJobs 返回需要启动的后台任务，interval <= 0 的任务不启动
+func (m *FileManager) backgroundJobs() []backgroundJob {
+	return []backgroundJob{
+		{
+			name:     "multipart-cleanup",
+			interval: m.config.MultipartCleanupInterval,
+			run: func(ctx context.Context) error {
+				count, err := m.CleanupMultipartUploads(ctx)
+				if count > 0 {
+					m.logger.Infof(ctx, "cleanup %d expired multipart uploads", count)
+				}
+				return err
+			},
+		},
+		{
+			name:     "trash-purge",
+			interval: m.config.TrashPurgeInterval,
+			run: func(ctx context.Context) error {
+				count, err := m.PurgeTrash(ctx)
+				if count > 0 {
+					m.logger.Infof(ctx, "purge %d files from trash", count)
+				}
+				return err
+			},
+		},
+		{
+			name:     "reconcile",
+			interval: m.config.ReconcileInterval,
+			run: func(ctx context.Context) error {
+				count, err := m.ReconcileUploads(ctx)
+				if count > 0 {
+					m.logger.Infof(ctx, "reconcile %d uploads", count)
+				}
+				return err
+			},
+		},
+		{
+			name:     "outbox",
+			interval: m.config.OutboxInterval,
+			run: func(ctx context.Context) error {
+				count, err := m.DispatchOutbox(ctx)
+				if count > 0 {
+					m.logger.Infof(ctx, "dispatch %d outbox events", count)
+				}
+				return err
+			},
+		},
+		{
+			name:     "scan",
+			interval: m.config.ScanInterval,
+			run: func(ctx context.Context) error {
+				count, err := m.ScanUploads(ctx)
+				if count > 0 {
+					m.logger.Infof(ctx, "scan %d uploads", count)
+				}
+				return err
+			},
+		},
+		{
+			name:     "variants",
+			interval: m.config.VariantInterval,
+			run: func(ctx context.Context) error {
+				_, err := m.ProcessVariants(ctx)
+				return err
+			},
+		},
+		{
+			name:     "gc",
+			interval: m.config.GCInterval,
+			run: func(ctx context.Context) error {
+				report, err := m.CollectGarbage(ctx, nil)
+				if report != nil && report.Scanned > 0 {
+					m.logger.Infof(ctx, "gc finished, dryRun: %v, scanned: %d, collected: %d, failed: %d",
+						report.DryRun, report.Scanned, report.Collected, report.Failed)
+				}
+				return err
+			},
+		},
+	}
+}
+
+// Start 启动后台任务
+func (m *FileManager) Start() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.cancel != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+
+	for _, job := range m.backgroundJobs() {
+		if job.interval <= 0 {
+			continue
+		}
+		m.wg.Add(1)
+		go m.runJob(ctx, job)
+	}
+
+	return nil
+}
+
+// Stop 停止后台任务，等待正在执行的任务结束
+func (m *FileManager) Stop() {
+	m.mutex.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mutex.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	m.wg.Wait()
+}
+
+func (m *FileManager) runJob(ctx context.Context, job backgroundJob) {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.runJobOnce(ctx, job)
+		}
+	}
+}
+
+// runJobOnce 执行一次任务，panic 只影响本次执行，任务在下一个周期继续运行
+func (m *FileManager) runJobOnce(ctx context.Context, job backgroundJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			m.logger.Errorf(ctx, "[%s] job panic: %v", job.name, r)
+		}
+	}()
+
+	if err := job.run(ctx); err != nil {
+		m.logger.Errorf(ctx, "[%s] job failed: %v", job.name, err)
+	}
+}
@@ -0,0 +1,40 @@
+package FileModule
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_RunJob_Panic(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		logger := glog.New()
+		logger.SetLevel(glog.LEVEL_CRIT)
+		m := &FileManager{logger: logger}
+
+		// 第一次执行 panic 后任务继续按周期执行
+		var runs int32
+		job := backgroundJob{name: "test", interval: 5 * time.Millisecond, run: func(ctx context.Context) error {
+			if atomic.AddInt32(&runs, 1) == 1 {
+				panic("boom")
+			}
+			return nil
+		}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		m.wg.Add(1)
+		go m.runJob(ctx, job)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for atomic.LoadInt32(&runs) < 3 && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		cancel()
+		m.wg.Wait()
+		t.AssertGE(atomic.LoadInt32(&runs), 3)
+	})
+}
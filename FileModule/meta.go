@@ -0,0 +1,165 @@
+package FileModule
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+const (
+	// 元数据键与标签的最大长度(字符)
+	maxMetaKeyLength = 64
+	// 开启访问控制时搜索每批取出的文件数
+	searchAccessBatchSize = 200
+)
+
+// SetMetadata 写入文件元数据(如上传者、描述、EXIF 字段)，已存在的键覆盖
+// 元数据属于逻辑文件，各版本共享
+func (m *FileManager) SetMetadata(ctx context.Context, tx gdb.TX, fileID string, metadata map[string]string) (err error) {
+	for key := range metadata {
+		if key == "" || utf8.RuneCountInString(key) > maxMetaKeyLength {
+			return ErrInvalidMetadata
+		}
+	}
+
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	return m.dao.SetMetadata(ctx, tx, info.LogicalFileID(), metadata)
+}
+
+// GetMetadata 获取文件的全部元数据
+func (m *FileManager) GetMetadata(ctx context.Context, fileID string) (out map[string]string, err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.dao.GetMetadata(ctx, info.LogicalFileID())
+}
+
+// DeleteMetadata 删除文件的元数据，未指定 keys 时删除全部
+func (m *FileManager) DeleteMetadata(ctx context.Context, tx gdb.TX, fileID string, keys ...string) (err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	return m.dao.DeleteMetadata(ctx, tx, info.LogicalFileID(), keys)
+}
+
+// AddTags 为文件添加标签，标签去除首尾空白后去重
+func (m *FileManager) AddTags(ctx context.Context, tx gdb.TX, fileID string, tags ...string) (err error) {
+	tags, err = normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	return m.dao.AddTags(ctx, tx, info.LogicalFileID(), tags)
+}
+
+// RemoveTags 删除文件的标签，未指定 tags 时删除全部
+func (m *FileManager) RemoveTags(ctx context.Context, tx gdb.TX, fileID string, tags ...string) (err error) {
+	tags, err = normalizeTags(tags)
+	if err != nil {
+		return err
+	}
+
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	return m.dao.RemoveTags(ctx, tx, info.LogicalFileID(), tags)
+}
+
+// ListTags 获取文件的标签
+func (m *FileManager) ListTags(ctx context.Context, fileID string) (out []string, err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.dao.ListTags(ctx, info.LogicalFileID())
+}
+
+// Search 按条件分页搜索文件(未删除文件的当前版本)，返回当前页及总数
+// 开启 EnableAccessControl 时只返回当前用户有权访问的文件，总数同样只统计有权访问的文件
+func (m *FileManager) Search(ctx context.Context, filter *FileSearchFilter) (out []*FileInfo, total int, err error) {
+	if filter == nil {
+		filter = &FileSearchFilter{}
+	}
+
+	filter.Tags, err = normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !m.config.EnableAccessControl {
+		return m.dao.Search(ctx, filter)
+	}
+	return m.searchAccessible(ctx, filter)
+}
+
+// searchAccessible 分批取出全部匹配的文件，按授权策略过滤后分页
+// 授权策略为任意函数，无法转换为查询条件，只能逐个校验
+func (m *FileManager) searchAccessible(ctx context.Context, filter *FileSearchFilter) (out []*FileInfo, total int, err error) {
+	if filter.Page <= 0 {
+		filter.Page = 1
+	}
+	if filter.Size <= 0 {
+		filter.Size = 10
+	}
+	offset := (filter.Page - 1) * filter.Size
+
+	out = []*FileInfo{}
+	batch := *filter
+	batch.Size = searchAccessBatchSize
+	for batch.Page = 1; ; batch.Page++ {
+		files, matched, err := m.dao.Search(ctx, &batch)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		for _, info := range files {
+			if m.authorize(ctx, info, "") != nil {
+				continue
+			}
+			if total >= offset && len(out) < filter.Size {
+				out = append(out, info)
+			}
+			total++
+		}
+
+		if len(files) < batch.Size || batch.Page*batch.Size >= matched {
+			return out, total, nil
+		}
+	}
+}
+
+// normalizeTags 去除标签首尾空白并去重，空标签或超长标签不合法
+func normalizeTags(tags []string) (out []string, err error) {
+	out = make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || utf8.RuneCountInString(tag) > maxMetaKeyLength {
+			return nil, ErrInvalidMetadata
+		}
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out, nil
+}
@@ -0,0 +1,29 @@
+package FileModule
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_NormalizeTags(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		tags, err := normalizeTags([]string{" 风景 ", "cat", "风景", "cat"})
+		t.AssertNil(err)
+		t.Assert(tags, []string{"风景", "cat"})
+
+		_, err = normalizeTags([]string{"ok", "  "})
+		t.Assert(err, ErrInvalidMetadata)
+
+		_, err = normalizeTags([]string{strings.Repeat("a", maxMetaKeyLength+1)})
+		t.Assert(err, ErrInvalidMetadata)
+	})
+}
+
+func Test_EscapeLike(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(escapeLike("50%_off"), `50\%\_off`)
+		t.Assert(escapeLike(`a\b`), `a\\b`)
+	})
+}
@@ -1,6 +1,10 @@
 package FileModule
 
-import "time"
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
 
 // FileModule 文件所属业务模块
 type FileModule int
@@ -15,6 +19,9 @@ const (
 	FileStatusInit          FileStatus = iota // Init
 	FileStatusUploadSuccess                   // Upload Success
 	FileStatusUploadFailed                    // Upload Failed
+	FileStatusDeleted                         // Deleted (垃圾回收后的墓碑记录)
+	FileStatusQuarantined                     // Quarantined (内容扫描发现威胁，已隔离)
+	FileStatusScanning                        // Scanning (已上传，等待内容扫描)
 )
 
 func GetFileStatusText(status FileStatus) string {
@@ -25,22 +32,47 @@ func GetFileStatusText(status FileStatus) string {
 		return "Upload Success"
 	case FileStatusUploadFailed:
 		return "Upload Failed"
+	case FileStatusDeleted:
+		return "Deleted"
+	case FileStatusQuarantined:
+		return "Quarantined"
+	case FileStatusScanning:
+		return "Scanning"
 	default:
 		return "Unknown File Status"
 	}
 }
 
 type FileInfoEntity struct {
-	ID         int64  `json:"id"`
-	Module     int    `json:"module"`
-	CustomID   string `json:"custom_id"`
-	Type       int    `json:"type"`
-	FileID     string `json:"file_id"`
-	FileName   string `json:"file_name"`
-	FileLink   string `json:"file_link"`
-	Status     int    `json:"status"`
-	CreateTime int64  `json:"create_time"`
-	UpdateTime int64  `json:"update_time"`
+	ID       int64  `json:"id"`
+	Module   int    `json:"module"`
+	CustomID string `json:"custom_id"`
+	Type     int    `json:"type"`
+	FileID   string `json:"file_id"`
+	FileName string `json:"file_name" orm:"file_orininal_name"`
+	FileLink string `json:"file_link"`
+	Status   int    `json:"status"`
+
+	StorageID   string `json:"storage_id"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+
+	ExpectedSHA256 string `json:"expected_sha256"`
+	OwnerID        string `json:"owner_id"`
+	OrgID          string `json:"org_id"`
+	RefCount       int    `json:"ref_count"`
+	RequireRef     int    `json:"require_ref"`
+
+	LogicalID  string `json:"logical_id"`
+	Version    int    `json:"version"`
+	IsCurrent  int    `json:"is_current"`
+	DeleteTime int64  `json:"delete_time"`
+
+	ScanAttempts int `json:"scan_attempts"`
+
+	CreateTime int64 `json:"create_time"`
+	UpdateTime int64 `json:"update_time"`
 }
 
 type FileInfo struct {
@@ -54,11 +86,65 @@ type FileInfo struct {
 	FileLink string     `json:"file_link"`
 	Status   FileStatus `json:"status"`
 
+	StorageID   string `json:"storage_id"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+
+	// ExpectedSHA256 上传方声明的哈希，上传完成校验通过后写入 SHA256
+	ExpectedSHA256 string `json:"expected_sha256"`
+	// OwnerID 上传者ID
+	OwnerID string `json:"owner_id"`
+	// OrgID 上传者所属组织ID
+	OrgID string `json:"org_id"`
+	// RefCount 业务关联引用数，Module/CustomID/Type 为主关联(最早的关联)
+	RefCount int `json:"ref_count"`
+	// RequireRef 是否由业务关联管理生命周期(上传时声明了业务模块或建立过关联)，为 true 时引用数为0的文件由垃圾回收删除
+	RequireRef bool `json:"require_ref"`
+
+	// LogicalID 逻辑文件ID，同一文件的各版本共享，首个版本的逻辑文件ID即其文件ID
+	LogicalID string `json:"logical_id"`
+	// Version 版本号，从1开始递增
+	Version int `json:"version"`
+	// IsCurrent 是否为当前版本
+	IsCurrent bool `json:"is_current"`
+	// DeleteTime 移入回收站的时间，未删除时为零值
+	DeleteTime time.Time `json:"delete_time"`
+
+	// ScanAttempts 内容扫描失败次数，达到 ScanMaxAttempts 后标记上传失败
+	ScanAttempts int `json:"scan_attempts"`
+
 	CreateTime time.Time `json:"create_time"`
 	UpdateTime time.Time `json:"update_time"`
 }
 
+// StorageFileID 返回文件在文件引擎中的ID，秒传的文件与源文件共享同一份存储
+func (f *FileInfo) StorageFileID() string {
+	if f.StorageID != "" {
+		return f.StorageID
+	}
+	return f.FileID
+}
+
+// LogicalFileID 返回文件的逻辑文件ID
+func (f *FileInfo) LogicalFileID() string {
+	if f.LogicalID != "" {
+		return f.LogicalID
+	}
+	return f.FileID
+}
+
+// IsDeleted 文件是否已移入回收站
+func (f *FileInfo) IsDeleted() bool {
+	return !f.DeleteTime.IsZero()
+}
+
 func ConvertFileModel(in *FileInfoEntity) (out *FileInfo) {
+	var deleteTime time.Time
+	if in.DeleteTime > 0 {
+		deleteTime = time.Unix(in.DeleteTime, 0)
+	}
+
 	return &FileInfo{
 		ID:       in.ID,
 		Module:   FileModule(in.Module),
@@ -70,6 +156,24 @@ func ConvertFileModel(in *FileInfoEntity) (out *FileInfo) {
 		FileLink: in.FileLink,
 		Status:   FileStatus(in.Status),
 
+		StorageID:   in.StorageID,
+		ContentType: in.ContentType,
+		Size:        in.Size,
+		SHA256:      in.SHA256,
+
+		ExpectedSHA256: in.ExpectedSHA256,
+		OwnerID:        in.OwnerID,
+		OrgID:          in.OrgID,
+		RefCount:       in.RefCount,
+		RequireRef:     in.RequireRef == 1,
+
+		LogicalID:  in.LogicalID,
+		Version:    in.Version,
+		IsCurrent:  in.IsCurrent == 1,
+		DeleteTime: deleteTime,
+
+		ScanAttempts: in.ScanAttempts,
+
 		CreateTime: time.Unix(in.CreateTime, 0),
 		UpdateTime: time.Unix(in.UpdateTime, 0),
 	}
@@ -80,9 +184,16 @@ type PreUploadReq struct {
 	ContentType string `json:"content_type" dc:"文件类型"`
 	Size        int64  `json:"size" dc:"文件大小"`
 	BucketID    string `json:"bucket_id" dc:"桶ID"`
+	SHA256      string `json:"sha256" dc:"文件内容SHA-256(可选，用于上传完成校验及上传者本人文件的秒传)"`
+
+	Module    FileModule `json:"module" dc:"目标业务模块(可选，用于匹配上传策略)"`
+	Type      FileType   `json:"type" dc:"目标文件类型(可选，用于匹配上传策略)"`
+	OwnerID   string     `json:"owner_id" dc:"上传者ID(可选，用于用户配额)"`
+	LogicalID string     `json:"logical_id" dc:"所属逻辑文件ID(可选，指定时上传为该文件的新版本)"`
 }
 
 type PreUploadRes struct {
+	Instant      bool   `json:"instant" dc:"是否秒传(为true时无需上传，UploadURL为空)"`
 	FileID       string `json:"file_id" dc:"文件ID"`
 	OriginalName string `json:"original_name" dc:"文件原始名称"`
 	FileLink     string `json:"file_link" dc:"文件链接"`
@@ -96,3 +207,442 @@ type PreDownloadRes struct {
 	ExpiresAt   string `json:"expires_at" dc:"过期时间"`
 	ExpiresIn   int64  `json:"expires_in" dc:"过期时间"`
 }
+
+// UploadMeta 服务端直传文件的元信息
+// Module/CustomID/Type 可选，非零时上传成功后直接创建业务关联
+type UploadMeta struct {
+	FileName    string `json:"filename" dc:"文件名称"`
+	ContentType string `json:"content_type" dc:"文件类型"`
+	Size        int64  `json:"size" dc:"文件大小(字节)，<=0 表示未知"`
+	BucketID    string `json:"bucket_id" dc:"桶ID"`
+	SHA256      string `json:"sha256" dc:"文件内容SHA-256(可选)"`
+
+	Module    FileModule `json:"module" dc:"业务模块"`
+	CustomID  string     `json:"custom_id" dc:"业务自定义ID"`
+	Type      FileType   `json:"type" dc:"文件类型"`
+	OwnerID   string     `json:"owner_id" dc:"上传者ID"`
+	LogicalID string     `json:"logical_id" dc:"所属逻辑文件ID(可选，指定时上传为该文件的新版本)"`
+}
+
+// MultipartStatus 分片上传会话状态
+type MultipartStatus int
+
+const (
+	MultipartStatusUploading MultipartStatus = iota // Uploading
+	MultipartStatusCompleted                        // Completed
+	MultipartStatusAborted                          // Aborted
+)
+
+func GetMultipartStatusText(status MultipartStatus) string {
+	switch status {
+	case MultipartStatusUploading:
+		return "Uploading"
+	case MultipartStatusCompleted:
+		return "Completed"
+	case MultipartStatusAborted:
+		return "Aborted"
+	default:
+		return "Unknown Multipart Status"
+	}
+}
+
+type MultipartUploadEntity struct {
+	ID         int64  `json:"id"`
+	FileID     string `json:"file_id"`
+	UploadID   string `json:"upload_id"`
+	PartSize   int64  `json:"part_size"`
+	PartCount  int    `json:"part_count"`
+	Status     int    `json:"status"`
+	ExpireTime int64  `json:"expire_time"`
+	CreateTime int64  `json:"create_time"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+type MultipartPartEntity struct {
+	ID         int64  `json:"id"`
+	UploadID   string `json:"upload_id"`
+	PartNumber int    `json:"part_number"`
+	ETag       string `json:"etag"`
+	Size       int64  `json:"size"`
+	CreateTime int64  `json:"create_time"`
+	UpdateTime int64  `json:"update_time"`
+}
+
+// MultipartUploadInfo 分片上传会话，Parts 为已上报的分片，可用于断点续传
+type MultipartUploadInfo struct {
+	FileID     string           `json:"file_id"`
+	UploadID   string           `json:"upload_id"`
+	PartSize   int64            `json:"part_size"`
+	PartCount  int              `json:"part_count"`
+	Status     MultipartStatus  `json:"status"`
+	ExpireTime time.Time        `json:"expire_time"`
+	Parts      []*MultipartPart `json:"parts"`
+
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+func ConvertMultipartUploadModel(in *MultipartUploadEntity) (out *MultipartUploadInfo) {
+	return &MultipartUploadInfo{
+		FileID:     in.FileID,
+		UploadID:   in.UploadID,
+		PartSize:   in.PartSize,
+		PartCount:  in.PartCount,
+		Status:     MultipartStatus(in.Status),
+		ExpireTime: time.Unix(in.ExpireTime, 0),
+
+		CreateTime: time.Unix(in.CreateTime, 0),
+		UpdateTime: time.Unix(in.UpdateTime, 0),
+	}
+}
+
+type MultipartPart struct {
+	PartNumber int    `json:"part_number" dc:"分片序号(从1开始)"`
+	ETag       string `json:"etag" dc:"存储返回的分片ETag"`
+	Size       int64  `json:"size" dc:"分片大小"`
+}
+
+type InitMultipartUploadReq struct {
+	FileName    string `json:"filename" dc:"文件名称"`
+	ContentType string `json:"content_type" dc:"文件类型"`
+	Size        int64  `json:"size" dc:"文件大小"`
+	BucketID    string `json:"bucket_id" dc:"桶ID"`
+	PartSize    int64  `json:"part_size" dc:"分片大小，为空时使用默认配置"`
+	SHA256      string `json:"sha256" dc:"文件内容SHA-256(可选，用于上传完成校验)"`
+
+	Module    FileModule `json:"module" dc:"目标业务模块(可选，用于匹配上传策略)"`
+	Type      FileType   `json:"type" dc:"目标文件类型(可选，用于匹配上传策略)"`
+	OwnerID   string     `json:"owner_id" dc:"上传者ID(可选，用于用户配额)"`
+	LogicalID string     `json:"logical_id" dc:"所属逻辑文件ID(可选，指定时上传为该文件的新版本)"`
+}
+
+type InitMultipartUploadRes struct {
+	FileID       string `json:"file_id" dc:"文件ID"`
+	OriginalName string `json:"original_name" dc:"文件原始名称"`
+	FileLink     string `json:"file_link" dc:"文件链接"`
+	UploadID     string `json:"upload_id" dc:"分片上传ID"`
+	PartSize     int64  `json:"part_size" dc:"分片大小"`
+	PartCount    int    `json:"part_count" dc:"分片数量"`
+	ExpiresAt    string `json:"expires_at" dc:"过期时间"`
+}
+
+type MultipartPartURL struct {
+	PartNumber int    `json:"part_number" dc:"分片序号"`
+	UploadURL  string `json:"upload_url" dc:"分片上传URL"`
+	ExpiresAt  string `json:"expires_at" dc:"过期时间"`
+}
+
+// GCOptions 垃圾回收参数，为空的字段使用配置中的默认值
+type GCOptions struct {
+	DryRun      bool          `json:"dry_run" dc:"仅生成报告，不删除"`
+	GracePeriod time.Duration `json:"grace_period" dc:"宽限期，最后更新时间早于该时长的文件才会被回收"`
+	Limit       int           `json:"limit" dc:"单次最多回收的文件数量，0表示不限制"`
+}
+
+// GCItem 单个文件的回收结果
+type GCItem struct {
+	FileID      string     `json:"file_id"`
+	StorageID   string     `json:"storage_id"`
+	Status      FileStatus `json:"status"`
+	Reason      string     `json:"reason"`
+	BlobDeleted bool       `json:"blob_deleted" dc:"是否删除了存储中的文件(被其他记录引用时不删除，删除由后台任务异步执行)"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// GCReport 垃圾回收报告
+type GCReport struct {
+	DryRun    bool      `json:"dry_run"`
+	Cutoff    time.Time `json:"cutoff"`
+	Scanned   int       `json:"scanned"`
+	Collected int       `json:"collected"`
+	Failed    int       `json:"failed"`
+	Items     []*GCItem `json:"items"`
+}
+
+// UploadEvent 上传完成事件
+type UploadEvent struct {
+	File    *FileInfo `json:"file"`
+	Success bool      `json:"success"`
+}
+
+// UploadCallback 上传完成回调
+type UploadCallback func(ctx context.Context, event *UploadEvent)
+
+// UploadWebhookReq 文件引擎上传完成回调请求体
+type UploadWebhookReq struct {
+	FileID  string `json:"file_id"`
+	Success bool   `json:"success"`
+}
+
+// VariantMode 衍生图缩放模式
+type VariantMode int
+
+const (
+	VariantModeFit  VariantMode = iota // 等比缩放至不超过目标宽高
+	VariantModeFill                    // 等比缩放并居中裁剪至目标宽高
+)
+
+// VariantProfile 衍生图规格(缩略图、Web优化图等)，按 FileType 注册
+type VariantProfile struct {
+	Name    string      `json:"name" dc:"规格名称，同一FileType下唯一，如 thumb、web"`
+	Width   int         `json:"width" dc:"目标宽度，0表示按高度等比"`
+	Height  int         `json:"height" dc:"目标高度，0表示按宽度等比"`
+	Mode    VariantMode `json:"mode" dc:"缩放模式"`
+	Format  string      `json:"format" dc:"输出格式(jpeg/png)，默认jpeg"`
+	Quality int         `json:"quality" dc:"JPEG质量(1-100)，默认85"`
+}
+
+// VariantStatus 衍生图生成状态
+type VariantStatus int
+
+const (
+	VariantStatusPending    VariantStatus = iota // Pending
+	VariantStatusProcessing                      // Processing
+	VariantStatusSuccess                         // Success
+	VariantStatusFailed                          // Failed
+)
+
+func GetVariantStatusText(status VariantStatus) string {
+	switch status {
+	case VariantStatusPending:
+		return "Pending"
+	case VariantStatusProcessing:
+		return "Processing"
+	case VariantStatusSuccess:
+		return "Success"
+	case VariantStatusFailed:
+		return "Failed"
+	default:
+		return "Unknown Variant Status"
+	}
+}
+
+type FileVariantEntity struct {
+	ID          int64  `json:"id"`
+	FileID      string `json:"file_id"`
+	Name        string `json:"name"`
+	Status      int    `json:"status"`
+	StorageID   string `json:"storage_id"`
+	FileLink    string `json:"file_link"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	RetryCount  int    `json:"retry_count"`
+	LastError   string `json:"last_error"`
+	CreateTime  int64  `json:"create_time"`
+	UpdateTime  int64  `json:"update_time"`
+}
+
+// FileVariant 衍生图记录，FileID 为原图文件ID
+type FileVariant struct {
+	ID          int64         `json:"id"`
+	FileID      string        `json:"file_id"`
+	Name        string        `json:"name"`
+	Status      VariantStatus `json:"status"`
+	StorageID   string        `json:"storage_id"`
+	FileLink    string        `json:"file_link"`
+	ContentType string        `json:"content_type"`
+	Size        int64         `json:"size"`
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	RetryCount  int           `json:"retry_count"`
+	LastError   string        `json:"last_error"`
+
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+func ConvertFileVariantModel(in *FileVariantEntity) (out *FileVariant) {
+	return &FileVariant{
+		ID:          in.ID,
+		FileID:      in.FileID,
+		Name:        in.Name,
+		Status:      VariantStatus(in.Status),
+		StorageID:   in.StorageID,
+		FileLink:    in.FileLink,
+		ContentType: in.ContentType,
+		Size:        in.Size,
+		Width:       in.Width,
+		Height:      in.Height,
+		RetryCount:  in.RetryCount,
+		LastError:   in.LastError,
+
+		CreateTime: time.Unix(in.CreateTime, 0),
+		UpdateTime: time.Unix(in.UpdateTime, 0),
+	}
+}
+
+// UploadPolicy 上传校验策略，按 FileModule/FileType 注册
+// 在 PreUpload 时按声明信息校验，上传完成时再按实际内容(嗅探的MIME类型、图片尺寸)校验
+type UploadPolicy struct {
+	AllowedMIMETypes  []string `json:"allowed_mime_types" dc:"允许的MIME类型，支持 image/* 通配，为空不限制"`
+	AllowedExtensions []string `json:"allowed_extensions" dc:"允许的扩展名(如 .jpg)，为空不限制"`
+	MaxSize           int64    `json:"max_size" dc:"最大文件大小(字节)，0不限制"`
+
+	MinWidth  int `json:"min_width" dc:"图片最小宽度，0不限制"`
+	MinHeight int `json:"min_height" dc:"图片最小高度，0不限制"`
+	MaxWidth  int `json:"max_width" dc:"图片最大宽度，0不限制"`
+	MaxHeight int `json:"max_height" dc:"图片最大高度，0不限制"`
+
+	UserQuota int64 `json:"user_quota" dc:"单个用户在该模块/类型下的总容量(字节)，0不限制"`
+}
+
+type FileAssociationEntity struct {
+	ID         int64  `json:"id"`
+	FileID     string `json:"file_id"`
+	Module     int    `json:"module"`
+	CustomID   string `json:"custom_id"`
+	Type       int    `json:"type"`
+	CreateTime int64  `json:"create_time"`
+}
+
+// FileAssociation 文件与业务实体的关联，一个文件可被多个业务实体引用
+type FileAssociation struct {
+	ID         int64      `json:"id"`
+	FileID     string     `json:"file_id"`
+	Module     FileModule `json:"module"`
+	CustomID   string     `json:"custom_id"`
+	Type       FileType   `json:"type"`
+	CreateTime time.Time  `json:"create_time"`
+}
+
+func ConvertFileAssociationModel(in *FileAssociationEntity) (out *FileAssociation) {
+	return &FileAssociation{
+		ID:         in.ID,
+		FileID:     in.FileID,
+		Module:     FileModule(in.Module),
+		CustomID:   in.CustomID,
+		Type:       FileType(in.Type),
+		CreateTime: time.Unix(in.CreateTime, 0),
+	}
+}
+
+// ListOptions 按业务实体查询文件的选项
+type ListOptions struct {
+	// WithHistory 为 true 时返回全部历史版本(按版本号降序)，默认只返回当前版本
+	WithHistory bool
+}
+
+// FileSearchFilter 文件搜索条件，只搜索未删除文件的当前版本
+// 支持按业务模块/类型(任一关联匹配)、状态、时间范围、名称前缀、标签(全部匹配)分页查询，分页采用 Page/Size
+type FileSearchFilter struct {
+	Module   FileModule
+	Type     FileType
+	Statuses []FileStatus
+	OwnerID  string
+
+	StartTime time.Time
+	EndTime   time.Time
+
+	NamePrefix string
+	Tags       []string
+
+	Page int
+	Size int
+}
+
+// OutboxOp 存储副作用操作类型
+type OutboxOp string
+
+const (
+	OutboxOpDeleteFile   OutboxOp = "delete_file"   // 删除存储中的文件
+	OutboxOpReportUpload OutboxOp = "report_upload" // 向文件引擎上报上传结果
+)
+
+// OutboxStatus 存储副作用事件状态，执行成功的事件直接删除
+type OutboxStatus int
+
+const (
+	OutboxStatusPending OutboxStatus = iota // Pending
+	OutboxStatusDead                        // Dead
+)
+
+func GetOutboxStatusText(status OutboxStatus) string {
+	switch status {
+	case OutboxStatusPending:
+		return "Pending"
+	case OutboxStatusDead:
+		return "Dead"
+	default:
+		return "Unknown Outbox Status"
+	}
+}
+
+type OutboxEventEntity struct {
+	ID            int64  `json:"id"`
+	Op            string `json:"op"`
+	TargetID      string `json:"target_id"`
+	Payload       string `json:"payload"`
+	Status        int    `json:"status"`
+	Attempts      int    `json:"attempts"`
+	NextRetryTime int64  `json:"next_retry_time"`
+	LastError     string `json:"last_error"`
+	CreateTime    int64  `json:"create_time"`
+	UpdateTime    int64  `json:"update_time"`
+}
+
+// OutboxEvent 存储副作用事件，与 t_file 的变更在同一事务中写入，由后台任务执行
+type OutboxEvent struct {
+	ID            int64          `json:"id"`
+	Op            OutboxOp       `json:"op"`
+	TargetID      string         `json:"target_id"`
+	Payload       *OutboxPayload `json:"payload"`
+	Status        OutboxStatus   `json:"status"`
+	Attempts      int            `json:"attempts"`
+	NextRetryTime time.Time      `json:"next_retry_time"`
+	LastError     string         `json:"last_error"`
+
+	CreateTime time.Time `json:"create_time"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// OutboxPayload 事件参数
+type OutboxPayload struct {
+	// Success 上报的上传结果(report_upload)
+	Success bool `json:"success,omitempty"`
+}
+
+func ConvertOutboxEventModel(in *OutboxEventEntity) (out *OutboxEvent) {
+	payload := &OutboxPayload{}
+	if in.Payload != "" {
+		_ = json.Unmarshal([]byte(in.Payload), payload)
+	}
+
+	return &OutboxEvent{
+		ID:            in.ID,
+		Op:            OutboxOp(in.Op),
+		TargetID:      in.TargetID,
+		Payload:       payload,
+		Status:        OutboxStatus(in.Status),
+		Attempts:      in.Attempts,
+		NextRetryTime: time.Unix(in.NextRetryTime, 0),
+		LastError:     in.LastError,
+
+		CreateTime: time.Unix(in.CreateTime, 0),
+		UpdateTime: time.Unix(in.UpdateTime, 0),
+	}
+}
+
+// ArchiveManifest 归档导出清单，以 manifest.json 写入压缩包根目录
+type ArchiveManifest struct {
+	Module     FileModule      `json:"module"`
+	CustomIDs  []string        `json:"custom_ids"`
+	ExportTime time.Time       `json:"export_time"`
+	Files      []*ArchiveEntry `json:"files" dc:"已导出的文件"`
+	Skipped    []*ArchiveEntry `json:"skipped" dc:"未导出的文件(无权访问、存储读取失败)"`
+}
+
+// ArchiveEntry 归档中的文件
+type ArchiveEntry struct {
+	Path        string   `json:"path,omitempty" dc:"压缩包内路径：<CustomID>/<类型目录>/<文件名>"`
+	CustomID    string   `json:"custom_id"`
+	Type        FileType `json:"type"`
+	FileID      string   `json:"file_id"`
+	FileName    string   `json:"file_name"`
+	ContentType string   `json:"content_type"`
+	Size        int64    `json:"size"`
+	SHA256      string   `json:"sha256,omitempty"`
+	Version     int      `json:"version"`
+	Reason      string   `json:"reason,omitempty" dc:"未导出的原因"`
+}
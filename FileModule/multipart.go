@@ -0,0 +1,252 @@
+package FileModule
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// multipartAbortRetryDelay 取消失败的过期会话下次清理前的等待时间
+const multipartAbortRetryDelay = time.Hour
+
+// InitMultipartUpload 初始化分片上传
+// 文件记录与分片会话一并落库，客户端断线后可通过 GetMultipartUpload 查询已上传分片继续上传
+func (m *FileManager) InitMultipartUpload(ctx context.Context, in *InitMultipartUploadReq) (out *InitMultipartUploadRes, err error) {
+	err = m.checkDeclared(in.Module, in.Type, in.FileName, in.ContentType, in.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := &FileInfo{OwnerID: in.OwnerID}
+	fillOwner(ctx, owner)
+	err = m.checkQuota(ctx, nil, in.Module, in.Type, in.Size, owner.OwnerID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if in.PartSize <= 0 {
+		in.PartSize = m.config.MultipartPartSize
+	}
+
+	out, err = m.fileEngine.InitMultipartUpload(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+	if out.PartSize <= 0 {
+		out.PartSize = in.PartSize
+	}
+	if in.Size > 0 {
+		out.PartCount = int((in.Size + out.PartSize - 1) / out.PartSize)
+	}
+
+	// 配额在事务中加锁重新校验，避免并发上传同时通过预检后超出配额
+	err = m.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		err := m.checkQuota(ctx, tx, in.Module, in.Type, in.Size, owner.OwnerID, "")
+		if err != nil {
+			return err
+		}
+
+		err = m.createFile(ctx, &FileInfo{
+			Module:         in.Module,
+			Type:           in.Type,
+			FileID:         out.FileID,
+			FileName:       out.OriginalName,
+			FileLink:       out.FileLink,
+			Status:         FileStatusInit,
+			ContentType:    in.ContentType,
+			Size:           in.Size,
+			ExpectedSHA256: strings.ToLower(in.SHA256),
+			OwnerID:        owner.OwnerID,
+			LogicalID:      in.LogicalID,
+		})
+		if err != nil {
+			return err
+		}
+
+		expireTime := time.Now().Add(m.config.MultipartExpire)
+		return m.dao.CreateMultipartUpload(ctx, tx, out.FileID, out.UploadID, out.PartSize, out.PartCount, expireTime)
+	})
+	if err != nil {
+		m.abortOrphanMultipart(ctx, out)
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// abortOrphanMultipart 回收没有对应记录的分片上传会话：取消会话，取消失败时通过删除事件回收存储文件
+func (m *FileManager) abortOrphanMultipart(ctx context.Context, upload *InitMultipartUploadRes) {
+	err := m.fileEngine.AbortMultipartUpload(ctx, upload.FileID, upload.UploadID)
+	if err == nil {
+		return
+	}
+
+	m.logger.Errorf(ctx, "abort orphan multipart upload failed, fileID: %s, uploadID: %s, err: %v", upload.FileID, upload.UploadID, err)
+	m.deleteOrphanStorage(ctx, upload.FileID)
+}
+
+// GetMultipartUpload 获取分片上传会话及已上报的分片
+func (m *FileManager) GetMultipartUpload(ctx context.Context, fileID string) (out *MultipartUploadInfo, err error) {
+	out, err = m.dao.GetMultipartUpload(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	out.Parts, err = m.dao.ListMultipartParts(ctx, out.UploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// GetMultipartPartURLs 获取指定分片的上传链接
+func (m *FileManager) GetMultipartPartURLs(ctx context.Context, fileID string, partNumbers []int) (out []*MultipartPartURL, err error) {
+	upload, err := m.getUploadingMultipart(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, partNumber := range partNumbers {
+		if !upload.isValidPartNumber(partNumber) {
+			return nil, ErrMultipartPartNumber
+		}
+	}
+
+	return m.fileEngine.GetMultipartPartURLs(ctx, fileID, upload.UploadID, partNumbers)
+}
+
+// ReportMultipartPart 上报已上传的分片
+func (m *FileManager) ReportMultipartPart(ctx context.Context, fileID string, part *MultipartPart) (err error) {
+	upload, err := m.getUploadingMultipart(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if !upload.isValidPartNumber(part.PartNumber) {
+		return ErrMultipartPartNumber
+	}
+
+	err = m.dao.SaveMultipartPart(ctx, upload.UploadID, part)
+	if err != nil {
+		return err
+	}
+
+	// 仍在上传的会话顺延过期时间，避免大文件上传过程中被清理
+	return m.dao.ExtendMultipartExpire(ctx, fileID, time.Now().Add(m.config.MultipartExpire))
+}
+
+// CompleteMultipartUpload 合并分片，完成上传
+func (m *FileManager) CompleteMultipartUpload(ctx context.Context, fileID string) (err error) {
+	upload, err := m.getUploadingMultipart(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	parts, err := m.dao.ListMultipartParts(ctx, upload.UploadID)
+	if err != nil {
+		return err
+	}
+	if len(parts) == 0 || (upload.PartCount > 0 && len(parts) != upload.PartCount) {
+		return ErrMultipartPartsIncomplete
+	}
+	for i, part := range parts {
+		if part.PartNumber != i+1 {
+			return ErrMultipartPartsIncomplete
+		}
+	}
+
+	err = m.fileEngine.CompleteMultipartUpload(ctx, fileID, upload.UploadID, parts)
+	if err != nil {
+		return err
+	}
+
+	err = m.dao.UpdateMultipartStatus(ctx, fileID, MultipartStatusCompleted)
+	if err != nil {
+		return err
+	}
+
+	return m.CompleteUpload(ctx, fileID)
+}
+
+// AbortMultipartUpload 取消分片上传，释放存储中已上传的分片
+func (m *FileManager) AbortMultipartUpload(ctx context.Context, fileID string) (err error) {
+	upload, err := m.getUploadingMultipart(ctx, fileID)
+	if err != nil {
+		return err
+	}
+
+	return m.abortMultipartUpload(ctx, upload)
+}
+
+func (m *FileManager) abortMultipartUpload(ctx context.Context, upload *MultipartUploadInfo) (err error) {
+	err = m.fileEngine.AbortMultipartUpload(ctx, upload.FileID, upload.UploadID)
+	if err != nil {
+		return err
+	}
+
+	err = m.dao.UpdateMultipartStatus(ctx, upload.FileID, MultipartStatusAborted)
+	if err != nil {
+		return err
+	}
+
+	err = m.dao.DeleteMultipartParts(ctx, upload.UploadID)
+	if err != nil {
+		return err
+	}
+
+	return m.dao.UpdateStatus(ctx, nil, upload.FileID, FileStatusUploadFailed)
+}
+
+// CleanupMultipartUploads 清理已过期的分片上传，返回清理数量
+// 取消失败的会话顺延 multipartAbortRetryDelay 后重试，不阻塞其他会话的清理
+func (m *FileManager) CleanupMultipartUploads(ctx context.Context) (count int, err error) {
+	const batchSize = 100
+
+	for {
+		uploads, err := m.dao.ListExpiredMultipartUploads(ctx, time.Now(), batchSize)
+		if err != nil {
+			return count, err
+		}
+
+		failed := 0
+		for _, upload := range uploads {
+			err = m.abortMultipartUpload(ctx, upload)
+			if err == nil {
+				count++
+				continue
+			}
+
+			failed++
+			m.logger.Errorf(ctx, "abort expired multipart upload failed, fileID: %s, uploadID: %s, err: %v", upload.FileID, upload.UploadID, err)
+			err = m.dao.ExtendMultipartExpire(ctx, upload.FileID, time.Now().Add(multipartAbortRetryDelay))
+			if err != nil {
+				m.logger.Errorf(ctx, "postpone multipart upload cleanup failed, fileID: %s, err: %v", upload.FileID, err)
+			}
+		}
+
+		// 整批失败时不再重复拉取同一批会话
+		if len(uploads) < batchSize || failed == len(uploads) {
+			return count, nil
+		}
+	}
+}
+
+func (m *FileManager) getUploadingMultipart(ctx context.Context, fileID string) (out *MultipartUploadInfo, err error) {
+	out, err = m.dao.GetMultipartUpload(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if out.Status != MultipartStatusUploading {
+		return nil, ErrMultipartUploadClosed
+	}
+	return out, nil
+}
+
+func (u *MultipartUploadInfo) isValidPartNumber(partNumber int) bool {
+	if partNumber < 1 {
+		return false
+	}
+	return u.PartCount == 0 || partNumber <= u.PartCount
+}
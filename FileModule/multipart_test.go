@@ -0,0 +1,142 @@
+package FileModule
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func multipartRows(uploads ...*MultipartUploadEntity) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "file_id", "upload_id", "part_size", "part_count", "status", "expire_time", "create_time", "update_time"})
+	for _, u := range uploads {
+		rows.AddRow(u.ID, u.FileID, u.UploadID, u.PartSize, u.PartCount, u.Status, u.ExpireTime, u.CreateTime, u.UpdateTime)
+	}
+	return rows
+}
+
+func Test_CleanupMultipartUploads(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		// 取消失败的会话顺延后跳过，不阻塞后续会话的清理
+		var aborts int32
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&aborts, 1)
+			if strings.Contains(r.URL.Path, "/files/f1/") {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		})
+
+		expired := time.Now().Add(-time.Hour).Unix()
+		mock.ExpectQuery("SELECT .* FROM t_file_multipart WHERE").WillReturnRows(multipartRows(
+			&MultipartUploadEntity{ID: 1, FileID: "f1", UploadID: "u1", ExpireTime: expired},
+			&MultipartUploadEntity{ID: 2, FileID: "f2", UploadID: "u2", ExpireTime: expired},
+		))
+		mock.ExpectExec("UPDATE t_file_multipart SET .*expire_time").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE t_file_multipart SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_multipart_part").WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("UPDATE t_file SET").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		count, err := m.CleanupMultipartUploads(ctx)
+		t.AssertNil(err)
+		t.Assert(count, 1)
+		t.Assert(atomic.LoadInt32(&aborts), 2)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+func Test_InitMultipartUpload_Orphan(t *testing.T) {
+	// newHandler 创建分片会话，取消会话时返回 abortStatus 并记录请求
+	newHandler := func(t *gtest.T, abortStatus int, requests *[]string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			*requests = append(*requests, r.Method+" "+r.URL.Path)
+			switch {
+			case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/files/multipart-uploads"):
+				_, _ = w.Write([]byte(`{"id":"f1","original_name":"a.bin","visit_url":"http://link/f1","upload_id":"u1","part_size":5}`))
+			case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/multipart-uploads/u1"):
+				w.WriteHeader(abortStatus)
+				_, _ = w.Write([]byte(`{}`))
+			case r.Method == http.MethodDelete && strings.HasSuffix(r.URL.Path, "/files/f1"):
+				_, _ = w.Write([]byte(`{}`))
+			default:
+				t.Errorf("unexpected engine request: %s %s", r.Method, r.URL.Path)
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		// 记录落库失败时取消已创建的分片会话
+		var requests []string
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newHandler(t, http.StatusOK, &requests))
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO t_file").WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		_, err := m.InitMultipartUpload(context.Background(), &InitMultipartUploadReq{FileName: "a.bin", Size: 10})
+		t.AssertNE(err, nil)
+		t.AssertNil(mock.ExpectationsWereMet())
+		t.Assert(requests, []string{
+			"POST /api/v1/file-engine/files/multipart-uploads",
+			"DELETE /api/v1/file-engine/files/f1/multipart-uploads/u1",
+		})
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 取消失败时通过删除事件回收存储文件
+		var requests []string
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newHandler(t, http.StatusInternalServerError, &requests))
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT INTO t_file").WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := m.InitMultipartUpload(context.Background(), &InitMultipartUploadReq{FileName: "a.bin", Size: 10})
+		t.AssertNE(err, nil)
+		t.AssertNil(mock.ExpectationsWereMet())
+		t.Assert(requests[len(requests)-1], "DELETE /api/v1/file-engine/files/f1")
+	})
+}
+
+func Test_ReportMultipartPart_ExtendsExpire(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		m, mock := newMockFileManager(t.T, nil, nil)
+		mock.ExpectQuery("SELECT .* FROM t_file_multipart WHERE").WillReturnRows(multipartRows(
+			&MultipartUploadEntity{ID: 1, FileID: "f1", UploadID: "u1", PartCount: 2, Status: int(MultipartStatusUploading)},
+		))
+		mock.ExpectExec("INSERT INTO t_file_multipart_part").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE t_file_multipart SET .*expire_time").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := m.ReportMultipartPart(ctx, "f1", &MultipartPart{PartNumber: 1, ETag: "e1", Size: 10})
+		t.AssertNil(err)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 超出分片数量的分片不记录
+		m, mock := newMockFileManager(t.T, nil, nil)
+		mock.ExpectQuery("SELECT .* FROM t_file_multipart WHERE").WillReturnRows(multipartRows(
+			&MultipartUploadEntity{ID: 1, FileID: "f1", UploadID: "u1", PartCount: 2, Status: int(MultipartStatusUploading)},
+		))
+
+		err := m.ReportMultipartPart(ctx, "f1", &MultipartPart{PartNumber: 3})
+		t.Assert(err, ErrMultipartPartNumber)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,138 @@
+package FileModule
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+const (
+	outboxBatchSize  = 100
+	outboxMaxBackoff = time.Hour
+	// outboxLease 事件被抢占后的执行时限，超时未完成(进程退出)的事件重新执行
+	outboxLease = 5 * time.Minute
+)
+
+// enqueueOutbox 在事务 tx 中写入存储副作用事件，事务提交后由 dispatchOutbox 或后台任务执行
+func (m *FileManager) enqueueOutbox(ctx context.Context, tx gdb.TX, op OutboxOp, targetID string, payload *OutboxPayload) (event *OutboxEvent, err error) {
+	if payload == nil {
+		payload = &OutboxPayload{}
+	}
+
+	event = &OutboxEvent{Op: op, TargetID: targetID, Payload: payload}
+	err = m.dao.CreateOutboxEvent(ctx, tx, event)
+	if err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// dispatchOutbox 事务提交后立即尝试执行事件，失败的事件由后台任务按退避策略重试
+func (m *FileManager) dispatchOutbox(ctx context.Context, events ...*OutboxEvent) {
+	for _, event := range events {
+		_, err := m.dispatchEvent(ctx, event)
+		if err != nil {
+			m.logger.Warningf(ctx, "dispatch outbox event failed, will retry, id: %d, op: %s, target: %s, err: %v", event.ID, event.Op, event.TargetID, err)
+		}
+	}
+}
+
+// deleteOrphanStorage 回收没有对应记录的存储文件：优先写入删除事件，失败的删除由后台任务重试；
+// 事件写入失败(通常数据库不可用)时直接删除，删除失败只记录日志
+func (m *FileManager) deleteOrphanStorage(ctx context.Context, storageID string) {
+	event, err := m.enqueueOutbox(ctx, nil, OutboxOpDeleteFile, storageID, nil)
+	if err == nil {
+		m.dispatchOutbox(ctx, event)
+		return
+	}
+	m.logger.Errorf(ctx, "enqueue orphan file deletion failed, deleting directly, fileID: %s, err: %v", storageID, err)
+
+	err = m.fileEngine.Delete(ctx, storageID)
+	if err != nil && !errors.Is(err, ErrFileNotFound) {
+		m.logger.Errorf(ctx, "delete orphan file failed, fileID: %s, err: %v", storageID, err)
+		return
+	}
+	m.evictDownloadURL(ctx, storageID)
+}
+
+// DispatchOutbox 执行到期的存储副作用事件，返回执行成功的数量
+func (m *FileManager) DispatchOutbox(ctx context.Context) (count int, err error) {
+	var afterID int64
+	for {
+		events, err := m.dao.ListDueOutboxEvents(ctx, time.Now(), afterID, outboxBatchSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, event := range events {
+			afterID = event.ID
+
+			executed, err := m.dispatchEvent(ctx, event)
+			if err != nil {
+				m.logger.Errorf(ctx, "dispatch outbox event failed, id: %d, op: %s, target: %s, attempts: %d, err: %v", event.ID, event.Op, event.TargetID, event.Attempts, err)
+				continue
+			}
+			if executed {
+				count++
+			}
+		}
+
+		if len(events) < outboxBatchSize {
+			return count, nil
+		}
+	}
+}
+
+// dispatchEvent 抢占并执行事件：成功则删除事件，失败则按退避时间重试，超过最大次数后标记为放弃
+// 事件被其他实例抢占时直接返回，executed 为 false
+func (m *FileManager) dispatchEvent(ctx context.Context, event *OutboxEvent) (executed bool, err error) {
+	ok, err := m.dao.ClaimOutboxEvent(ctx, event, time.Now().Add(outboxLease))
+	if err != nil || !ok {
+		return false, err
+	}
+
+	execErr := m.executeOutboxEvent(ctx, event)
+	if execErr == nil {
+		return true, m.dao.DeleteOutboxEvent(ctx, event.ID)
+	}
+
+	status := OutboxStatusPending
+	if event.Attempts >= m.config.OutboxMaxAttempts {
+		status = OutboxStatusDead
+		m.logger.Errorf(ctx, "outbox event dead after %d attempts, id: %d, op: %s, target: %s", event.Attempts, event.ID, event.Op, event.TargetID)
+	}
+
+	err = m.dao.UpdateOutboxFailure(ctx, event.ID, status, time.Now().Add(m.outboxBackoff(event.Attempts)), execErr.Error())
+	if err != nil {
+		return true, err
+	}
+	return true, execErr
+}
+
+func (m *FileManager) executeOutboxEvent(ctx context.Context, event *OutboxEvent) (err error) {
+	switch event.Op {
+	case OutboxOpDeleteFile:
+		err = m.fileEngine.Delete(ctx, event.TargetID)
+		// 重试时文件可能已在上一次执行中删除
+		if err != nil && !errors.Is(err, ErrFileNotFound) {
+			return err
+		}
+		m.evictDownloadURL(ctx, event.TargetID)
+		return nil
+	case OutboxOpReportUpload:
+		return m.fileEngine.ReportUploadResult(ctx, event.TargetID, event.Payload.Success)
+	default:
+		return ErrUnknownOutboxOp
+	}
+}
+
+// outboxBackoff 第 attempts 次执行失败后的重试间隔
+func (m *FileManager) outboxBackoff(attempts int) time.Duration {
+	backoff := m.config.OutboxRetryBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, outboxMaxBackoff)
+}
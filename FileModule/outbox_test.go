@@ -0,0 +1,165 @@
+package FileModule
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_OutboxBackoff(t *testing.T) {
+	m := &FileManager{config: &Config{OutboxRetryBackoff: 10 * time.Second}}
+
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(m.outboxBackoff(1), 10*time.Second)
+		t.Assert(m.outboxBackoff(2), 20*time.Second)
+		t.Assert(m.outboxBackoff(4), 80*time.Second)
+		t.Assert(m.outboxBackoff(100), outboxMaxBackoff)
+	})
+}
+
+// argRecorder 记录 SQL 参数，用于断言字段顺序不固定的 Data 更新
+type argRecorder struct {
+	values []driver.Value
+}
+
+func (r *argRecorder) Match(v driver.Value) bool {
+	r.values = append(r.values, v)
+	return true
+}
+
+// contains 是否包含与 value 相等的参数
+func (r *argRecorder) contains(value driver.Value) bool {
+	for _, v := range r.values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// containsUnixAfter 是否包含不早于 after 的 Unix 时间戳参数
+func (r *argRecorder) containsUnixAfter(after time.Time) bool {
+	for _, v := range r.values {
+		if unix, ok := v.(int64); ok && unix >= after.Unix() && unix < after.Add(time.Minute).Unix() {
+			return true
+		}
+	}
+	return false
+}
+
+// newDeleteHandler 模拟文件引擎删除文件，返回指定状态码并统计调用次数
+func newDeleteHandler(t *testing.T, status int, calls *int32) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete || r.URL.Path != "/api/v1/file-engine/files/s1" {
+			t.Errorf("unexpected engine request: %s %s", r.Method, r.URL.Path)
+		}
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+func outboxRows(events ...*OutboxEventEntity) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "op", "target_id", "payload", "status", "attempts", "next_retry_time", "last_error", "create_time", "update_time"})
+	for _, e := range events {
+		rows.AddRow(e.ID, e.Op, e.TargetID, e.Payload, e.Status, e.Attempts, e.NextRetryTime, e.LastError, e.CreateTime, e.UpdateTime)
+	}
+	return rows
+}
+
+func Test_DispatchOutbox(t *testing.T) {
+	cases := []struct {
+		name       string
+		attempts   int
+		claimed    bool
+		engine     int
+		calls      int32
+		count      int
+		deleted    bool
+		status     OutboxStatus
+		retryAfter time.Duration
+	}{
+		{name: "success deletes event", claimed: true, engine: http.StatusOK, calls: 1, count: 1, deleted: true},
+		{name: "already deleted in storage", claimed: true, engine: http.StatusNotFound, calls: 1, count: 1, deleted: true},
+		{name: "claimed by another instance", claimed: false, calls: 0, count: 0},
+		{name: "failure retries with backoff", claimed: true, engine: http.StatusInternalServerError, calls: 1, status: OutboxStatusPending, retryAfter: 10 * time.Second},
+		{name: "dead after max attempts", attempts: 2, claimed: true, engine: http.StatusInternalServerError, calls: 1, status: OutboxStatusDead, retryAfter: 40 * time.Second},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			var calls int32
+			m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1, OutboxMaxAttempts: 3}, newDeleteHandler(t.T, c.engine, &calls))
+			now := time.Now()
+
+			mock.ExpectQuery("SELECT .* FROM t_file_outbox WHERE").WillReturnRows(outboxRows(&OutboxEventEntity{
+				ID: 9, Op: string(OutboxOpDeleteFile), TargetID: "s1", Payload: "{}", Status: int(OutboxStatusPending), Attempts: c.attempts,
+			}))
+			claimed := int64(0)
+			if c.claimed {
+				claimed = 1
+			}
+			claim := &argRecorder{}
+			mock.ExpectExec("UPDATE t_file_outbox SET").
+				WithArgs(claim, claim, claim, int64(9), int(OutboxStatusPending), c.attempts).
+				WillReturnResult(sqlmock.NewResult(0, claimed))
+			if c.deleted {
+				mock.ExpectExec("DELETE FROM t_file_outbox").WithArgs(int64(9)).WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+			failure := &argRecorder{}
+			if c.retryAfter > 0 {
+				mock.ExpectExec("UPDATE t_file_outbox SET").
+					WithArgs(failure, failure, failure, failure, int64(9)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+			}
+
+			count, err := m.DispatchOutbox(context.Background())
+			t.AssertNil(err)
+			if count != c.count {
+				t.Fatalf("%s: count %d, want %d", c.name, count, c.count)
+			}
+			t.Assert(atomic.LoadInt32(&calls), c.calls)
+			t.AssertNil(mock.ExpectationsWereMet())
+			// 抢占时增加执行次数并将下次执行时间推迟一个租约
+			t.Assert(claim.contains(int64(c.attempts+1)), true)
+			t.Assert(claim.containsUnixAfter(now.Add(outboxLease)), true)
+			if c.retryAfter > 0 {
+				t.Assert(failure.contains(int64(c.status)), true)
+				t.Assert(failure.containsUnixAfter(now.Add(c.retryAfter)), true)
+			}
+		}
+	})
+}
+
+func Test_DeleteOrphanStorage(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		// 删除事件写入成功时由事件执行删除
+		var calls int32
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newDeleteHandler(t.T, http.StatusOK, &calls))
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(9, 1))
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		m.deleteOrphanStorage(context.Background(), "s1")
+		t.Assert(atomic.LoadInt32(&calls), 1)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 删除事件写入失败时直接删除存储中的文件
+		var calls int32
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newDeleteHandler(t.T, http.StatusOK, &calls))
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnError(gerror.New("database unavailable"))
+
+		m.deleteOrphanStorage(context.Background(), "s1")
+		t.Assert(atomic.LoadInt32(&calls), 1)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
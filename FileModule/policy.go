@@ -0,0 +1,245 @@
+package FileModule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// sniffHeadSize 嗅探文件内容时读取的头部字节数，需覆盖常见图片格式的尺寸信息(含 EXIF)
+const sniffHeadSize = 128 << 10
+
+// sniffedAliases 内容嗅探只能识别容器格式的类型，允许其匹配的具体MIME类型
+var sniffedAliases = map[string][]string{
+	"application/zip": {
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	},
+	"text/plain": {
+		"text/csv",
+		"application/json",
+	},
+}
+
+// RegisterUploadPolicy 注册上传校验策略，module/typ 为 0 表示匹配任意值，精确匹配优先
+func (m *FileManager) RegisterUploadPolicy(module FileModule, typ FileType, policy *UploadPolicy) {
+	m.policyMutex.Lock()
+	defer m.policyMutex.Unlock()
+
+	if m.policies == nil {
+		m.policies = make(map[moduleTypeKey]*UploadPolicy)
+	}
+	m.policies[moduleTypeKey{module: module, typ: typ}] = policy
+}
+
+// getUploadPolicy 获取匹配的上传策略及其注册键
+func (m *FileManager) getUploadPolicy(module FileModule, typ FileType) (policy *UploadPolicy, key moduleTypeKey) {
+	m.policyMutex.RLock()
+	defer m.policyMutex.RUnlock()
+
+	for _, key = range lookupKeys(module, typ) {
+		if policy = m.policies[key]; policy != nil {
+			return policy, key
+		}
+	}
+	return nil, key
+}
+
+// checkDeclared 按上传方声明的文件名、MIME类型、大小校验
+func (m *FileManager) checkDeclared(module FileModule, typ FileType, fileName string, contentType string, size int64) error {
+	policy, _ := m.getUploadPolicy(module, typ)
+	if policy == nil {
+		return nil
+	}
+
+	if len(policy.AllowedExtensions) > 0 && !matchExtension(policy.AllowedExtensions, fileName) {
+		return ErrFileTypeNotAllowed
+	}
+	if contentType != "" && !matchMIMEType(policy.AllowedMIMETypes, baseMIMEType(contentType)) {
+		return ErrFileTypeNotAllowed
+	}
+	if policy.MaxSize > 0 && size > policy.MaxSize {
+		return ErrFileTooLarge
+	}
+
+	return nil
+}
+
+// checkSize 按写入存储的实际大小校验全局上传大小限制与 module/typ 上传策略的大小限制
+// 声明的大小由客户端提供(可能为 0 或小于实际大小)，内容写入后须按实际大小重新校验
+func (m *FileManager) checkSize(module FileModule, typ FileType, size int64) error {
+	if m.config.MaxUploadSize > 0 && size > m.config.MaxUploadSize {
+		return ErrFileTooLarge
+	}
+
+	policy, _ := m.getUploadPolicy(module, typ)
+	if policy != nil && policy.MaxSize > 0 && size > policy.MaxSize {
+		return ErrFileTooLarge
+	}
+	return nil
+}
+
+// checkQuota 校验用户配额，ownerID 为空时不校验；excludeLogicalID 不为空时不统计该逻辑文件(已计入用量的文件重新关联)
+// tx 不为空时锁定用户的文件记录，与同一用户并发创建文件互斥，校验结果在事务提交前有效
+func (m *FileManager) checkQuota(ctx context.Context, tx gdb.TX, module FileModule, typ FileType, size int64, ownerID string, excludeLogicalID string) error {
+	policy, key := m.getUploadPolicy(module, typ)
+	if policy == nil || policy.UserQuota <= 0 || ownerID == "" {
+		return nil
+	}
+
+	used, err := m.dao.SumOwnerSize(ctx, tx, ownerID, key.module, key.typ, excludeLogicalID)
+	if err != nil {
+		return err
+	}
+	if used+size > policy.UserQuota {
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// checkStoredContent 按存储中的实际内容校验文件是否符合 module/typ 的上传策略
+// 上传时已按同一策略嗅探(或将在确认上传时嗅探)的文件不重复读取；其他文件须已写入存储
+func (m *FileManager) checkStoredContent(ctx context.Context, info *FileInfo, module FileModule, typ FileType) error {
+	policy, _ := m.getUploadPolicy(module, typ)
+	if policy == nil {
+		return nil
+	}
+	if declared, _ := m.getUploadPolicy(info.Module, info.Type); declared == policy {
+		return nil
+	}
+
+	switch info.Status {
+	case FileStatusUploadSuccess, FileStatusScanning:
+	case FileStatusInit:
+		return ErrUploadNotConfirmed
+	default:
+		return ErrFileUploadFailed
+	}
+
+	head, err := m.readHead(ctx, info)
+	if err != nil {
+		return err
+	}
+	_, err = m.checkContent(module, typ, head, info.Size)
+	return err
+}
+
+// checkContent 按实际内容校验，返回嗅探出的MIME类型；未注册策略时不读取内容
+func (m *FileManager) checkContent(module FileModule, typ FileType, head []byte, size int64) (sniffed string, err error) {
+	policy, _ := m.getUploadPolicy(module, typ)
+	if policy == nil {
+		return "", nil
+	}
+
+	sniffed = baseMIMEType(http.DetectContentType(head))
+	if !matchSniffedType(policy.AllowedMIMETypes, sniffed) {
+		return sniffed, ErrFileTypeNotAllowed
+	}
+	if policy.MaxSize > 0 && size > policy.MaxSize {
+		return sniffed, ErrFileTooLarge
+	}
+
+	if policy.MinWidth > 0 || policy.MinHeight > 0 || policy.MaxWidth > 0 || policy.MaxHeight > 0 {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(head))
+		if err != nil {
+			return sniffed, ErrImageDimensions
+		}
+		if cfg.Width < policy.MinWidth || cfg.Height < policy.MinHeight ||
+			(policy.MaxWidth > 0 && cfg.Width > policy.MaxWidth) ||
+			(policy.MaxHeight > 0 && cfg.Height > policy.MaxHeight) {
+			return sniffed, ErrImageDimensions
+		}
+	}
+
+	return sniffed, nil
+}
+
+// readHead 读取存储中文件的头部内容用于嗅探
+func (m *FileManager) readHead(ctx context.Context, info *FileInfo) ([]byte, error) {
+	preDownload, err := m.fileEngine.PreDownload(ctx, info.StorageFileID())
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := m.fileEngine.GetContent(ctx, preDownload.DownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(io.LimitReader(body, sniffHeadSize))
+}
+
+// isUploadRejected 判断错误是否为文件内容被拒绝(上传已被标记为失败)
+func isUploadRejected(err error) bool {
+	for _, rejected := range []error{
+		ErrFileUploadFailed, ErrFileHashMismatch, ErrFileSizeMismatch, ErrFileTooLarge,
+		ErrFileTypeNotAllowed, ErrImageDimensions,
+	} {
+		if errors.Is(err, rejected) {
+			return true
+		}
+	}
+	return false
+}
+
+func baseMIMEType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
+
+// matchMIMEType 判断MIME类型是否在允许列表中，支持 image/* 通配；列表为空时不限制
+func matchMIMEType(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == contentType {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(contentType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchSniffedType(allowed []string, sniffed string) bool {
+	if matchMIMEType(allowed, sniffed) {
+		return true
+	}
+	for _, alias := range sniffedAliases[sniffed] {
+		if matchMIMEType(allowed, alias) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchExtension(allowed []string, fileName string) bool {
+	ext := strings.ToLower(path.Ext(fileName))
+	for _, allowedExt := range allowed {
+		allowedExt = strings.ToLower(allowedExt)
+		if !strings.HasPrefix(allowedExt, ".") {
+			allowedExt = "." + allowedExt
+		}
+		if allowedExt == ext {
+			return true
+		}
+	}
+	return false
+}
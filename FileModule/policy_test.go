@@ -0,0 +1,270 @@
+package FileModule
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/yyboo586/common/MiddleWare"
+)
+
+func Test_MatchMIMEType(t *testing.T) {
+	cases := []struct {
+		name        string
+		allowed     []string
+		contentType string
+		ok          bool
+	}{
+		{name: "empty list allows all", allowed: nil, contentType: "application/pdf", ok: true},
+		{name: "exact match", allowed: []string{"image/png"}, contentType: "image/png", ok: true},
+		{name: "pattern is case insensitive", allowed: []string{"Image/PNG"}, contentType: "image/png", ok: true},
+		{name: "wildcard", allowed: []string{"image/*"}, contentType: "image/jpeg", ok: true},
+		{name: "wildcard other type", allowed: []string{"image/*"}, contentType: "video/mp4", ok: false},
+		{name: "wildcard needs slash", allowed: []string{"image/*"}, contentType: "imagex/png", ok: false},
+		{name: "not listed", allowed: []string{"image/png", "image/gif"}, contentType: "image/jpeg", ok: false},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			t.Assert(matchMIMEType(c.allowed, c.contentType), c.ok)
+		}
+	})
+}
+
+func encodePNG(t *testing.T, width int, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func Test_CheckContent(t *testing.T) {
+	pngHead := encodePNG(t, 40, 20)
+	zipHead := []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00")
+
+	cases := []struct {
+		name    string
+		policy  *UploadPolicy
+		head    []byte
+		size    int64
+		sniffed string
+		err     error
+	}{
+		{name: "no policy", policy: nil, head: []byte("hello"), sniffed: ""},
+		{name: "allowed image", policy: &UploadPolicy{AllowedMIMETypes: []string{"image/*"}}, head: pngHead, sniffed: "image/png"},
+		{name: "text disguised as image", policy: &UploadPolicy{AllowedMIMETypes: []string{"image/*"}}, head: []byte("hello"), sniffed: "text/plain", err: ErrFileTypeNotAllowed},
+		{name: "zip container matches office type", policy: &UploadPolicy{AllowedMIMETypes: []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"}}, head: zipHead, sniffed: "application/zip"},
+		{name: "too large", policy: &UploadPolicy{MaxSize: 10}, head: pngHead, size: 11, sniffed: "image/png", err: ErrFileTooLarge},
+		{name: "within dimensions", policy: &UploadPolicy{MinWidth: 40, MaxHeight: 20}, head: pngHead, sniffed: "image/png"},
+		{name: "too narrow", policy: &UploadPolicy{MinWidth: 41}, head: pngHead, sniffed: "image/png", err: ErrImageDimensions},
+		{name: "too tall", policy: &UploadPolicy{MaxHeight: 19}, head: pngHead, sniffed: "image/png", err: ErrImageDimensions},
+		{name: "dimensions of non image", policy: &UploadPolicy{MinWidth: 1}, head: []byte("hello"), sniffed: "text/plain", err: ErrImageDimensions},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			m := &FileManager{}
+			if c.policy != nil {
+				m.RegisterUploadPolicy(1, 2, c.policy)
+			}
+			sniffed, err := m.checkContent(1, 2, c.head, c.size)
+			t.Assert(sniffed, c.sniffed)
+			t.Assert(err, c.err)
+		}
+	})
+}
+
+func Test_PreUpload_Quota(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		// 预检通过后其他上传已占用配额，创建记录时加锁重新校验并回收已申请的存储
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newTransferHandler(t.T, ""))
+		m.RegisterUploadPolicy(1, 0, &UploadPolicy{UserQuota: 10})
+		mock.ExpectQuery("SELECT SUM\\(size\\) FROM t_file WHERE .*owner_id").WithArgs("u1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(4))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT SUM\\(size\\) FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(8))
+		mock.ExpectRollback()
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		_, err := m.PreUpload(context.WithValue(context.Background(), MiddleWare.CustomCtxKey, &MiddleWare.ContextUser{UserID: "u1"}), &PreUploadReq{Module: 1, FileName: "a.txt", Size: 5})
+		t.Assert(err, ErrQuotaExceeded)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+// expectRejectUpload 上传失败并在同一事务中记录删除存储文件与上报结果的事件，随后依次执行
+func expectRejectUpload(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE t_file SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	for i := 0; i < 2; i++ {
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func Test_CompleteUpload_ActualSize(t *testing.T) {
+	ctx := context.Background()
+	const sum = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+	// newHandler 文件引擎按实际内容 "hello" 返回大小与哈希，并记录删除请求
+	newHandler := func(t *gtest.T, deleted *int32) http.HandlerFunc {
+		transfer := newTransferHandler(t.T, "hello")
+		return func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/files/f1"):
+				_, _ = fmt.Fprintf(w, `{"id":"f1","status":"uploaded","size":5,"sha256":"%s"}`, sum)
+				return
+			case r.Method == http.MethodDelete:
+				atomic.AddInt32(deleted, 1)
+			}
+			transfer(w, r)
+		}
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		// 声明大小为 0 时按实际大小校验策略的大小限制，超出时上传失败并删除存储中的文件
+		var deleted int32
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newHandler(t, &deleted))
+		m.RegisterUploadPolicy(1, 0, &UploadPolicy{MaxSize: 4})
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Module: 1, Status: int(FileStatusInit), OwnerID: "u1"}))
+		expectRejectUpload(mock)
+
+		err := m.CompleteUpload(ctx, "f1")
+		t.Assert(err, ErrFileTooLarge)
+		t.AssertNil(mock.ExpectationsWereMet())
+		t.Assert(atomic.LoadInt32(&deleted), 1)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 确认事务中按实际大小锁定统计配额，超出时上传失败并删除存储中的文件
+		var deleted int32
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newHandler(t, &deleted))
+		m.RegisterUploadPolicy(1, 0, &UploadPolicy{UserQuota: 10})
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Module: 1, Status: int(FileStatusInit), OwnerID: "u1"}))
+		mock.ExpectExec("UPDATE t_file SET .*content_type").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE t_file SET .*sha256").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT SUM\\(size\\) FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(12))
+		mock.ExpectRollback()
+		expectRejectUpload(mock)
+
+		err := m.CompleteUpload(ctx, "f1")
+		t.Assert(err, ErrQuotaExceeded)
+		t.AssertNil(mock.ExpectationsWereMet())
+		t.Assert(atomic.LoadInt32(&deleted), 1)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 实际用量未超出配额时确认上传成功
+		var deleted int32
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newHandler(t, &deleted))
+		m.RegisterUploadPolicy(1, 0, &UploadPolicy{UserQuota: 10})
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Module: 1, Status: int(FileStatusInit), OwnerID: "u1"}))
+		mock.ExpectExec("UPDATE t_file SET .*content_type").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE t_file SET .*sha256").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT SUM\\(size\\) FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(9))
+		mock.ExpectExec("UPDATE t_file SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", IsCurrent: 1}))
+		mock.ExpectCommit()
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := m.CompleteUpload(ctx, "f1")
+		t.AssertNil(err)
+		t.AssertNil(mock.ExpectationsWereMet())
+		t.Assert(atomic.LoadInt32(&deleted), 0)
+	})
+}
+
+func Test_AttachFile_Policy(t *testing.T) {
+	ctx := context.Background()
+	policy := &UploadPolicy{AllowedMIMETypes: []string{"image/png"}, UserQuota: 10}
+
+	gtest.C(t, func(t *gtest.T) {
+		// 关联到其他模块时按目标策略嗅探存储中的实际内容
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newTransferHandler(t.T, "hello"))
+		m.RegisterUploadPolicy(2, 0, policy)
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Module: 1, Status: int(FileStatusUploadSuccess), Size: 5, OwnerID: "u1", LogicalID: "f1"}))
+
+		err := m.AttachFile(ctx, nil, "f1", 2, "c1", 3)
+		t.Assert(err, ErrFileTypeNotAllowed)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 尚未确认上传的文件无法嗅探，不允许关联到有策略的其他模块
+		m, mock := newMockFileManager(t.T, nil, nil)
+		m.RegisterUploadPolicy(2, 0, policy)
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Module: 1, Status: int(FileStatusInit), Size: 5, OwnerID: "u1", LogicalID: "f1"}))
+
+		err := m.AttachFile(ctx, nil, "f1", 2, "c1", 3)
+		t.Assert(err, ErrUploadNotConfirmed)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 上传时已按同一模块校验的文件不重复嗅探，配额按上传者统计且不重复计入该文件
+		m, mock := newMockFileManager(t.T, nil, nil)
+		m.RegisterUploadPolicy(2, 0, policy)
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Module: 2, Status: int(FileStatusUploadSuccess), Size: 5, OwnerID: "u1", LogicalID: "f1"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT SUM\\(size\\) FROM t_file WHERE .*logical_id != .* FOR UPDATE").
+			WithArgs("u1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 2, "f1").
+			WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(6))
+		mock.ExpectRollback()
+
+		err := m.AttachFile(ctx, nil, "f1", 2, "c1", 3)
+		t.Assert(err, ErrQuotaExceeded)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+func Test_IsUploadRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		ok   bool
+	}{
+		{name: "nil", err: nil, ok: false},
+		{name: "type not allowed", err: ErrFileTypeNotAllowed, ok: true},
+		{name: "wrapped size limit", err: gerror.Wrap(ErrFileTooLarge, "put content failed"), ok: true},
+		{name: "wrapped hash mismatch", err: fmt.Errorf("complete upload: %w", ErrFileHashMismatch), ok: true},
+		{name: "not confirmed yet", err: ErrUploadNotConfirmed, ok: false},
+		{name: "engine error", err: newEngineError("get file", 503, nil), ok: false},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			t.Assert(isUploadRejected(c.err), c.ok)
+		}
+	})
+}
@@ -0,0 +1,90 @@
+package FileModule
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+)
+
+// webhookSignatureHeader 上传完成回调的签名请求头，值为请求体的 HMAC-SHA256(十六进制)
+const webhookSignatureHeader = "X-FileEngine-Signature"
+
+// HandleUploadWebhook 接收文件引擎的上传完成回调
+// 回调只作为触发信号，文件是否上传成功始终以向文件引擎查询的结果为准
+func (m *FileManager) HandleUploadWebhook(r *ghttp.Request) {
+	ctx := r.GetCtx()
+	body := r.GetBody()
+
+	if !m.verifyWebhookSignature(body, r.Header.Get(webhookSignatureHeader)) {
+		r.Response.WriteStatus(http.StatusUnauthorized)
+		r.Response.WriteJsonExit(g.Map{"code": http.StatusUnauthorized, "message": ErrInvalidWebhook.Error()})
+	}
+
+	var req UploadWebhookReq
+	if err := json.Unmarshal(body, &req); err != nil || req.FileID == "" {
+		r.Response.WriteStatus(http.StatusBadRequest)
+		r.Response.WriteJsonExit(g.Map{"code": http.StatusBadRequest, "message": ErrInvalidWebhook.Error()})
+	}
+
+	err := m.CompleteUpload(ctx, req.FileID)
+	if err != nil && !isUploadRejected(err) {
+		m.logger.Errorf(ctx, "handle upload webhook failed, fileID: %s, success: %v, err: %v", req.FileID, req.Success, err)
+		r.Response.WriteStatus(http.StatusInternalServerError)
+		r.Response.WriteJsonExit(g.Map{"code": http.StatusInternalServerError, "message": err.Error()})
+	}
+
+	r.Response.WriteJsonExit(g.Map{"code": 0, "message": "OK"})
+}
+
+func (m *FileManager) verifyWebhookSignature(body []byte, signature string) bool {
+	if m.config.WebhookSecret == "" {
+		return true
+	}
+
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(m.config.WebhookSecret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// ReconcileUploads 对账：向文件引擎确认处于初始化状态的文件，返回确认完成(成功或失败)的数量
+func (m *FileManager) ReconcileUploads(ctx context.Context) (count int, err error) {
+	const batchSize = 100
+
+	createdBefore := time.Now().Add(-m.config.ReconcileDelay)
+	var afterID int64
+	for {
+		files, err := m.dao.ListPendingUploads(ctx, createdBefore, afterID, batchSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, info := range files {
+			afterID = info.ID
+
+			err = m.CompleteUpload(ctx, info.FileID)
+			switch {
+			case err == nil || isUploadRejected(err):
+				count++
+			case err == ErrUploadNotConfirmed:
+			default:
+				m.logger.Errorf(ctx, "reconcile upload failed, fileID: %s, err: %v", info.FileID, err)
+			}
+		}
+
+		if len(files) < batchSize {
+			return count, nil
+		}
+	}
+}
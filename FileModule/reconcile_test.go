@@ -0,0 +1,168 @@
+package FileModule
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func sign(secret string, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func Test_VerifyWebhookSignature(t *testing.T) {
+	body := `{"file_id":"f1","success":true}`
+	cases := []struct {
+		name      string
+		secret    string
+		signature string
+		ok        bool
+	}{
+		{name: "no secret configured", secret: "", signature: "", ok: true},
+		{name: "valid signature", secret: "s1", signature: sign("s1", body), ok: true},
+		{name: "signed with another secret", secret: "s1", signature: sign("s2", body), ok: false},
+		{name: "missing signature", secret: "s1", signature: "", ok: false},
+		{name: "not hex", secret: "s1", signature: "zz", ok: false},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			m := &FileManager{config: &Config{WebhookSecret: c.secret}}
+			t.Assert(m.verifyWebhookSignature([]byte(body), c.signature), c.ok)
+		}
+	})
+}
+
+// newEngineFileHandler 模拟文件引擎查询文件状态，statuses 为文件ID到存储状态的映射
+func newEngineFileHandler(t *testing.T, statuses map[string]string, content string) http.HandlerFunc {
+	transfer := newTransferHandler(t, content)
+	return func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "/api/v1/file-engine/files/"
+		fileID := strings.TrimPrefix(r.URL.Path, prefix)
+		if status, ok := statuses[fileID]; ok && r.Method == http.MethodGet {
+			_, _ = fmt.Fprintf(w, `{"id":"%s","status":"%s","size":%d}`, fileID, status, len(content))
+			return
+		}
+		transfer(w, r)
+	}
+}
+
+func Test_HandleUploadWebhook(t *testing.T) {
+	m, mock := newMockFileManager(t, &Config{WebhookSecret: "secret"}, newEngineFileHandler(t, map[string]string{"f1": engineFileStatusUploaded}, "hello"))
+	var fired int32
+	m.RegisterUploadCallback(0, 0, func(ctx context.Context, event *UploadEvent) {
+		atomic.AddInt32(&fired, 1)
+	})
+
+	// g.Server 按名称复用实例，名称唯一避免 -count 多次运行时重复注册路由
+	s := g.Server(fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano()))
+	s.BindHandler("POST:/webhook", m.HandleUploadWebhook)
+	s.SetDumpRouterMap(false)
+	s.SetAccessLogEnabled(false)
+	s.SetPort(0)
+	s.Start()
+	defer s.Shutdown()
+
+	client := g.Client().SetPrefix(fmt.Sprintf("http://127.0.0.1:%d", s.GetListenedPort()))
+	body := `{"file_id":"f1","success":true}`
+	post := func(signature string) int {
+		res, err := client.Header(map[string]string{webhookSignatureHeader: signature}).Post(context.Background(), "/webhook", body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Close()
+		_, _ = io.ReadAll(res.Body)
+		return res.StatusCode
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		// 签名不正确的回调不处理
+		t.Assert(post(sign("other", body)), http.StatusUnauthorized)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusInit), Size: 5}))
+		mock.ExpectExec("UPDATE t_file SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		expectFinishUpload(mock, true)
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusUploadSuccess), Size: 5}))
+
+		t.Assert(post(sign("secret", body)), http.StatusOK)
+		t.Assert(atomic.LoadInt32(&fired), 1)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 重复的回调不再触发上传完成回调
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusUploadSuccess), Size: 5}))
+
+		t.Assert(post(sign("secret", body)), http.StatusOK)
+		t.Assert(atomic.LoadInt32(&fired), 1)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+func Test_FinishUpload_Once(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		// 与对账并发完成时，状态已不是初始化的一方不上报、不触发回调
+		m, mock := newMockFileManager(t.T, nil, nil)
+		var fired int32
+		m.RegisterUploadCallback(0, 0, func(ctx context.Context, event *UploadEvent) {
+			atomic.AddInt32(&fired, 1)
+		})
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE t_file SET").WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "f1", FileStatusInit).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := m.finishUpload(ctx, "f1", true)
+		t.AssertNil(err)
+		t.Assert(atomic.LoadInt32(&fired), 0)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+func Test_ReconcileUploads(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		// 存储尚未确认的文件保持初始化状态，上传失败的文件标记失败
+		m, mock := newMockFileManager(t.T, nil, newEngineFileHandler(t.T, map[string]string{
+			"f1": engineFileStatusPending,
+			"f2": engineFileStatusFailed,
+		}, ""))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows(
+			&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusInit)},
+			&FileInfoEntity{ID: 2, FileID: "f2", Status: int(FileStatusInit)},
+		))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusInit)}))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 2, FileID: "f2", Status: int(FileStatusInit)}))
+		expectFinishUpload(mock, false)
+
+		count, err := m.ReconcileUploads(ctx)
+		t.AssertNil(err)
+		t.Assert(count, 1)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
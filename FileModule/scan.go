@@ -0,0 +1,125 @@
+package FileModule
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+const (
+	scanBatchSize = 20
+	// 扫描失败后重试间隔的上限
+	scanMaxRetryDelay = time.Hour
+)
+
+// ScanUploads 扫描等待内容扫描的文件，返回完成扫描的数量
+// 未发现威胁的文件标记为上传成功，发现威胁的文件标记为已隔离；两者都会上报文件引擎并触发上传完成回调
+// 扫描失败(扫描器不可用、读取存储失败)的文件按 ScanInterval 指数退避重试，达到 ScanMaxAttempts 后标记上传失败
+func (m *FileManager) ScanUploads(ctx context.Context) (count int, err error) {
+	if m.config.Scanner == nil {
+		return 0, nil
+	}
+
+	var afterID int64
+	for {
+		files, err := m.dao.ListScanningFiles(ctx, afterID, scanBatchSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, info := range files {
+			afterID = info.ID
+			if info.ScanAttempts > 0 && time.Since(info.UpdateTime) < m.scanRetryDelay(info.ScanAttempts) {
+				continue
+			}
+
+			err = m.scanFile(ctx, info)
+			if err != nil {
+				m.logger.Errorf(ctx, "scan file failed, fileID: %s, attempts: %d, err: %v", info.FileID, info.ScanAttempts+1, err)
+				m.recordScanFailure(ctx, info)
+				continue
+			}
+			count++
+		}
+
+		if len(files) < scanBatchSize {
+			return count, nil
+		}
+	}
+}
+
+func (m *FileManager) scanFile(ctx context.Context, info *FileInfo) (err error) {
+	preDownload, err := m.fileEngine.PreDownload(ctx, info.StorageFileID())
+	if err != nil {
+		return err
+	}
+
+	body, _, err := m.fileEngine.GetContent(ctx, preDownload.DownloadURL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	result, err := m.config.Scanner.Scan(ctx, body)
+	if err != nil {
+		return err
+	}
+
+	status := FileStatusUploadSuccess
+	if result.Infected {
+		status = FileStatusQuarantined
+		m.logger.Warningf(ctx, "file quarantined, fileID: %s, signature: %s", info.FileID, result.Signature)
+	}
+
+	return m.finishScan(ctx, info.FileID, status)
+}
+
+// finishScan 将扫描中的文件更新为扫描结果，上报文件引擎并触发上传完成回调
+// 多个实例同时扫描同一文件时，只有一个能完成状态变更
+func (m *FileManager) finishScan(ctx context.Context, fileID string, status FileStatus) (err error) {
+	success := status == FileStatusUploadSuccess
+
+	var report *OutboxEvent
+	err = m.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		ok, err := m.dao.TransitStatus(ctx, tx, fileID, FileStatusScanning, status)
+		if err != nil || !ok {
+			return err
+		}
+
+		report, err = m.enqueueOutbox(ctx, tx, OutboxOpReportUpload, fileID, &OutboxPayload{Success: success})
+		return err
+	})
+	if err != nil || report == nil {
+		return err
+	}
+
+	return m.settleUpload(ctx, fileID, success, report)
+}
+
+// recordScanFailure 记录扫描失败，达到 ScanMaxAttempts 后将文件标记为上传失败，避免一直处于扫描中
+func (m *FileManager) recordScanFailure(ctx context.Context, info *FileInfo) {
+	attempts := info.ScanAttempts + 1
+	if attempts < m.config.ScanMaxAttempts {
+		err := m.dao.UpdateScanAttempts(ctx, info.FileID, attempts)
+		if err != nil {
+			m.logger.Errorf(ctx, "update scan attempts failed, fileID: %s, err: %v", info.FileID, err)
+		}
+		return
+	}
+
+	m.logger.Errorf(ctx, "scan file gave up, fileID: %s, attempts: %d", info.FileID, attempts)
+	err := m.finishScan(ctx, info.FileID, FileStatusUploadFailed)
+	if err != nil {
+		m.logger.Errorf(ctx, "mark unscannable file failed, fileID: %s, err: %v", info.FileID, err)
+	}
+}
+
+// scanRetryDelay 第 attempts 次扫描失败后的重试间隔
+func (m *FileManager) scanRetryDelay(attempts int) time.Duration {
+	delay := max(m.config.ScanInterval, time.Second)
+	for i := 1; i < attempts && delay < scanMaxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, scanMaxRetryDelay)
+}
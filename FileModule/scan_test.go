@@ -0,0 +1,138 @@
+package FileModule
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+// scannerFunc 以函数实现 Scanner
+type scannerFunc func(ctx context.Context, r io.Reader) (*ScanResult, error)
+
+func (f scannerFunc) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	return f(ctx, r)
+}
+
+func Test_ScanRetryDelay(t *testing.T) {
+	cases := []struct {
+		interval time.Duration
+		attempts int
+		delay    time.Duration
+	}{
+		{interval: 10 * time.Second, attempts: 1, delay: 10 * time.Second},
+		{interval: 10 * time.Second, attempts: 2, delay: 20 * time.Second},
+		{interval: 10 * time.Second, attempts: 4, delay: 80 * time.Second},
+		{interval: 10 * time.Second, attempts: 20, delay: scanMaxRetryDelay},
+		{interval: -1, attempts: 1, delay: time.Second},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			m := &FileManager{config: &Config{ScanInterval: c.interval}}
+			t.Assert(m.scanRetryDelay(c.attempts), c.delay)
+		}
+	})
+}
+
+func Test_ScanUploads(t *testing.T) {
+	ctx := context.Background()
+	clean := scannerFunc(func(ctx context.Context, r io.Reader) (*ScanResult, error) {
+		return &ScanResult{}, nil
+	})
+	infected := scannerFunc(func(ctx context.Context, r io.Reader) (*ScanResult, error) {
+		return &ScanResult{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+	})
+	broken := scannerFunc(func(ctx context.Context, r io.Reader) (*ScanResult, error) {
+		return nil, gerror.New("clamd unavailable")
+	})
+	scanning := func(attempts int, updateTime time.Time) *sqlmock.Rows {
+		return fileRows(&FileInfoEntity{ID: 1, FileID: "f1", Status: int(FileStatusScanning), ScanAttempts: attempts, UpdateTime: updateTime.Unix()})
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		// 扫描通过：扫描中 -> 上传成功并提升为当前版本
+		m, mock := newMockFileManager(t.T, &Config{Scanner: clean}, newTransferHandler(t.T, "hello"))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(scanning(0, time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE t_file SET").WithArgs(FileStatusUploadSuccess, sqlmock.AnyArg(), "f1", FileStatusScanning).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "f1", IsCurrent: 1}))
+		mock.ExpectCommit()
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		count, err := m.ScanUploads(ctx)
+		t.AssertNil(err)
+		t.Assert(count, 1)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 发现威胁：扫描中 -> 已隔离，不提升为当前版本
+		m, mock := newMockFileManager(t.T, &Config{Scanner: infected}, newTransferHandler(t.T, "hello"))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(scanning(0, time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE t_file SET").WithArgs(FileStatusQuarantined, sqlmock.AnyArg(), "f1", FileStatusScanning).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		count, err := m.ScanUploads(ctx)
+		t.AssertNil(err)
+		t.Assert(count, 1)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 扫描失败：记录失败次数，文件保持扫描中
+		m, mock := newMockFileManager(t.T, &Config{Scanner: broken, ScanMaxAttempts: 3}, newTransferHandler(t.T, "hello"))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(scanning(1, time.Now().Add(-time.Hour)))
+		mock.ExpectExec("UPDATE t_file SET .*scan_attempts").WithArgs(2, sqlmock.AnyArg(), "f1", FileStatusScanning).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		count, err := m.ScanUploads(ctx)
+		t.AssertNil(err)
+		t.Assert(count, 0)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 未到重试时间的文件不扫描
+		m, mock := newMockFileManager(t.T, &Config{Scanner: broken, ScanMaxAttempts: 3}, nil)
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(scanning(1, time.Now()))
+
+		count, err := m.ScanUploads(ctx)
+		t.AssertNil(err)
+		t.Assert(count, 0)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 达到最大尝试次数：扫描中 -> 上传失败，不再重试
+		m, mock := newMockFileManager(t.T, &Config{Scanner: broken, ScanMaxAttempts: 3}, newTransferHandler(t.T, "hello"))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(scanning(2, time.Now().Add(-time.Hour)))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE t_file SET").WithArgs(FileStatusUploadFailed, sqlmock.AnyArg(), "f1", FileStatusScanning).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+
+		count, err := m.ScanUploads(ctx)
+		t.AssertNil(err)
+		t.Assert(count, 0)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
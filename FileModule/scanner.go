@@ -0,0 +1,123 @@
+package FileModule
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// ScanResult 内容扫描结果
+type ScanResult struct {
+	// Infected 是否发现威胁
+	Infected bool `json:"infected"`
+	// Signature 命中的特征名称
+	Signature string `json:"signature"`
+}
+
+// Scanner 内容扫描器(病毒、恶意内容等)
+// 返回错误表示扫描未完成，文件保持扫描中状态并在下一轮重试
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (result *ScanResult, err error)
+}
+
+// clamAVChunkSize INSTREAM 每个数据块的大小
+const clamAVChunkSize = 64 << 10
+
+// ClamAVScanner 基于 clamd INSTREAM 协议的扫描器
+type ClamAVScanner struct {
+	network string
+	addr    string
+	timeout time.Duration
+}
+
+// NewClamAVScanner 创建 ClamAV 扫描器，addr 为 host:port 或 unix socket 路径(以 / 开头)
+// timeout 为单次扫描的超时时间，0 表示仅受 ctx 控制
+func NewClamAVScanner(addr string, timeout time.Duration) *ClamAVScanner {
+	network := "tcp"
+	if strings.HasPrefix(addr, "/") {
+		network = "unix"
+	}
+	return &ClamAVScanner{network: network, addr: addr, timeout: timeout}
+}
+
+// Scan 以 INSTREAM 方式将内容流式发送给 clamd
+// 协议：发送 "zINSTREAM\0"，随后是若干 [4字节大端长度][数据] 块，以长度为 0 的块结束；
+// 响应为 "stream: OK"、"stream: <特征> FOUND" 或 "<原因> ERROR"
+func (s *ClamAVScanner) Scan(ctx context.Context, r io.Reader) (result *ScanResult, err error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.addr)
+	if err != nil {
+		return nil, gerror.Newf("clamav dial failed, err: %s", err.Error())
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	err = s.sendStream(conn, r)
+	if err != nil {
+		return nil, gerror.Newf("clamav send stream failed, err: %s", err.Error())
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && err != io.EOF {
+		return nil, gerror.Newf("clamav read reply failed, err: %s", err.Error())
+	}
+
+	return parseClamAVReply(reply)
+}
+
+func (s *ClamAVScanner) sendStream(conn net.Conn, r io.Reader) error {
+	_, err := conn.Write([]byte("zINSTREAM\x00"))
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 4+clamAVChunkSize)
+	for {
+		n, readErr := r.Read(buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err = conn.Write(buf[:4+n]); err != nil {
+				return err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+
+	_, err = conn.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+// parseClamAVReply 解析 clamd 的扫描响应
+func parseClamAVReply(reply string) (result *ScanResult, err error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	default:
+		return nil, gerror.Newf("clamav scan failed, reply: %s", reply)
+	}
+}
@@ -0,0 +1,90 @@
+package FileModule
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// startClamdStub 启动一个实现 INSTREAM 的本地 clamd 桩服务，内容包含 EICAR 特征时返回 FOUND
+func startClamdStub(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveClamdStub(conn)
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func serveClamdStub(conn net.Conn) {
+	defer conn.Close()
+
+	command := make([]byte, len("zINSTREAM\x00"))
+	if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND ERROR\x00"))
+		return
+	}
+
+	var content bytes.Buffer
+	for {
+		var size uint32
+		if err := binary.Read(conn, binary.BigEndian, &size); err != nil {
+			return
+		}
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&content, conn, int64(size)); err != nil {
+			return
+		}
+	}
+
+	if strings.Contains(content.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+		conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func Test_ClamAVScanner(t *testing.T) {
+	ctx := context.Background()
+	scanner := NewClamAVScanner(startClamdStub(t), 5*time.Second)
+
+	gtest.C(t, func(t *gtest.T) {
+		result, err := scanner.Scan(ctx, strings.NewReader(eicar))
+		t.AssertNil(err)
+		t.Assert(result.Infected, true)
+		t.Assert(result.Signature, "Eicar-Test-Signature")
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 大于单个数据块的内容
+		result, err := scanner.Scan(ctx, bytes.NewReader(bytes.Repeat([]byte("a"), 3*clamAVChunkSize+1)))
+		t.AssertNil(err)
+		t.Assert(result.Infected, false)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		_, err := parseClamAVReply("INSTREAM size limit exceeded. ERROR\x00")
+		t.AssertNE(err, nil)
+	})
+}
@@ -0,0 +1,38 @@
+package FileModule
+
+import "io"
+
+// sizeLimitReader 在流式读取过程中统计字节数并校验大小
+// maxSize > 0 时超出即返回 ErrFileTooLarge；expected > 0 时读到 EOF 需与声明大小一致
+type sizeLimitReader struct {
+	r        io.Reader
+	n        int64
+	maxSize  int64
+	expected int64
+}
+
+func newSizeLimitReader(r io.Reader, maxSize int64, expected int64) *sizeLimitReader {
+	return &sizeLimitReader{r: r, maxSize: maxSize, expected: expected}
+}
+
+func (l *sizeLimitReader) Read(p []byte) (n int, err error) {
+	n, err = l.r.Read(p)
+	l.n += int64(n)
+
+	if l.maxSize > 0 && l.n > l.maxSize {
+		return n, ErrFileTooLarge
+	}
+	if l.expected > 0 && l.n > l.expected {
+		return n, ErrFileSizeMismatch
+	}
+	if err == io.EOF && l.expected > 0 && l.n != l.expected {
+		return n, ErrFileSizeMismatch
+	}
+
+	return n, err
+}
+
+// Size 返回已读取的字节数
+func (l *sizeLimitReader) Size() int64 {
+	return l.n
+}
@@ -0,0 +1,42 @@
+package FileModule
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_SizeLimitReader(t *testing.T) {
+	cases := []struct {
+		name     string
+		content  string
+		maxSize  int64
+		expected int64
+		err      error
+	}{
+		{name: "unlimited", content: "hello"},
+		{name: "within max size", content: "hello", maxSize: 5},
+		{name: "exceeds max size", content: "hello world", maxSize: 5, err: ErrFileTooLarge},
+		{name: "matches declared size", content: "hello", expected: 5},
+		{name: "shorter than declared", content: "hell", expected: 5, err: ErrFileSizeMismatch},
+		{name: "longer than declared", content: "hello world", expected: 5, err: ErrFileSizeMismatch},
+		{name: "max size checked first", content: "hello world", maxSize: 5, expected: 5, err: ErrFileTooLarge},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			reader := newSizeLimitReader(strings.NewReader(c.content), c.maxSize, c.expected)
+			data, err := io.ReadAll(reader)
+			if c.err == nil {
+				t.AssertNil(err)
+				t.Assert(string(data), c.content)
+				t.Assert(reader.Size(), len(c.content))
+				continue
+			}
+			t.Assert(errors.Is(err, c.err), true)
+		}
+	})
+}
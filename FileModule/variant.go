@@ -0,0 +1,291 @@
+package FileModule
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+const (
+	variantBatchSize    = 20
+	variantStuckTimeout = 10 * time.Minute
+)
+
+// RegisterVariantProfiles 注册文件类型的衍生图规格
+// 文件上传成功且关联了该类型后，后台任务会按规格异步生成衍生图
+func (m *FileManager) RegisterVariantProfiles(typ FileType, profiles ...*VariantProfile) error {
+	names := make(map[string]bool, len(profiles))
+	for _, profile := range profiles {
+		if profile == nil || profile.Name == "" {
+			return gerror.New("variant profile name is required")
+		}
+		if profile.Width < 0 || profile.Height < 0 {
+			return gerror.Newf("variant profile %s: invalid size", profile.Name)
+		}
+		if names[profile.Name] {
+			return gerror.Newf("variant profile %s: duplicate name", profile.Name)
+		}
+		names[profile.Name] = true
+	}
+
+	m.variantMutex.Lock()
+	defer m.variantMutex.Unlock()
+
+	if m.variantProfiles == nil {
+		m.variantProfiles = make(map[FileType][]*VariantProfile)
+	}
+	m.variantProfiles[typ] = profiles
+	return nil
+}
+
+func (m *FileManager) getVariantProfile(typ FileType, name string) *VariantProfile {
+	m.variantMutex.RLock()
+	defer m.variantMutex.RUnlock()
+
+	for _, profile := range m.variantProfiles[typ] {
+		if profile.Name == name {
+			return profile
+		}
+	}
+	return nil
+}
+
+func (m *FileManager) hasVariantProfiles() bool {
+	m.variantMutex.RLock()
+	defer m.variantMutex.RUnlock()
+
+	return len(m.variantProfiles) > 0
+}
+
+// enqueueVariants 为上传成功的文件创建待生成的衍生图记录
+func (m *FileManager) enqueueVariants(ctx context.Context, tx gdb.TX, info *FileInfo) error {
+	if info.Status != FileStatusUploadSuccess {
+		return nil
+	}
+
+	m.variantMutex.RLock()
+	profiles := m.variantProfiles[info.Type]
+	m.variantMutex.RUnlock()
+	if len(profiles) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(profiles))
+	for _, profile := range profiles {
+		names = append(names, profile.Name)
+	}
+	return m.dao.CreateVariants(ctx, tx, info.FileID, names)
+}
+
+// variantUploadCallback 上传完成回调：已关联类型的文件上传成功后生成衍生图
+func (m *FileManager) variantUploadCallback(ctx context.Context, event *UploadEvent) {
+	if !event.Success {
+		return
+	}
+
+	err := m.enqueueVariants(ctx, nil, event.File)
+	if err != nil {
+		m.logger.Errorf(ctx, "enqueue variants failed, fileID: %s, err: %v", event.File.FileID, err)
+	}
+}
+
+// ListVariants 获取文件的衍生图
+func (m *FileManager) ListVariants(ctx context.Context, fileID string) (out []*FileVariant, err error) {
+	return m.dao.ListVariants(ctx, fileID)
+}
+
+// ProcessVariants 生成待处理的衍生图，返回处理数量
+func (m *FileManager) ProcessVariants(ctx context.Context) (count int, err error) {
+	if !m.hasVariantProfiles() {
+		return 0, nil
+	}
+
+	for {
+		variants, err := m.dao.ListPendingVariants(ctx, time.Now().Add(-variantStuckTimeout), variantBatchSize)
+		if err != nil {
+			return count, err
+		}
+
+		claimed := 0
+		for _, variant := range variants {
+			ok, err := m.dao.ClaimVariant(ctx, variant)
+			if err != nil {
+				return count, err
+			}
+			if !ok {
+				continue
+			}
+			claimed++
+
+			m.processVariant(ctx, variant)
+			count++
+		}
+
+		if len(variants) < variantBatchSize || claimed == 0 {
+			return count, nil
+		}
+	}
+}
+
+func (m *FileManager) processVariant(ctx context.Context, variant *FileVariant) {
+	err := m.generateVariant(ctx, variant)
+	if err == nil {
+		return
+	}
+
+	retryCount := variant.RetryCount + 1
+	status := VariantStatusPending
+	if retryCount >= m.config.VariantMaxRetry {
+		status = VariantStatusFailed
+	}
+	m.logger.Errorf(ctx, "generate variant failed, fileID: %s, name: %s, retry: %d, err: %v", variant.FileID, variant.Name, retryCount, err)
+
+	err = m.dao.UpdateVariantFailure(ctx, variant.ID, status, retryCount, err.Error())
+	if err != nil {
+		m.logger.Errorf(ctx, "update variant failed, fileID: %s, name: %s, err: %v", variant.FileID, variant.Name, err)
+	}
+}
+
+// generateVariant 读取原图、按规格生成衍生图并写入存储
+// 衍生图没有 t_file 记录，存储文件ID在上传前即写入衍生图记录，进程中途退出时遗留的存储文件
+// 在重新生成时回收，原图被回收时随衍生图记录一起删除
+func (m *FileManager) generateVariant(ctx context.Context, variant *FileVariant) error {
+	if variant.StorageID != "" {
+		err := m.releaseVariantStorage(ctx, variant)
+		if err != nil {
+			return err
+		}
+	}
+
+	info, err := m.dao.Get(ctx, variant.FileID)
+	if err != nil {
+		return err
+	}
+	profile := m.getVariantProfile(info.Type, variant.Name)
+	if profile == nil {
+		return gerror.Newf("variant profile %s not registered for file type %d", variant.Name, info.Type)
+	}
+
+	src, err := m.readContent(ctx, info, m.config.VariantMaxSourceSize)
+	if err != nil {
+		return err
+	}
+
+	content, contentType, width, height, err := renderVariant(src, profile, m.config.VariantMaxPixels)
+	if err != nil {
+		return err
+	}
+
+	preUpload, err := m.fileEngine.PreUpload(ctx, &PreUploadReq{
+		FileName:    variantFileName(info.FileName, profile),
+		ContentType: contentType,
+		Size:        int64(len(content)),
+	})
+	if err != nil {
+		return err
+	}
+
+	err = m.dao.UpdateVariantStorage(ctx, nil, variant.ID, preUpload.FileID)
+	if err != nil {
+		m.reportVariantFailure(ctx, variant, preUpload.FileID)
+		return err
+	}
+
+	err = m.fileEngine.PutContent(ctx, preUpload.UploadURL, bytes.NewReader(content), int64(len(content)), contentType)
+	if err != nil {
+		m.reportVariantFailure(ctx, variant, preUpload.FileID)
+		return err
+	}
+
+	err = m.fileEngine.ReportUploadResult(ctx, preUpload.FileID, true)
+	if err != nil {
+		return err
+	}
+
+	err = m.dao.UpdateVariantSuccess(ctx, variant.ID, &FileVariant{
+		StorageID:   preUpload.FileID,
+		FileLink:    preUpload.FileLink,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		Width:       width,
+		Height:      height,
+	})
+	if err != nil {
+		// 记录未写入，回收已上传的衍生图，重试时重新生成
+		m.deleteOrphanStorage(ctx, preUpload.FileID)
+		return err
+	}
+	return nil
+}
+
+// reportVariantFailure 向文件引擎上报衍生图写入失败，上报失败只记录日志，存储文件在重新生成时回收
+func (m *FileManager) reportVariantFailure(ctx context.Context, variant *FileVariant, storageID string) {
+	err := m.fileEngine.ReportUploadResult(ctx, storageID, false)
+	if err != nil {
+		m.logger.Errorf(ctx, "report variant upload failed, fileID: %s, name: %s, storageID: %s, err: %v", variant.FileID, variant.Name, storageID, err)
+	}
+}
+
+// releaseVariantStorage 回收上一次未完成的生成遗留的存储文件
+func (m *FileManager) releaseVariantStorage(ctx context.Context, variant *FileVariant) error {
+	var event *OutboxEvent
+	err := m.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) (err error) {
+		event, err = m.enqueueOutbox(ctx, tx, OutboxOpDeleteFile, variant.StorageID, nil)
+		if err != nil {
+			return err
+		}
+		return m.dao.UpdateVariantStorage(ctx, tx, variant.ID, "")
+	})
+	if err != nil {
+		return err
+	}
+
+	m.dispatchOutbox(ctx, event)
+	variant.StorageID = ""
+	return nil
+}
+
+// readContent 读取存储中的文件内容，超过 maxSize 时返回 ErrFileTooLarge
+func (m *FileManager) readContent(ctx context.Context, info *FileInfo, maxSize int64) ([]byte, error) {
+	preDownload, err := m.fileEngine.PreDownload(ctx, info.StorageFileID())
+	if err != nil {
+		return nil, err
+	}
+
+	body, _, err := m.fileEngine.GetContent(ctx, preDownload.DownloadURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(newSizeLimitReader(body, maxSize, 0))
+}
+
+// deleteVariants 在事务 tx 中删除文件的全部衍生图记录，并返回删除衍生图存储的事件
+func (m *FileManager) deleteVariants(ctx context.Context, tx gdb.TX, fileID string) (events []*OutboxEvent, err error) {
+	variants, err := m.dao.ListVariants(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, variant := range variants {
+		if variant.StorageID == "" {
+			continue
+		}
+		event, err := m.enqueueOutbox(ctx, tx, OutboxOpDeleteFile, variant.StorageID, nil)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	err = m.dao.DeleteVariants(ctx, tx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
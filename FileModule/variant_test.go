@@ -0,0 +1,40 @@
+package FileModule
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_PreDownloadVariant_EmptyName(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		m, mock := newMockFileManager(t.T, nil, nil)
+
+		_, err := m.PreDownloadVariant(context.Background(), "f1", "")
+		t.Assert(err, ErrVariantNotFound)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+func Test_GenerateVariant_ReleasesLeftoverStorage(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		// 上一次生成遗留的存储文件先回收并清空记录，再重新生成
+		m, mock := newMockFileManager(t.T, &Config{EngineMaxRetry: -1}, newTransferHandler(t.T, ""))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO t_file_outbox").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE t_file_variant SET").WithArgs("", sqlmock.AnyArg(), int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectExec("UPDATE t_file_outbox SET").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM t_file_outbox").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").WillReturnRows(fileRows())
+
+		variant := &FileVariant{ID: 7, FileID: "f1", Name: "thumb", StorageID: "old"}
+		err := m.generateVariant(context.Background(), variant)
+		t.Assert(err, ErrFileNotFound)
+		t.Assert(variant.StorageID, "")
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,116 @@
+package FileModule
+
+import (
+	"context"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+)
+
+// createFile 创建文件记录
+// 指定了 LogicalID 时创建为该逻辑文件的新版本：沿用当前版本的关联与引用数，上传成功后成为当前版本
+func (m *FileManager) createFile(ctx context.Context, in *FileInfo) (err error) {
+	fillOwner(ctx, in)
+
+	return m.dao.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		if in.LogicalID != "" {
+			source, err := m.dao.Get(ctx, in.LogicalID)
+			if err != nil {
+				return err
+			}
+			if source.IsDeleted() {
+				return ErrFileDeleted
+			}
+
+			// 锁定当前版本后再分配版本号，同一逻辑文件的并发上传依次分配
+			current, err := m.dao.GetCurrentVersion(ctx, tx, source.LogicalFileID())
+			if err != nil {
+				return err
+			}
+			maxVersion, err := m.dao.MaxVersion(ctx, tx, current.LogicalFileID())
+			if err != nil {
+				return err
+			}
+
+			in.LogicalID = current.LogicalFileID()
+			in.Version = maxVersion + 1
+			in.RefCount, in.RequireRef = current.RefCount, current.RequireRef
+			in.Module, in.CustomID, in.Type = current.Module, current.CustomID, current.Type
+		}
+
+		err := m.dao.Create(ctx, tx, in)
+		if err != nil {
+			return err
+		}
+
+		// 秒传的新版本创建即上传成功
+		if in.Status == FileStatusUploadSuccess {
+			_, err = m.dao.PromoteVersion(ctx, in.FileID)
+		}
+		return err
+	})
+}
+
+// Delete 将文件(逻辑文件的全部版本)移入回收站
+// 回收站中的文件不可下载、不出现在业务列表中，保留 TrashRetention 后彻底删除，期间可通过 Restore 恢复
+func (m *FileManager) Delete(ctx context.Context, tx gdb.TX, fileID string) (err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if info.IsDeleted() {
+		return ErrFileDeleted
+	}
+
+	return m.dao.MoveToTrash(ctx, tx, info.LogicalFileID())
+}
+
+// Restore 从回收站恢复文件(逻辑文件的全部版本)，业务关联保持不变
+func (m *FileManager) Restore(ctx context.Context, tx gdb.TX, fileID string) (err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return err
+	}
+	if !info.IsDeleted() {
+		return nil
+	}
+
+	return m.dao.RestoreFromTrash(ctx, tx, info.LogicalFileID())
+}
+
+// ListVersions 获取文件所属逻辑文件的全部版本(按版本号降序)
+func (m *FileManager) ListVersions(ctx context.Context, fileID string) (out []*FileInfo, err error) {
+	info, err := m.dao.Get(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.dao.ListVersions(ctx, info.LogicalFileID())
+}
+
+// PurgeTrash 彻底删除回收站中超过保留期的文件，返回删除数量
+// 存储、衍生图及逻辑文件附属数据的删除同垃圾回收
+func (m *FileManager) PurgeTrash(ctx context.Context) (count int, err error) {
+	cutoff := time.Now().Add(-m.config.TrashRetention)
+
+	var afterID int64
+	for {
+		files, err := m.dao.ListExpiredTrash(ctx, cutoff, afterID, gcBatchSize)
+		if err != nil {
+			return count, err
+		}
+
+		for _, info := range files {
+			afterID = info.ID
+
+			item := m.collectFile(ctx, info, false)
+			if item.Error == "" {
+				count++
+			}
+		}
+
+		if len(files) < gcBatchSize {
+			return count, nil
+		}
+	}
+}
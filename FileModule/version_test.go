@@ -0,0 +1,55 @@
+package FileModule
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_CreateFile_Version(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		// 锁定当前版本后在同一事务中分配版本号并写入
+		m, mock := newMockFileManager(t.T, nil, nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "v1", LogicalID: "l1", Version: 1, IsCurrent: 1}))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .*is_current = 1.* FOR UPDATE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "v1", LogicalID: "l1", Version: 1, IsCurrent: 1, RefCount: 2, RequireRef: 1, Module: 3}))
+		mock.ExpectQuery("SELECT MAX\\(version\\) FROM t_file").
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(4))
+		mock.ExpectExec("INSERT INTO t_file").WillReturnResult(sqlmock.NewResult(5, 1))
+		mock.ExpectCommit()
+
+		in := &FileInfo{FileID: "v5", LogicalID: "v1", Status: FileStatusInit}
+		err := m.createFile(context.Background(), in)
+		t.AssertNil(err)
+		t.Assert(in.LogicalID, "l1")
+		t.Assert(in.Version, 5)
+		t.Assert(in.RefCount, 2)
+		t.Assert(in.Module, 3)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 版本号冲突(唯一索引)时回滚，不留下重复版本
+		m, mock := newMockFileManager(t.T, nil, nil)
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "v1", LogicalID: "l1", Version: 1, IsCurrent: 1}))
+		mock.ExpectQuery("SELECT .* FROM t_file WHERE .* FOR UPDATE").
+			WillReturnRows(fileRows(&FileInfoEntity{ID: 1, FileID: "v1", LogicalID: "l1", Version: 1, IsCurrent: 1}))
+		mock.ExpectQuery("SELECT MAX\\(version\\) FROM t_file").
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(1))
+		mock.ExpectExec("INSERT INTO t_file").WillReturnError(errDuplicateVersion)
+		mock.ExpectRollback()
+
+		err := m.createFile(context.Background(), &FileInfo{FileID: "v2", LogicalID: "l1", Status: FileStatusInit})
+		t.AssertNE(err, nil)
+		t.AssertNil(mock.ExpectationsWereMet())
+	})
+}
+
+var errDuplicateVersion = gerror.New("Duplicate entry 'l1-2' for key 'idx_logical_id_version'")
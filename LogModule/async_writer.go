@@ -0,0 +1,324 @@
+package LogModule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+// flushTimeout 后台写库的超时时间
+const flushTimeout = 30 * time.Second
+
+// batchSink 批量写库函数
+type batchSink func(ctx context.Context, entities []*LogEntity) error
+
+// permanentWriteErrors 数据本身不合法导致的 MySQL 错误码，重试不会成功
+var permanentWriteErrors = map[uint16]bool{
+	1048: true, // 字段不能为空
+	1062: true, // 唯一键冲突
+	1264: true, // 数值超出范围
+	1292: true, // 时间值不合法
+	1366: true, // 字符串编码不合法
+	1406: true, // 字段超长
+}
+
+// isPermanentWriteError 写库错误是否由数据本身导致
+// 无法判断的错误(连接断开、超时、锁等待等)按临时错误处理，保留日志等待重试
+func isPermanentWriteError(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return permanentWriteErrors[mysqlErr.Number]
+	}
+	return false
+}
+
+// writeEachRow 逐条写库，用于整批被数据库拒绝时找出不合法的日志
+// 返回已处理(写库或被拒绝)的条数与被拒绝的日志；遇到临时性错误时停止，batch[handled:] 均未写库
+func writeEachRow(ctx context.Context, sink batchSink, batch []*LogEntity) (handled int, rejected []*LogEntity, err error) {
+	for _, entity := range batch {
+		err = sink(ctx, []*LogEntity{entity})
+		if err != nil {
+			if !isPermanentWriteError(err) {
+				return handled, rejected, err
+			}
+			rejected = append(rejected, entity)
+		}
+		handled++
+	}
+	return handled, rejected, nil
+}
+
+// asyncWriter 异步日志写入器
+// BatchWrite 将日志放入有界队列后立即返回，后台协程在积累到 MaxBatch 条或到达 FlushInterval 时批量写库
+// 写库失败的日志在配置了 SpillDir 时落盘，数据库恢复后重新写入
+type asyncWriter struct {
+	sink     batchSink
+	logger   *glog.Logger
+	queue    chan *LogEntity
+	maxBatch int
+	interval time.Duration
+	policy   OverflowPolicy
+	spill    *spillStore
+
+	// closeMutex 保证 Close 之后不再有日志入队
+	closeMutex sync.RWMutex
+	closed     bool
+	stop       chan struct{}
+	done       chan struct{}
+
+	// healthy 上一次写库是否未出现临时性错误，出现时暂停重放落盘的日志
+	healthy bool
+
+	written      atomic.Int64
+	dropped      atomic.Int64
+	spilled      atomic.Int64
+	deadLettered atomic.Int64
+}
+
+func newAsyncWriter(config *Config, sink batchSink, logger *glog.Logger) (*asyncWriter, error) {
+	w := &asyncWriter{
+		sink:     sink,
+		logger:   logger,
+		queue:    make(chan *LogEntity, config.QueueSize),
+		maxBatch: config.MaxBatch,
+		interval: config.FlushInterval,
+		policy:   config.OverflowPolicy,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		healthy:  true,
+	}
+
+	if config.SpillDir != "" {
+		spill, err := newSpillStore(config.SpillDir, logger)
+		if err != nil {
+			return nil, err
+		}
+		w.spill = spill
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Write 将日志放入队列，队列已满时按 OverflowPolicy 处理
+// OverflowBlock 策略下 ctx 结束时返回 ctx 的错误，此时 ctx 结束前的日志已入队
+// 其他策略下有日志因队列已满被丢弃(含落盘失败)时返回 ErrQueueFull，未被丢弃的日志已入队
+func (w *asyncWriter) Write(ctx context.Context, entities []*LogEntity) error {
+	w.closeMutex.RLock()
+	defer w.closeMutex.RUnlock()
+
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	var (
+		overflow []*LogEntity
+		dropped  bool
+	)
+	for i, entity := range entities {
+		select {
+		case w.queue <- entity:
+			continue
+		default:
+		}
+
+		switch w.policy {
+		case OverflowBlock:
+			select {
+			case w.queue <- entity:
+			case <-ctx.Done():
+				w.logger.Errorf(ctx, "log queue full, %d logs not written: %v", len(entities)-i, ctx.Err())
+				return ctx.Err()
+			}
+		case OverflowDropOldest:
+			select {
+			case <-w.queue:
+				w.dropped.Add(1)
+				dropped = true
+			default:
+			}
+			select {
+			case w.queue <- entity:
+			default:
+				w.dropped.Add(1)
+				dropped = true
+			}
+		default:
+			overflow = append(overflow, entity)
+		}
+	}
+
+	if len(overflow) > 0 && !w.overflow(ctx, overflow) {
+		dropped = true
+	}
+	if dropped {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+// overflow 处理队列已满时未入队的日志：OverflowSpill 策略下落盘，否则丢弃；返回日志是否已落盘
+func (w *asyncWriter) overflow(ctx context.Context, entities []*LogEntity) bool {
+	if w.policy == OverflowSpill && w.spill != nil {
+		err := w.spill.Append(entities)
+		if err == nil {
+			w.spilled.Add(int64(len(entities)))
+			return true
+		}
+		w.logger.Errorf(ctx, "spill logs failed: %v", err)
+	}
+
+	w.dropped.Add(int64(len(entities)))
+	w.logger.Errorf(ctx, "log queue full, %d logs dropped, total dropped: %d", len(entities), w.dropped.Load())
+	return false
+}
+
+func (w *asyncWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	batch := make([]*LogEntity, 0, w.maxBatch)
+	for {
+		select {
+		case entity := <-w.queue:
+			batch = append(batch, entity)
+			if len(batch) >= w.maxBatch {
+				w.flush(batch)
+				batch = make([]*LogEntity, 0, w.maxBatch)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = make([]*LogEntity, 0, w.maxBatch)
+			}
+			w.replay()
+		case <-w.stop:
+			// 关闭时写完队列中剩余的日志
+			for {
+				select {
+				case entity := <-w.queue:
+					batch = append(batch, entity)
+					if len(batch) >= w.maxBatch {
+						w.flush(batch)
+						batch = make([]*LogEntity, 0, w.maxBatch)
+					}
+				default:
+					if len(batch) > 0 {
+						w.flush(batch)
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// flush 批量写库，失败时落盘或丢弃
+// 整批被数据库拒绝时逐条重试，只有不合法的日志写入死信文件，不再重试
+func (w *asyncWriter) flush(batch []*LogEntity) {
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	err := w.sink(ctx, batch)
+	if err == nil {
+		w.healthy = true
+		w.written.Add(int64(len(batch)))
+		return
+	}
+
+	if isPermanentWriteError(err) {
+		handled, rejected, rowErr := writeEachRow(ctx, w.sink, batch)
+		w.written.Add(int64(handled - len(rejected)))
+		if len(rejected) > 0 {
+			w.deadLetter(ctx, rejected, err)
+		}
+		// 数据不合法不代表数据库不可用，不暂停重放
+		w.healthy = rowErr == nil
+		if rowErr == nil {
+			return
+		}
+		batch, err = batch[handled:], rowErr
+	} else {
+		w.healthy = false
+	}
+
+	if w.spill != nil {
+		spillErr := w.spill.Append(batch)
+		if spillErr == nil {
+			w.spilled.Add(int64(len(batch)))
+			w.logger.Warningf(ctx, "write logs failed, %d logs spilled to disk: %v", len(batch), err)
+			return
+		}
+		w.logger.Errorf(ctx, "spill logs failed: %v", spillErr)
+	}
+
+	w.dropped.Add(int64(len(batch)))
+	w.logger.Errorf(ctx, "write logs failed, %d logs dropped: %v", len(batch), err)
+}
+
+// deadLetter 将被数据库拒绝的日志写入死信文件，未配置 SpillDir 或写入失败时丢弃
+func (w *asyncWriter) deadLetter(ctx context.Context, rejected []*LogEntity, cause error) {
+	if w.spill != nil {
+		err := w.spill.DeadLetter(rejected)
+		if err == nil {
+			w.deadLettered.Add(int64(len(rejected)))
+			w.logger.Errorf(ctx, "write logs rejected by database, %d logs moved to dead letter: %v", len(rejected), cause)
+			return
+		}
+		w.logger.Errorf(ctx, "dead letter logs failed: %v", err)
+	}
+
+	w.dropped.Add(int64(len(rejected)))
+	w.logger.Errorf(ctx, "write logs rejected by database, %d logs dropped: %v", len(rejected), cause)
+}
+
+// replay 重新写入落盘的日志，上一次写库临时性失败时跳过
+// 被数据库拒绝的批次由 spillStore 移入死信文件，只有临时性错误会暂停重放
+func (w *asyncWriter) replay() {
+	if w.spill == nil || !w.healthy {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+	defer cancel()
+
+	count, err := w.spill.Replay(ctx, w.sink, w.maxBatch)
+	if count > 0 {
+		w.written.Add(int64(count))
+		w.logger.Infof(ctx, "replay %d spilled logs", count)
+	}
+	if err != nil {
+		w.healthy = false
+		w.logger.Warningf(ctx, "replay spilled logs failed: %v", err)
+	}
+}
+
+// Close 停止接收日志并写完队列中剩余的日志，ctx 结束时不再等待
+func (w *asyncWriter) Close(ctx context.Context) error {
+	w.closeMutex.Lock()
+	if w.closed {
+		w.closeMutex.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.closeMutex.Unlock()
+
+	close(w.stop)
+	select {
+	case <-w.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if w.spill != nil {
+		return w.spill.Close()
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package LogModule
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+// memorySink 记录写库结果的内存 sink，fail 为 true 时写库失败
+type memorySink struct {
+	mutex   sync.Mutex
+	fail    bool
+	batches [][]*LogEntity
+}
+
+func (s *memorySink) sink(ctx context.Context, entities []*LogEntity) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.fail {
+		return errors.New("db unavailable")
+	}
+	s.batches = append(s.batches, append([]*LogEntity(nil), entities...))
+	return nil
+}
+
+func (s *memorySink) count() (n int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, batch := range s.batches {
+		n += len(batch)
+	}
+	return n
+}
+
+func (s *memorySink) setFail(fail bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.fail = fail
+}
+
+func newTestEntities(n int) []*LogEntity {
+	entities := make([]*LogEntity, 0, n)
+	for i := 0; i < n; i++ {
+		entities = append(entities, &LogEntity{Module: 1, Action: i, CreateTime: time.Now().Unix()})
+	}
+	return entities
+}
+
+func Test_AsyncWriter(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		sink := &memorySink{}
+		w, err := newAsyncWriter(&Config{QueueSize: 100, MaxBatch: 10, FlushInterval: time.Hour}, sink.sink, glog.New())
+		t.AssertNil(err)
+
+		t.AssertNil(w.Write(ctx, newTestEntities(25)))
+		time.Sleep(100 * time.Millisecond)
+		// 按 MaxBatch 写库，剩余的等待定时刷新
+		t.Assert(sink.count(), 20)
+
+		t.AssertNil(w.Close(ctx))
+		t.Assert(sink.count(), 25)
+		t.Assert(w.Write(ctx, newTestEntities(1)), ErrWriterClosed)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		sink := &memorySink{}
+		w := &asyncWriter{queue: make(chan *LogEntity, 2), policy: OverflowDropOldest, logger: glog.New()}
+
+		entities := newTestEntities(3)
+		t.Assert(w.Write(ctx, entities), ErrQueueFull)
+		t.Assert(w.dropped.Load(), 1)
+		t.Assert((<-w.queue).Action, 1)
+		t.Assert((<-w.queue).Action, 2)
+		t.Assert(sink.count(), 0)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		// 队列已满时落盘成功的日志不算丢弃，丢弃新日志时返回 ErrQueueFull
+		spill, err := newSpillStore(t.TempDir(), glog.New())
+		t.AssertNil(err)
+		w := &asyncWriter{queue: make(chan *LogEntity, 1), policy: OverflowSpill, spill: spill, logger: glog.New()}
+		t.AssertNil(w.Write(ctx, newTestEntities(2)))
+		t.Assert(w.spilled.Load(), 1)
+		t.AssertNil(spill.Close())
+
+		w = &asyncWriter{queue: make(chan *LogEntity, 1), policy: OverflowDropNewest, logger: glog.New()}
+		t.Assert(w.Write(ctx, newTestEntities(2)), ErrQueueFull)
+		t.Assert(w.dropped.Load(), 1)
+		t.Assert(len(w.queue), 1)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		sink := &memorySink{fail: true}
+		w, err := newAsyncWriter(&Config{QueueSize: 100, MaxBatch: 4, FlushInterval: 20 * time.Millisecond, SpillDir: t.TempDir()}, sink.sink, glog.New())
+		t.AssertNil(err)
+
+		t.AssertNil(w.Write(ctx, newTestEntities(10)))
+		time.Sleep(100 * time.Millisecond)
+		t.Assert(sink.count(), 0)
+		t.Assert(w.spilled.Load(), 10)
+
+		// 数据库恢复后，下一次写库成功时重放落盘的日志
+		sink.setFail(false)
+		t.AssertNil(w.Write(ctx, newTestEntities(1)))
+		time.Sleep(200 * time.Millisecond)
+		t.AssertNil(w.Close(ctx))
+		t.Assert(sink.count(), 11)
+	})
+	gtest.C(t, func(t *gtest.T) {
+		// 整批被数据库拒绝时逐条重试，只有被拒绝的日志写入死信文件，不落盘重放，也不暂停重放
+		sink := &memorySink{}
+		rejected := func(ctx context.Context, entities []*LogEntity) error {
+			for _, entity := range entities {
+				if entity.Action == 1 {
+					return &mysql.MySQLError{Number: 1406, Message: "Data too long"}
+				}
+			}
+			return sink.sink(ctx, entities)
+		}
+		w, err := newAsyncWriter(&Config{QueueSize: 100, MaxBatch: 4, FlushInterval: time.Hour, SpillDir: t.TempDir()}, rejected, glog.New())
+		t.AssertNil(err)
+
+		t.AssertNil(w.Write(ctx, newTestEntities(4)))
+		t.AssertNil(w.Close(ctx))
+		t.Assert(w.deadLettered.Load(), 1)
+		t.Assert(w.written.Load(), 3)
+		t.Assert(sink.count(), 3)
+		t.Assert(w.spilled.Load(), 0)
+		t.Assert(w.healthy, true)
+	})
+}
@@ -0,0 +1,226 @@
+package LogModule
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/yyboo586/common/MiddleWare"
+)
+
+// 请求结构体 g.Meta 中的审计标签，例如：
+//
+//	g.Meta `path:"/company/{id}" method:"put" auditModule:"1" auditAction:"2" auditTarget:"company" auditTargetId:"id"`
+const (
+	auditTagModule   = "auditModule"   // 业务模块编码，声明后该路由记录审计日志
+	auditTagAction   = "auditAction"   // 业务动作编码
+	auditTagMessage  = "auditMessage"  // 日志概要，默认为 "<METHOD> <路由>"
+	auditTagTarget   = "auditTarget"   // 操作对象类型
+	auditTagTargetID = "auditTargetId" // 操作对象ID所在的请求参数名
+)
+
+const redactedValue = "***"
+
+// AuditRoute 审计路由，用于未在请求结构体 g.Meta 中声明审计标签的路由(如直接注册的 HandlerFunc)
+type AuditRoute struct {
+	Method        string    // HTTP方法，为空匹配任意方法
+	Path          string    // 路由规则，与注册时一致，如 /api/v1/company/{id}
+	Module        LogModule // 业务模块
+	Action        LogAction // 业务动作
+	Message       string    // 日志概要，默认为 "<METHOD> <路由>"
+	TargetType    string    // 操作对象类型
+	TargetIDParam string    // 操作对象ID所在的请求参数名
+}
+
+// AuditConfig 审计中间件配置
+type AuditConfig struct {
+	// 审计路由，优先级低于 g.Meta 中的审计标签
+	Routes []*AuditRoute
+
+	// 是否记录请求参数/响应内容(JSON)，记录前按 RedactFields 脱敏
+	LogRequestBody  bool
+	LogResponseBody bool
+
+	// 需要脱敏的字段名(不区分大小写)，默认 password、token 等常见敏感字段
+	RedactFields []string
+
+	// 请求参数/响应内容的最大记录长度(字节)，超出部分截断，默认4096
+	MaxBodySize int
+
+	// 写入日志的超时时间，默认5秒
+	WriteTimeout time.Duration
+}
+
+var defaultRedactFields = []string{"password", "passwd", "secret", "token", "access_token", "refresh_token", "authorization", "id_card", "bank_card"}
+
+// NewAuditMiddleware 创建审计日志中间件，记录声明了审计标签或在 config.Routes 中配置的路由
+// 操作人取自 MiddleWare.GetContextUser，应注册在 Auth 之后、HandleResponse 之前，以便取得当前用户、处理错误及响应内容
+// 日志在处理函数返回后于独立协程中写入，不阻塞请求
+func NewAuditMiddleware(manager ILogManager, config *AuditConfig) ghttp.HandlerFunc {
+	if config == nil {
+		config = &AuditConfig{}
+	}
+	if len(config.RedactFields) == 0 {
+		config.RedactFields = defaultRedactFields
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 4096
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 5 * time.Second
+	}
+
+	routes := make(map[string]*AuditRoute, len(config.Routes))
+	for _, route := range config.Routes {
+		routes[strings.ToUpper(route.Method)+" "+route.Path] = route
+	}
+
+	redact := make(map[string]bool, len(config.RedactFields))
+	for _, field := range config.RedactFields {
+		redact[strings.ToLower(field)] = true
+	}
+
+	return func(r *ghttp.Request) {
+		r.Middleware.Next()
+
+		route := resolveAuditRoute(r, routes)
+		if route == nil {
+			return
+		}
+		item := buildAuditItem(r, route, config, redact)
+
+		// 请求结束后 ctx 会被取消，写入使用不随请求取消的 ctx(保留链路追踪ID等上下文信息)
+		ctx := context.WithoutCancel(r.Context())
+		go func() {
+			ctx, cancel := context.WithTimeout(ctx, config.WriteTimeout)
+			defer cancel()
+
+			err := manager.BatchWrite(ctx, []*LogItem{item})
+			if err != nil {
+				g.Log().Warningf(ctx, "write audit log failed, route: %s, err: %v", item.Message, err)
+			}
+		}()
+	}
+}
+
+// resolveAuditRoute 按 g.Meta 审计标签、config.Routes 的顺序确定路由的审计信息，未配置时返回 nil
+func resolveAuditRoute(r *ghttp.Request, routes map[string]*AuditRoute) *AuditRoute {
+	if handler := r.GetServeHandler(); handler != nil {
+		if moduleTag := handler.GetMetaTag(auditTagModule); moduleTag != "" {
+			module, err := strconv.Atoi(moduleTag)
+			if err != nil {
+				return nil
+			}
+			action, _ := strconv.Atoi(handler.GetMetaTag(auditTagAction))
+			return &AuditRoute{
+				Module:        LogModule(module),
+				Action:        LogAction(action),
+				Message:       handler.GetMetaTag(auditTagMessage),
+				TargetType:    handler.GetMetaTag(auditTagTarget),
+				TargetIDParam: handler.GetMetaTag(auditTagTargetID),
+			}
+		}
+	}
+
+	if r.Router == nil {
+		return nil
+	}
+	if route, ok := routes[r.Method+" "+r.Router.Uri]; ok {
+		return route
+	}
+	return routes[" "+r.Router.Uri]
+}
+
+func buildAuditItem(r *ghttp.Request, route *AuditRoute, config *AuditConfig, redact map[string]bool) *LogItem {
+	uri := r.URL.Path
+	if r.Router != nil {
+		uri = r.Router.Uri
+	}
+
+	item := &LogItem{
+		Module:     route.Module,
+		Action:     route.Action,
+		Message:    route.Message,
+		IP:         r.GetClientIp(),
+		TargetType: route.TargetType,
+		RequestID:  r.Header.Get("X-Request-Id"),
+		UserAgent:  r.UserAgent(),
+		Result:     LogResultSuccess,
+		CreateTime: time.Now(),
+	}
+	if item.Message == "" {
+		item.Message = r.Method + " " + uri
+	}
+	if item.RequestID == "" {
+		item.RequestID = gctx.CtxId(r.Context())
+	}
+	if route.TargetIDParam != "" {
+		item.TargetID = r.Get(route.TargetIDParam).String()
+	}
+	if user, err := MiddleWare.GetContextUser(r.Context()); err == nil && user != nil {
+		item.OperatorID = user.UserID
+	}
+
+	detail := map[string]interface{}{
+		"method": r.Method,
+		"path":   r.URL.Path,
+		"status": r.Response.Status,
+	}
+	if err := r.GetError(); err != nil {
+		item.Result = LogResultFailure
+		detail["error"] = err.Error()
+	} else if r.Response.Status >= http.StatusBadRequest {
+		item.Result = LogResultFailure
+	}
+	if config.LogRequestBody {
+		detail["request"] = limitBody(redactValue(r.GetRequestMap(), redact), config.MaxBodySize)
+	}
+	if config.LogResponseBody {
+		var response interface{}
+		if json.Unmarshal(r.Response.Buffer(), &response) == nil {
+			detail["response"] = limitBody(redactValue(response, redact), config.MaxBodySize)
+		}
+	}
+	item.Detail = detail
+
+	return item
+}
+
+// redactValue 递归替换敏感字段的值
+func redactValue(value interface{}, redact map[string]bool) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, field := range v {
+			if redact[strings.ToLower(key)] {
+				out[key] = redactedValue
+				continue
+			}
+			out[key] = redactValue(field, redact)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(v))
+		for _, field := range v {
+			out = append(out, redactValue(field, redact))
+		}
+		return out
+	default:
+		return value
+	}
+}
+
+// limitBody 序列化后超过 maxSize 时返回截断的 JSON 字符串
+func limitBody(value interface{}, maxSize int) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil || len(data) <= maxSize {
+		return value
+	}
+	return string(data[:maxSize]) + "...(truncated)"
+}
@@ -0,0 +1,122 @@
+package LogModule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/frame/g"
+	"github.com/gogf/gf/v2/net/ghttp"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/yyboo586/common/MiddleWare"
+)
+
+// memoryLogManager 记录写入日志的 ILogManager，只实现审计中间件用到的方法
+type memoryLogManager struct {
+	ILogManager
+
+	mutex sync.Mutex
+	items []*LogItem
+}
+
+func (m *memoryLogManager) BatchWrite(ctx context.Context, in []*LogItem) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.items = append(m.items, in...)
+	return nil
+}
+
+func (m *memoryLogManager) List(ctx context.Context, filter *LogListFilter) ([]*LogItem, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return append([]*LogItem(nil), m.items...), nil
+}
+
+// waitFor 轮询直到 cond 成立，超过 timeout 时测试失败
+func waitFor(t *gtest.T, timeout time.Duration, cond func() bool) {
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met within %s", timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type updateCompanyReq struct {
+	g.Meta   `path:"/company/{id}" method:"put" auditModule:"1" auditAction:"2" auditTarget:"company" auditTargetId:"id"`
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+type updateCompanyRes struct{}
+
+type companyController struct{}
+
+func (c *companyController) Update(ctx context.Context, req *updateCompanyReq) (res *updateCompanyRes, err error) {
+	return &updateCompanyRes{}, nil
+}
+
+func Test_AuditMiddleware(t *testing.T) {
+	manager := &memoryLogManager{}
+
+	// g.Server 按名称复用实例，名称唯一避免 -count 多次运行时重复注册路由
+	s := g.Server(fmt.Sprintf("%s-%d", t.Name(), time.Now().UnixNano()))
+	s.Group("/", func(group *ghttp.RouterGroup) {
+		group.Middleware(func(r *ghttp.Request) {
+			MiddleWare.ContextInit(r, &MiddleWare.ContextUser{UserID: "u1"})
+			r.Middleware.Next()
+		})
+		group.Middleware(NewAuditMiddleware(manager, &AuditConfig{
+			LogRequestBody: true,
+			Routes:         []*AuditRoute{{Method: "POST", Path: "/login", Module: 2, Action: 1}},
+		}))
+		group.Middleware(MiddleWare.HandleResponse)
+		group.Bind(&companyController{})
+		group.POST("/login", func(r *ghttp.Request) { r.Response.WriteStatus(401) })
+		group.GET("/ping", func(r *ghttp.Request) { r.Response.Write("pong") })
+	})
+	s.SetDumpRouterMap(false)
+	s.SetAccessLogEnabled(false)
+	s.SetPort(0)
+	s.Start()
+	defer s.Shutdown()
+
+	gtest.C(t, func(t *gtest.T) {
+		client := g.Client().SetPrefix(fmt.Sprintf("http://127.0.0.1:%d", s.GetListenedPort()))
+		client.PutContent(context.Background(), "/company/c1", `{"name":"n1","password":"p1"}`)
+		client.PostContent(context.Background(), "/login", `{}`)
+		client.GetContent(context.Background(), "/ping")
+
+		// 审计日志在响应返回后写入
+		var items []*LogItem
+		waitFor(t, 5*time.Second, func() bool {
+			items, _ = manager.List(context.Background(), nil)
+			return len(items) >= 2
+		})
+		t.Assert(len(items), 2)
+
+		var update, login *LogItem
+		for _, item := range items {
+			if item.Module == 1 {
+				update = item
+			} else {
+				login = item
+			}
+		}
+
+		t.Assert(update.Action, 2)
+		t.Assert(update.OperatorID, "u1")
+		t.Assert(update.TargetType, "company")
+		t.Assert(update.TargetID, "c1")
+		t.Assert(update.Result, LogResultSuccess)
+		request := update.Detail.(map[string]interface{})["request"].(map[string]interface{})
+		t.Assert(request["name"], "n1")
+		t.Assert(request["password"], redactedValue)
+
+		t.Assert(login.Module, 2)
+		t.Assert(login.Result, LogResultFailure)
+	})
+}
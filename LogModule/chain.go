@@ -0,0 +1,251 @@
+package LogModule
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const verifyBatchSize = 500
+
+// chainContent 参与哈希计算的日志内容，字段顺序固定
+type chainContent struct {
+	Module     int    `json:"module"`
+	Action     int    `json:"action"`
+	Message    string `json:"message"`
+	Detail     string `json:"detail"`
+	OperatorID string `json:"operator_id"`
+	IP         string `json:"ip"`
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	RequestID  string `json:"request_id"`
+	UserAgent  string `json:"user_agent"`
+	Result     int    `json:"result"`
+	Diff       string `json:"diff"`
+	CreateTime int64  `json:"create_time"`
+}
+
+// computeLogHash 计算日志行哈希：SHA-256(上一行哈希 + "\n" + 日志内容JSON)
+func computeLogHash(prevHash string, entity *LogEntity) string {
+	content, _ := json.Marshal(&chainContent{
+		Module:     entity.Module,
+		Action:     entity.Action,
+		Message:    entity.Message,
+		Detail:     entity.Detail,
+		OperatorID: entity.OperatorID,
+		IP:         entity.IP,
+		TargetType: entity.TargetType,
+		TargetID:   entity.TargetID,
+		RequestID:  entity.RequestID,
+		UserAgent:  entity.UserAgent,
+		Result:     entity.Result,
+		Diff:       entity.Diff,
+		CreateTime: entity.CreateTime,
+	})
+
+	hash := sha256.New()
+	hash.Write([]byte(prevHash))
+	hash.Write([]byte("\n"))
+	hash.Write(content)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// signCheckpoint 计算检查点签名：HMAC-SHA256(key, "<日志ID>:<哈希>:<创建时间>")
+func signCheckpoint(key string, logID int64, hash string, createTime time.Time) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	fmt.Fprintf(mac, "%d:%s:%d", logID, hash, createTime.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Checkpoint 为当前链尾生成签名检查点，链尾未变化时返回最新的检查点，尚无日志时返回 nil
+func (m *LogManager) Checkpoint(ctx context.Context) (*LogCheckpoint, error) {
+	if !m.config.EnableHashChain {
+		return nil, ErrHashChainDisabled
+	}
+
+	tail, err := m.dao.GetChainTail(ctx)
+	if err != nil || tail == nil {
+		return nil, err
+	}
+
+	last, err := m.dao.GetLastCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil && last.LogID == tail.ID {
+		return last, nil
+	}
+
+	checkpoint := &LogCheckpoint{LogID: tail.ID, Hash: tail.Hash, CreateTime: time.Unix(time.Now().Unix(), 0)}
+	checkpoint.Signature = signCheckpoint(m.config.CheckpointKey, checkpoint.LogID, checkpoint.Hash, checkpoint.CreateTime)
+
+	err = m.dao.CreateCheckpoint(ctx, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// Verify 按ID顺序校验 [fromID, toID] 范围内的哈希链，toID 为 0 表示校验到链尾，返回第一处断链
+// 校验内容：每行哈希与内容一致(未被修改)、每行记录的上一行哈希与实际上一行一致(未被删除或插入)、
+// 范围内检查点的签名有效且与对应日志一致(链尾未被删除、整条链未被重算)、
+// 校验到链尾时链头记录的哈希对应的日志存在(最后若干行未被删除)
+func (m *LogManager) Verify(ctx context.Context, fromID int64, toID int64) (result *VerifyResult, err error) {
+	if !m.config.EnableHashChain {
+		return nil, ErrHashChainDisabled
+	}
+	if fromID < 1 {
+		fromID = 1
+	}
+
+	checkpoints, err := m.dao.ListCheckpoints(ctx, fromID, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先读取链头，校验期间新写入的日志排在链头日志之后，不影响判断
+	var head string
+	if toID == 0 {
+		head, err = m.dao.GetChainHead(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// 起点之前的一行作为链的上一环；不存在时链头可能已按保留策略清理，以第一行记录的上一行哈希为准
+	prev, err := m.dao.GetLogBefore(ctx, fromID)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := m.newChainVerifier(prev, checkpoints, head)
+	afterID := fromID - 1
+	for {
+		entities, err := m.dao.ListByIDRange(ctx, afterID, toID, verifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, entity := range entities {
+			afterID = entity.ID
+			if !verifier.check(entity) {
+				return verifier.result, nil
+			}
+		}
+
+		if len(entities) < verifyBatchSize {
+			break
+		}
+	}
+
+	verifier.finish(afterID)
+	return verifier.result, nil
+}
+
+// chainVerifier 按ID顺序逐行校验哈希链
+type chainVerifier struct {
+	m      *LogManager
+	result *VerifyResult
+
+	// expected 下一行应记录的上一行哈希，anchored 为 false 时以第一行记录的为准
+	expected string
+	anchored bool
+
+	checkpoints []*LogCheckpoint
+	pending     map[int64]*LogCheckpoint
+
+	// head 链头记录的链尾哈希，为空时不校验链尾；tailReached 是否校验到链尾日志
+	head        string
+	tailReached bool
+}
+
+// newChainVerifier prev 为校验起点之前的一行，head 为链头哈希(不校验到链尾时为空)
+func (m *LogManager) newChainVerifier(prev *LogEntity, checkpoints []*LogCheckpoint, head string) *chainVerifier {
+	v := &chainVerifier{
+		m:           m,
+		result:      &VerifyResult{Valid: true},
+		checkpoints: checkpoints,
+		pending:     make(map[int64]*LogCheckpoint, len(checkpoints)),
+		head:        head,
+	}
+	for _, checkpoint := range checkpoints {
+		v.pending[checkpoint.LogID] = checkpoint
+	}
+	if prev != nil {
+		v.anchored = true
+		v.expected = prev.Hash
+		v.tailReached = head != "" && prev.Hash == head
+	}
+	return v
+}
+
+func (v *chainVerifier) broken(id int64, reason string) bool {
+	v.result.Valid = false
+	v.result.BrokenID = id
+	v.result.Reason = reason
+	return false
+}
+
+// check 校验一行日志，断链时返回 false
+func (v *chainVerifier) check(entity *LogEntity) bool {
+	if entity.Hash == "" {
+		// 开启哈希链之前写入的日志
+		if v.expected == "" {
+			v.result.Unchained++
+			return true
+		}
+		return v.broken(entity.ID, "log row has no hash after the chain started")
+	}
+	if !v.anchored {
+		v.anchored = true
+		v.expected = entity.PrevHash
+	}
+	if entity.PrevHash != v.expected {
+		return v.broken(entity.ID, "previous hash mismatch, rows before it were deleted, inserted or modified")
+	}
+	if computeLogHash(entity.PrevHash, entity) != entity.Hash {
+		return v.broken(entity.ID, "hash mismatch, log row was modified")
+	}
+	v.expected = entity.Hash
+	v.result.Checked++
+	if v.head != "" && entity.Hash == v.head {
+		v.tailReached = true
+	}
+
+	if checkpoint, ok := v.pending[entity.ID]; ok {
+		delete(v.pending, entity.ID)
+		if !v.m.checkpointValid(checkpoint) {
+			return v.broken(entity.ID, fmt.Sprintf("checkpoint %d signature invalid", checkpoint.ID))
+		}
+		if checkpoint.Hash != entity.Hash {
+			return v.broken(entity.ID, fmt.Sprintf("checkpoint %d hash mismatch, chain was recomputed", checkpoint.ID))
+		}
+		v.result.Checkpoints++
+	}
+	return true
+}
+
+// finish 全部日志校验通过后检查未匹配的检查点与链尾，lastID 为最后一行日志ID
+func (v *chainVerifier) finish(lastID int64) {
+	// 检查点对应的日志不存在：链尾日志被删除
+	for _, checkpoint := range v.checkpoints {
+		if _, ok := v.pending[checkpoint.LogID]; ok {
+			v.broken(checkpoint.LogID, fmt.Sprintf("checkpoint %d log row missing, rows were deleted", checkpoint.ID))
+			return
+		}
+	}
+
+	// 链头记录的哈希对应的日志不存在：最后若干行被删除
+	if v.head != "" && !v.tailReached {
+		v.broken(lastID, "chain tail log row missing, rows after it were deleted")
+	}
+}
+
+func (m *LogManager) checkpointValid(checkpoint *LogCheckpoint) bool {
+	expected := signCheckpoint(m.config.CheckpointKey, checkpoint.LogID, checkpoint.Hash, checkpoint.CreateTime)
+	return hmac.Equal([]byte(expected), []byte(checkpoint.Signature))
+}
@@ -0,0 +1,101 @@
+package LogModule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_LogHash(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		entity := &LogEntity{Module: 1, Action: 2, Message: "update", Detail: `{"a":1}`, OperatorID: "u1", CreateTime: 1760000000}
+		first := computeLogHash("", entity)
+		t.Assert(len(first), 64)
+		t.Assert(computeLogHash("", entity), first)
+
+		// 上一行哈希或内容变化都会改变哈希
+		t.AssertNE(computeLogHash(first, entity), first)
+		modified := *entity
+		modified.Message = "delete"
+		t.AssertNE(computeLogHash("", &modified), first)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		m := &LogManager{config: &Config{CheckpointKey: "k1"}}
+		checkpoint := &LogCheckpoint{LogID: 10, Hash: "h", CreateTime: time.Unix(1760000000, 0)}
+		checkpoint.Signature = signCheckpoint("k1", checkpoint.LogID, checkpoint.Hash, checkpoint.CreateTime)
+		t.Assert(m.checkpointValid(checkpoint), true)
+
+		checkpoint.LogID = 9
+		t.Assert(m.checkpointValid(checkpoint), false)
+	})
+}
+
+// newTestChain 构造ID从1开始、哈希链完整的日志序列
+func newTestChain(n int) []*LogEntity {
+	entities := make([]*LogEntity, 0, n)
+	var prevHash string
+	for i := 1; i <= n; i++ {
+		entity := &LogEntity{ID: int64(i), Module: 1, Action: i, Message: fmt.Sprintf("log %d", i), CreateTime: 1760000000}
+		entity.PrevHash = prevHash
+		entity.Hash = computeLogHash(prevHash, entity)
+		prevHash = entity.Hash
+		entities = append(entities, entity)
+	}
+	return entities
+}
+
+// verifyEntities 按顺序校验内存中的日志序列
+func verifyEntities(v *chainVerifier, entities []*LogEntity) *VerifyResult {
+	var lastID int64
+	for _, entity := range entities {
+		lastID = entity.ID
+		if !v.check(entity) {
+			return v.result
+		}
+	}
+	v.finish(lastID)
+	return v.result
+}
+
+func Test_VerifyChain(t *testing.T) {
+	m := &LogManager{config: &Config{CheckpointKey: "k1"}}
+	chain := newTestChain(5)
+	head := chain[4].Hash
+
+	checkpoint := &LogCheckpoint{ID: 7, LogID: 5, Hash: head, CreateTime: time.Unix(1760000000, 0)}
+	checkpoint.Signature = signCheckpoint("k1", checkpoint.LogID, checkpoint.Hash, checkpoint.CreateTime)
+
+	modified := *chain[2]
+	modified.Message = "tampered"
+
+	cases := []struct {
+		name        string
+		entities    []*LogEntity
+		prev        *LogEntity
+		checkpoints []*LogCheckpoint
+		head        string
+		valid       bool
+		checked     int
+		brokenID    int64
+	}{
+		{name: "intact", entities: chain, checkpoints: []*LogCheckpoint{checkpoint}, head: head, valid: true, checked: 5},
+		{name: "modified row", entities: []*LogEntity{chain[0], chain[1], &modified, chain[3], chain[4]}, head: head, brokenID: 3},
+		{name: "deleted row", entities: []*LogEntity{chain[0], chain[1], chain[3], chain[4]}, head: head, brokenID: 4},
+		{name: "deleted tail", entities: chain[:3], head: head, brokenID: 3},
+		{name: "deleted checkpoint row", entities: chain[:4], checkpoints: []*LogCheckpoint{checkpoint}, brokenID: 5},
+		{name: "range before tail", entities: chain[1:3], prev: chain[0], valid: true, checked: 2},
+		{name: "range from purged head", entities: chain[2:], head: head, valid: true, checked: 3},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			result := verifyEntities(m.newChainVerifier(c.prev, c.checkpoints, c.head), c.entities)
+			if result.Valid != c.valid || result.BrokenID != c.brokenID || (c.valid && result.Checked != c.checked) {
+				t.Fatalf("%s: unexpected result %+v", c.name, result)
+			}
+		}
+	})
+}
@@ -1,15 +1,36 @@
 package LogModule
 
+import (
+	"time"
+)
+
 // Config LogModule 配置信息
 // 参考 FileModule 的配置结构，主要用于数据库初始化与日志输出控制
 //
 // 字段说明：
-//   DSN         数据库连接串，支持 mysql/postgres 等 gdb 支持的驱动
-//   Group       gdb 分组名称，用于多库场景隔离，默认 "log"
-//   TableName   日志表名称，默认 "t_log"
-//   EnableDebug 是否开启 gf 数据库调试与日志器调试
-//   MaxBatch    BatchWrite 单次写库的最大行数，超过后自动拆分
-//   LogLevel    组件内部使用的日志级别（debug/info/warn/error）
+//   DSN            数据库连接串，支持 mysql/postgres 等 gdb 支持的驱动
+//   Group          gdb 分组名称，用于多库场景隔离，默认 "default"
+//   TableName      日志表名称，默认 "t_log"
+//   EnableDebug    是否开启 gf 数据库调试与日志器调试
+//   MaxBatch       BatchWrite 单次写库的最大行数，超过后自动拆分；异步写入时队列积累到该数量立即写库
+//   LogLevel       组件内部使用的日志级别（debug/info/warn/error）
+//   AsyncWrite     是否异步写入：BatchWrite 只入队，由后台协程批量写库，Close 时写完队列中的日志
+//   QueueSize      异步写入队列容量，默认 10000
+//   FlushInterval  异步写入的最长写库间隔，默认 1 秒
+//   OverflowPolicy 异步写入队列已满时的处理策略，默认阻塞等待
+//   SpillDir       溢出目录，设置后写库失败(及 OverflowSpill 策略下队列已满)的日志落盘，数据库恢复后重新写入
+//                  被数据库拒绝(数据不合法，重试不会成功)的日志写入 deadletter-*.ndjson 死信文件，不再重放
+//   EnableHashChain    是否开启防篡改哈希链：每行日志记录自身内容与上一行哈希计算的哈希值
+//   CheckpointKey      检查点签名密钥(HMAC-SHA256)，开启哈希链时必填
+//   CheckpointInterval 哈希链检查点生成间隔，默认1小时，小于0表示不自动生成
+//   Retention          按模块配置的日志保留期，如登录日志180天、财务日志3年；值小于等于0的模块永久保留
+//                      哈希链只能从链头整体截断，不能与 EnableHashChain 同时开启
+//   DefaultRetention   未在 Retention 中配置的模块的保留期，0 表示永久保留；开启哈希链时按该保留期清理链头
+//   PurgeInterval      过期日志清理间隔，默认1小时，小于0表示不自动清理；未配置任何保留期时不清理
+//   ArchiveDir         归档目录，设置后过期日志先归档为 gzip 压缩的 NDJSON 文件再删除
+//   Partition          是否按月分表(如 t_log_202610)，List/Count 自动查询时间范围覆盖的分表；
+//                      各分表ID独立自增，不能与 EnableHashChain 同时开启
+//   FileManager        文件上传器(如 FileModule.IFileManager)，ExportAsync 通过它上传导出文件
 //
 // 业务可以基于该配置扩展，如配置分库分表策略、外部日志服务地址等
 
@@ -19,14 +40,47 @@ type Config struct {
 	TableName   string
 	EnableDebug bool
 	MaxBatch    int
+
+	AsyncWrite     bool
+	QueueSize      int
+	FlushInterval  time.Duration
+	OverflowPolicy OverflowPolicy
+	SpillDir       string
+
+	EnableHashChain    bool
+	CheckpointKey      string
+	CheckpointInterval time.Duration
+
+	Retention        map[LogModule]time.Duration
+	DefaultRetention time.Duration
+	PurgeInterval    time.Duration
+	ArchiveDir       string
+	Partition        bool
+
+	FileManager FileUploader
 }
 
+// OverflowPolicy 异步写入队列已满时的处理策略
+type OverflowPolicy int
+
+const (
+	OverflowBlock      OverflowPolicy = iota // 阻塞等待队列空闲，直到 ctx 结束
+	OverflowDropNewest                       // 丢弃新写入的日志
+	OverflowDropOldest                       // 丢弃队列中最早的日志
+	OverflowSpill                            // 写入溢出目录，未配置 SpillDir 时同 OverflowDropNewest
+)
+
 // DefaultConfig 返回默认配置
 func DefaultConfig() *Config {
 	return &Config{
-		Group:       "default",
-		TableName:   "t_log",
-		EnableDebug: true,
-		MaxBatch:    200,
+		Group:         "default",
+		TableName:     "t_log",
+		EnableDebug:   true,
+		MaxBatch:      200,
+		QueueSize:     10000,
+		FlushInterval: time.Second,
+
+		CheckpointInterval: time.Hour,
+		PurgeInterval:      time.Hour,
 	}
 }
@@ -3,6 +3,8 @@ package LogModule
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/gogf/gf/v2/database/gdb"
 	"github.com/gogf/gf/v2/errors/gerror"
@@ -17,6 +19,16 @@ type LogManagerDAO struct {
 	db        gdb.DB
 	maxBatch  int
 	ctx       context.Context
+
+	// 防篡改哈希链
+	hashChain           bool
+	chainTableName      string
+	checkpointTableName string
+
+	// 按月分表，partitions 记录已确认存在的分表
+	partition      bool
+	partitions     map[string]struct{}
+	partitionMutex sync.Mutex
 }
 
 func newLogManagerDAO(ctx context.Context, config *Config) (*LogManagerDAO, error) {
@@ -51,28 +63,157 @@ func newLogManagerDAO(ctx context.Context, config *Config) (*LogManagerDAO, erro
 		db:        db,
 		maxBatch:  config.MaxBatch,
 		ctx:       ctx,
+
+		hashChain:           config.EnableHashChain,
+		chainTableName:      config.TableName + "_chain",
+		checkpointTableName: config.TableName + "_checkpoint",
+
+		partition:  config.Partition,
+		partitions: make(map[string]struct{}),
 	}, nil
 }
 
 func (d *LogManagerDAO) EnsureTable() error {
+	err := d.ensureLogTable(d.tableName)
+	if err != nil {
+		return err
+	}
+
+	if d.hashChain {
+		return d.ensureChainTables()
+	}
+	return nil
+}
+
+// ensureLogTable 创建日志表并补齐旧表缺失的字段与索引，按月分表时用于创建分表
+func (d *LogManagerDAO) ensureLogTable(table string) error {
 	createTableSQL := fmt.Sprintf(`
 CREATE TABLE IF NOT EXISTS %s (
     id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
-    module TINYINT(1) NOT NULL DEFAULT 0 COMMENT '业务模块',
-    action TINYINT(1) NOT NULL DEFAULT 0 COMMENT '业务动作',
+    module INT(11) NOT NULL DEFAULT 0 COMMENT '业务模块',
+    action INT(11) NOT NULL DEFAULT 0 COMMENT '业务动作',
     message VARCHAR(255) NOT NULL DEFAULT '' COMMENT '日志概要',
     detail TEXT COMMENT '日志详情(JSON)',
     operator_id VARCHAR(40) NOT NULL DEFAULT '' COMMENT '操作人ID',
     ip VARCHAR(64) NOT NULL DEFAULT '' COMMENT '操作人IP',
+    target_type VARCHAR(64) NOT NULL DEFAULT '' COMMENT '操作对象类型',
+    target_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT '操作对象ID',
+    request_id VARCHAR(64) NOT NULL DEFAULT '' COMMENT '请求ID/链路追踪ID',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '' COMMENT '客户端User-Agent',
+    result TINYINT(1) NOT NULL DEFAULT 0 COMMENT '操作结果(0:未知,1:成功,2:失败)',
+    diff TEXT COMMENT '变更前后差异(JSON)',
+    hash CHAR(64) NOT NULL DEFAULT '' COMMENT '行哈希(防篡改哈希链)',
+    prev_hash CHAR(64) NOT NULL DEFAULT '' COMMENT '上一行哈希(防篡改哈希链)',
     create_time BIGINT(20) NOT NULL COMMENT '创建时间(秒)',
     PRIMARY KEY (id),
-    KEY idx_module_action_time (module, action, operator_id, create_time)
-) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='通用日志表';`, d.tableName)
+    KEY idx_module_action_time (module, action, operator_id, create_time),
+    KEY idx_create_time_id (create_time, id),
+    KEY idx_target (target_type, target_id, create_time),
+    KEY idx_request_id (request_id),
+    KEY idx_hash (hash)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='通用日志表';`, table)
 
 	_, err := d.db.Exec(d.ctx, createTableSQL)
 	if err != nil {
 		return fmt.Errorf("failed to create log table: %w", err)
 	}
+
+	// 兼容已存在的旧表
+	columns := []tableColumn{
+		{name: "target_type", definition: "VARCHAR(64) NOT NULL DEFAULT '' COMMENT '操作对象类型'"},
+		{name: "target_id", definition: "VARCHAR(64) NOT NULL DEFAULT '' COMMENT '操作对象ID'", index: "KEY idx_target (target_type, target_id, create_time)"},
+		{name: "request_id", definition: "VARCHAR(64) NOT NULL DEFAULT '' COMMENT '请求ID/链路追踪ID'", index: "KEY idx_request_id (request_id)"},
+		{name: "user_agent", definition: "VARCHAR(255) NOT NULL DEFAULT '' COMMENT '客户端User-Agent'"},
+		{name: "result", definition: "TINYINT(1) NOT NULL DEFAULT 0 COMMENT '操作结果(0:未知,1:成功,2:失败)'"},
+		{name: "diff", definition: "TEXT COMMENT '变更前后差异(JSON)'"},
+		{name: "hash", definition: "CHAR(64) NOT NULL DEFAULT '' COMMENT '行哈希(防篡改哈希链)'", index: "KEY idx_hash (hash)"},
+		{name: "prev_hash", definition: "CHAR(64) NOT NULL DEFAULT '' COMMENT '上一行哈希(防篡改哈希链)'"},
+	}
+	err = d.ensureColumns(table, columns)
+	if err != nil {
+		return err
+	}
+
+	// 旧表的 module/action 为 TINYINT(1)，最多只能存储127个编码
+	err = d.ensureColumnType(table, "module", "int", "INT(11) NOT NULL DEFAULT 0 COMMENT '业务模块'")
+	if err != nil {
+		return err
+	}
+	err = d.ensureColumnType(table, "action", "int", "INT(11) NOT NULL DEFAULT 0 COMMENT '业务动作'")
+	if err != nil {
+		return err
+	}
+
+	return d.ensureIndex(table, "idx_create_time_id", "(create_time, id)")
+}
+
+// tableColumn 需要补齐的表字段，index 不为空时随字段一起创建索引
+type tableColumn struct {
+	name       string
+	definition string
+	index      string
+}
+
+// ensureColumns 为已存在的表补齐缺失字段
+func (d *LogManagerDAO) ensureColumns(table string, columns []tableColumn) error {
+	for _, column := range columns {
+		count, err := d.db.GetCount(d.ctx,
+			"SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+			table, column.name)
+		if err != nil {
+			return fmt.Errorf("failed to check column %s.%s: %w", table, column.name, err)
+		}
+		if count > 0 {
+			continue
+		}
+
+		alterSQL := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column.name, column.definition)
+		if column.index != "" {
+			alterSQL += ", ADD " + column.index
+		}
+		_, err = d.db.Exec(d.ctx, alterSQL)
+		if err != nil {
+			return fmt.Errorf("failed to add column %s.%s: %w", table, column.name, err)
+		}
+	}
+	return nil
+}
+
+// ensureColumnType 字段类型与 dataType 不一致时修改为 definition
+func (d *LogManagerDAO) ensureColumnType(table string, column string, dataType string, definition string) error {
+	value, err := d.db.GetValue(d.ctx,
+		"SELECT DATA_TYPE FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?",
+		table, column)
+	if err != nil {
+		return fmt.Errorf("failed to check column %s.%s: %w", table, column, err)
+	}
+	if value.IsEmpty() || strings.EqualFold(value.String(), dataType) {
+		return nil
+	}
+
+	_, err = d.db.Exec(d.ctx, fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s %s", table, column, definition))
+	if err != nil {
+		return fmt.Errorf("failed to modify column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// ensureIndex 索引不存在时创建
+func (d *LogManagerDAO) ensureIndex(table string, name string, columns string) error {
+	count, err := d.db.GetCount(d.ctx,
+		"SELECT COUNT(*) FROM information_schema.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?",
+		table, name)
+	if err != nil {
+		return fmt.Errorf("failed to check index %s.%s: %w", table, name, err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	_, err = d.db.Exec(d.ctx, fmt.Sprintf("ALTER TABLE %s ADD KEY %s %s", table, name, columns))
+	if err != nil {
+		return fmt.Errorf("failed to add index %s.%s: %w", table, name, err)
+	}
 	return nil
 }
 
@@ -92,29 +233,26 @@ func (d *LogManagerDAO) BatchCreate(ctx context.Context, entities []*LogEntity)
 			end = len(entities)
 		}
 
-		chunk := entities[start:end]
-		data := make([]g.Map, 0, len(chunk))
-		for _, entity := range chunk {
-			if entity == nil {
-				continue
+		chunk := make([]*LogEntity, 0, end-start)
+		for _, entity := range entities[start:end] {
+			if entity != nil {
+				chunk = append(chunk, entity)
 			}
-
-			data = append(data, g.Map{
-				"module":      entity.Module,
-				"action":      entity.Action,
-				"message":     entity.Message,
-				"detail":      entity.Detail,
-				"operator_id": entity.OperatorID,
-				"ip":          entity.IP,
-				"create_time": entity.CreateTime,
-			})
 		}
 
-		if len(data) == 0 {
+		if len(chunk) == 0 {
 			continue
 		}
 
-		if _, err := d.db.Model(d.tableName).Ctx(ctx).Data(data).Insert(); err != nil {
+		var err error
+		if d.hashChain {
+			err = d.createChained(ctx, chunk)
+		} else if d.partition {
+			err = d.createPartitioned(ctx, chunk)
+		} else {
+			_, err = d.db.Model(d.tableName).Ctx(ctx).Data(entityData(chunk)).Insert()
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -122,6 +260,33 @@ func (d *LogManagerDAO) BatchCreate(ctx context.Context, entities []*LogEntity)
 	return nil
 }
 
+func entityData(entities []*LogEntity) []g.Map {
+	data := make([]g.Map, 0, len(entities))
+	for _, entity := range entities {
+		data = append(data, g.Map{
+			"module":      entity.Module,
+			"action":      entity.Action,
+			"message":     entity.Message,
+			"detail":      entity.Detail,
+			"operator_id": entity.OperatorID,
+			"ip":          entity.IP,
+			"target_type": entity.TargetType,
+			"target_id":   entity.TargetID,
+			"request_id":  entity.RequestID,
+			"user_agent":  entity.UserAgent,
+			"result":      entity.Result,
+			"diff":        entity.Diff,
+			"hash":        entity.Hash,
+			"prev_hash":   entity.PrevHash,
+			"create_time": entity.CreateTime,
+		})
+	}
+	return data
+}
+
+// List 分页查询日志，按 (create_time, id) 倒序
+// 设置了 Cursor 时使用游标分页，只返回游标之后的日志，避免深分页时扫描大量偏移行
+// 按月分表时依次查询时间范围覆盖的分表，分表按月份倒序排列，拼接后整体仍按 (create_time, id) 倒序
 func (d *LogManagerDAO) List(ctx context.Context, filter *LogListFilter) ([]*LogEntity, error) {
 	if filter.Page <= 0 {
 		filter.Page = 1
@@ -130,19 +295,85 @@ func (d *LogManagerDAO) List(ctx context.Context, filter *LogListFilter) ([]*Log
 		filter.Size = 10
 	}
 
-	model := d.buildFilterModel(ctx, filter)
-	model = model.Page(filter.Page, filter.Size).OrderDesc("create_time")
+	var (
+		createTime, cursorID int64
+		err                  error
+	)
+	if filter.Cursor != "" {
+		createTime, cursorID, err = parseCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	var entities []*LogEntity
-	if err := model.Scan(&entities); err != nil {
+	tables, err := d.queryTables(ctx, filter)
+	if err != nil {
 		return nil, err
 	}
 
+	offset := 0
+	if filter.Cursor == "" {
+		offset = (filter.Page - 1) * filter.Size
+	}
+	remaining := filter.Size
+
+	var entities []*LogEntity
+	for _, table := range tables {
+		if remaining <= 0 {
+			break
+		}
+
+		model := d.buildFilterModel(ctx, table, filter)
+		if filter.Cursor != "" {
+			model = model.Where("(create_time < ? OR (create_time = ? AND id < ?))", createTime, createTime, cursorID)
+		}
+
+		// 偏移量跨过整张分表时直接跳过
+		if offset > 0 && len(tables) > 1 {
+			count, err := model.Clone().Count()
+			if err != nil {
+				return nil, err
+			}
+			if count <= offset {
+				offset -= count
+				continue
+			}
+		}
+
+		var batch []*LogEntity
+		err = model.OrderDesc("create_time").OrderDesc("id").Limit(offset, remaining).Scan(&batch)
+		if err != nil {
+			return nil, err
+		}
+
+		offset = 0
+		remaining -= len(batch)
+		entities = append(entities, batch...)
+	}
+
 	return entities, nil
 }
 
-func (d *LogManagerDAO) buildFilterModel(ctx context.Context, filter *LogListFilter) *gdb.Model {
-	model := d.db.Model(d.tableName).Ctx(ctx)
+// Count 统计满足条件的日志数量
+func (d *LogManagerDAO) Count(ctx context.Context, filter *LogListFilter) (int, error) {
+	tables, err := d.queryTables(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, table := range tables {
+		count, err := d.buildFilterModel(ctx, table, filter).Count()
+		if err != nil {
+			return 0, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+func (d *LogManagerDAO) buildFilterModel(ctx context.Context, table string, filter *LogListFilter) *gdb.Model {
+	model := d.db.Model(table).Ctx(ctx)
 	if filter == nil {
 		return model
 	}
@@ -156,6 +387,18 @@ func (d *LogManagerDAO) buildFilterModel(ctx context.Context, filter *LogListFil
 	if filter.OperatorID != "" {
 		model = model.Where("operator_id = ?", filter.OperatorID)
 	}
+	if filter.TargetType != "" {
+		model = model.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		model = model.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		model = model.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.Result != 0 {
+		model = model.Where("result = ?", int(filter.Result))
+	}
 	if !filter.StartTime.IsZero() {
 		model = model.Where("create_time >= ?", filter.StartTime.Unix())
 	}
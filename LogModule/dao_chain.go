@@ -0,0 +1,196 @@
+package LogModule
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/gogf/gf/v2/database/gdb"
+	"github.com/gogf/gf/v2/frame/g"
+)
+
+// ensureChainTables 创建哈希链头表与检查点表
+// 链头表只有一行，记录链尾哈希；写入日志时锁定该行，保证多实例并发写入时日志ID顺序与链顺序一致
+func (d *LogManagerDAO) ensureChainTables() error {
+	createChainSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id TINYINT(1) NOT NULL COMMENT '固定为1',
+    last_hash CHAR(64) NOT NULL DEFAULT '' COMMENT '链尾日志哈希',
+    update_time BIGINT(20) NOT NULL COMMENT '更新时间',
+    PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='日志哈希链头表';`, d.chainTableName)
+
+	_, err := d.db.Exec(d.ctx, createChainSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create log chain table: %w", err)
+	}
+
+	createCheckpointSQL := fmt.Sprintf(`
+CREATE TABLE IF NOT EXISTS %s (
+    id BIGINT(20) NOT NULL AUTO_INCREMENT COMMENT '自增ID',
+    log_id BIGINT(20) NOT NULL COMMENT '链尾日志ID',
+    hash CHAR(64) NOT NULL COMMENT '链尾日志哈希',
+    signature CHAR(64) NOT NULL COMMENT '签名(HMAC-SHA256)',
+    create_time BIGINT(20) NOT NULL COMMENT '创建时间(秒)',
+    PRIMARY KEY (id),
+    KEY idx_log_id (log_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='日志哈希链检查点表';`, d.checkpointTableName)
+
+	_, err = d.db.Exec(d.ctx, createCheckpointSQL)
+	if err != nil {
+		return fmt.Errorf("failed to create log checkpoint table: %w", err)
+	}
+
+	// 首次开启时链头取已有日志中最后一条计算过哈希的日志
+	lastHash, err := d.db.Model(d.tableName).Ctx(d.ctx).Fields("hash").Where("hash != ''").OrderDesc("id").Limit(1).Value()
+	if err != nil {
+		return fmt.Errorf("failed to query log chain tail: %w", err)
+	}
+
+	_, err = d.db.Model(d.chainTableName).Ctx(d.ctx).Data(g.Map{
+		"id":          1,
+		"last_hash":   lastHash.String(),
+		"update_time": time.Now().Unix(),
+	}).InsertIgnore()
+	if err != nil {
+		return fmt.Errorf("failed to init log chain: %w", err)
+	}
+	return nil
+}
+
+// createChained 在事务中锁定链头，依次计算日志哈希后写入，并更新链头
+func (d *LogManagerDAO) createChained(ctx context.Context, entities []*LogEntity) error {
+	return d.db.Transaction(ctx, func(ctx context.Context, tx gdb.TX) error {
+		head, err := tx.Model(d.chainTableName).Ctx(ctx).Fields("last_hash").Where("id = 1").LockUpdate().Value()
+		if err != nil {
+			return err
+		}
+
+		prevHash := head.String()
+		for _, entity := range entities {
+			entity.PrevHash = prevHash
+			entity.Hash = computeLogHash(prevHash, entity)
+			prevHash = entity.Hash
+		}
+
+		// 单条 INSERT 语句内的自增ID按行顺序递增
+		_, err = tx.Model(d.tableName).Ctx(ctx).Data(entityData(entities)).Insert()
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Model(d.chainTableName).Ctx(ctx).
+			Data(g.Map{"last_hash": prevHash, "update_time": time.Now().Unix()}).
+			Where("id = 1").
+			Update()
+		return err
+	})
+}
+
+// GetChainHead 获取链头记录的链尾日志哈希，尚无日志时为空
+func (d *LogManagerDAO) GetChainHead(ctx context.Context) (string, error) {
+	lastHash, err := d.db.Model(d.chainTableName).Ctx(ctx).Fields("last_hash").Where("id = 1").Value()
+	if err != nil {
+		return "", err
+	}
+	return lastHash.String(), nil
+}
+
+// GetChainTail 获取链尾日志(哈希与链头一致的日志)
+func (d *LogManagerDAO) GetChainTail(ctx context.Context) (*LogEntity, error) {
+	lastHash, err := d.GetChainHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if lastHash == "" {
+		return nil, nil
+	}
+
+	var entity *LogEntity
+	err = d.db.Model(d.tableName).Ctx(ctx).Where("hash = ?", lastHash).OrderDesc("id").Limit(1).Scan(&entity)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// GetLogBefore 获取ID小于 id 的最后一条日志
+func (d *LogManagerDAO) GetLogBefore(ctx context.Context, id int64) (*LogEntity, error) {
+	var entity *LogEntity
+	err := d.db.Model(d.tableName).Ctx(ctx).Where("id < ?", id).OrderDesc("id").Limit(1).Scan(&entity)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return entity, nil
+}
+
+// ListByIDRange 按ID升序查询 (afterID, toID] 范围内的日志，toID 为 0 表示不限
+func (d *LogManagerDAO) ListByIDRange(ctx context.Context, afterID int64, toID int64, limit int) ([]*LogEntity, error) {
+	model := d.db.Model(d.tableName).Ctx(ctx).Where("id > ?", afterID)
+	if toID > 0 {
+		model = model.Where("id <= ?", toID)
+	}
+
+	var entities []*LogEntity
+	err := model.OrderAsc("id").Limit(limit).Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// GetLastCheckpoint 获取最新的检查点
+func (d *LogManagerDAO) GetLastCheckpoint(ctx context.Context) (*LogCheckpoint, error) {
+	var entity *LogCheckpointEntity
+	err := d.db.Model(d.checkpointTableName).Ctx(ctx).OrderDesc("id").Limit(1).Scan(&entity)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if entity == nil {
+		return nil, nil
+	}
+	return ConvertLogCheckpoint(entity), nil
+}
+
+// CreateCheckpoint 写入检查点
+func (d *LogManagerDAO) CreateCheckpoint(ctx context.Context, checkpoint *LogCheckpoint) error {
+	id, err := d.db.Model(d.checkpointTableName).Ctx(ctx).Data(g.Map{
+		"log_id":      checkpoint.LogID,
+		"hash":        checkpoint.Hash,
+		"signature":   checkpoint.Signature,
+		"create_time": checkpoint.CreateTime.Unix(),
+	}).InsertAndGetId()
+	if err != nil {
+		return err
+	}
+
+	checkpoint.ID = id
+	return nil
+}
+
+// ListCheckpoints 查询日志ID在 [fromID, toID] 范围内的检查点，toID 为 0 表示不限
+func (d *LogManagerDAO) ListCheckpoints(ctx context.Context, fromID int64, toID int64) ([]*LogCheckpoint, error) {
+	model := d.db.Model(d.checkpointTableName).Ctx(ctx).Where("log_id >= ?", fromID)
+	if toID > 0 {
+		model = model.Where("log_id <= ?", toID)
+	}
+
+	var entities []*LogCheckpointEntity
+	err := model.OrderAsc("log_id").Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]*LogCheckpoint, 0, len(entities))
+	for _, entity := range entities {
+		out = append(out, ConvertLogCheckpoint(entity))
+	}
+	return out, nil
+}
+
+// DeleteCheckpointsBefore 删除指向已清理日志的检查点
+func (d *LogManagerDAO) DeleteCheckpointsBefore(ctx context.Context, logID int64) error {
+	_, err := d.db.Model(d.checkpointTableName).Ctx(ctx).Where("log_id < ?", logID).Delete()
+	return err
+}
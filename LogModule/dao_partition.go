@@ -0,0 +1,203 @@
+package LogModule
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// partitionLayout 分表名中的月份格式，如 t_log_202610
+const partitionLayout = "200601"
+
+// partitionTable 返回创建时间所在月份的分表名
+func (d *LogManagerDAO) partitionTable(createTime int64) string {
+	return d.tableName + "_" + time.Unix(createTime, 0).Format(partitionLayout)
+}
+
+// partitionMonth 解析分表对应月份的第一天，不是分表时返回 false
+func (d *LogManagerDAO) partitionMonth(table string) (time.Time, bool) {
+	suffix, ok := strings.CutPrefix(table, d.tableName+"_")
+	if !ok || len(suffix) != len(partitionLayout) {
+		return time.Time{}, false
+	}
+
+	month, err := time.ParseInLocation(partitionLayout, suffix, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+// ensurePartition 分表不存在时创建
+func (d *LogManagerDAO) ensurePartition(table string) error {
+	d.partitionMutex.Lock()
+	defer d.partitionMutex.Unlock()
+
+	if _, ok := d.partitions[table]; ok {
+		return nil
+	}
+
+	err := d.ensureLogTable(table)
+	if err != nil {
+		return err
+	}
+	d.partitions[table] = struct{}{}
+	return nil
+}
+
+// createPartitioned 按创建时间所在月份写入对应分表
+func (d *LogManagerDAO) createPartitioned(ctx context.Context, entities []*LogEntity) error {
+	var tables []string
+	groups := make(map[string][]*LogEntity)
+	for _, entity := range entities {
+		table := d.partitionTable(entity.CreateTime)
+		if _, ok := groups[table]; !ok {
+			tables = append(tables, table)
+		}
+		groups[table] = append(groups[table], entity)
+	}
+
+	for _, table := range tables {
+		err := d.ensurePartition(table)
+		if err != nil {
+			return err
+		}
+
+		_, err = d.db.Model(table).Ctx(ctx).Data(entityData(groups[table])).Insert()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListPartitions 查询已存在的分表，按月份倒序
+func (d *LogManagerDAO) ListPartitions(ctx context.Context) ([]string, error) {
+	pattern := strings.ReplaceAll(d.tableName, "_", `\_`) + `\_%`
+	names, err := d.db.GetArray(ctx,
+		"SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME LIKE ? ORDER BY TABLE_NAME DESC",
+		pattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list log partitions: %w", err)
+	}
+
+	partitions := make([]string, 0, len(names))
+	for _, name := range names {
+		if _, ok := d.partitionMonth(name.String()); ok {
+			partitions = append(partitions, name.String())
+		}
+	}
+	return partitions, nil
+}
+
+// queryTables 返回查询需要覆盖的表，按时间倒序
+// 未分表时只有主表；分表时为时间范围覆盖的分表，最后是开启分表前写入主表的历史日志
+func (d *LogManagerDAO) queryTables(ctx context.Context, filter *LogListFilter) ([]string, error) {
+	if !d.partition {
+		return []string{d.tableName}, nil
+	}
+
+	partitions, err := d.ListPartitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var from, to string
+	if filter != nil && !filter.StartTime.IsZero() {
+		from = d.partitionTable(filter.StartTime.Unix())
+	}
+	if filter != nil && !filter.EndTime.IsZero() {
+		to = d.partitionTable(filter.EndTime.Unix())
+	}
+
+	tables := make([]string, 0, len(partitions)+1)
+	for _, table := range partitions {
+		if (from != "" && table < from) || (to != "" && table > to) {
+			continue
+		}
+		tables = append(tables, table)
+	}
+	return append(tables, d.tableName), nil
+}
+
+// DropTable 删除整张分表
+func (d *LogManagerDAO) DropTable(ctx context.Context, table string) error {
+	_, err := d.db.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", table))
+	if err != nil {
+		return fmt.Errorf("failed to drop log table %s: %w", table, err)
+	}
+
+	d.partitionMutex.Lock()
+	delete(d.partitions, table)
+	d.partitionMutex.Unlock()
+	return nil
+}
+
+// purgeRule 过期日志清理条件
+type purgeRule struct {
+	modules []int // 清理的模块，为空表示全部模块
+	exclude bool  // 为 true 时清理 modules 以外的模块
+	before  int64 // 清理 create_time 早于该时间的日志
+
+	// beforeID 大于0时只按ID清理 id < beforeID 的日志，哈希链只能整体截断链头
+	beforeID int64
+}
+
+// ListPurgeBatch 按ID顺序查询一批待清理的日志
+func (d *LogManagerDAO) ListPurgeBatch(ctx context.Context, table string, rule *purgeRule, limit int) ([]*LogEntity, error) {
+	model := d.db.Model(table).Ctx(ctx)
+	if rule.beforeID > 0 {
+		model = model.Where("id < ?", rule.beforeID)
+	} else {
+		model = model.Where("create_time < ?", rule.before)
+		if len(rule.modules) > 0 {
+			if rule.exclude {
+				model = model.WhereNotIn("module", rule.modules)
+			} else {
+				model = model.WhereIn("module", rule.modules)
+			}
+		}
+	}
+
+	var entities []*LogEntity
+	err := model.OrderAsc("id").Limit(limit).Scan(&entities)
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+// DeleteByIDs 按ID删除日志
+func (d *LogManagerDAO) DeleteByIDs(ctx context.Context, table string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := d.db.Model(table).Ctx(ctx).WhereIn("id", ids).Delete()
+	return err
+}
+
+// GetChainPurgeBoundary 返回哈希链按时间清理的边界：第一条 create_time 不早于 before 的日志ID
+// 该ID之前的日志全部可清理，之后的日志即使已过期也保留，保证清理后剩余的日志仍是一条完整的链
+func (d *LogManagerDAO) GetChainPurgeBoundary(ctx context.Context, before int64) (int64, error) {
+	value, err := d.db.Model(d.tableName).Ctx(ctx).Where("create_time >= ?", before).Min("id")
+	if err != nil {
+		return 0, err
+	}
+	if value > 0 {
+		return int64(value), nil
+	}
+
+	// 全部日志均已过期
+	value, err = d.db.Model(d.tableName).Ctx(ctx).Max("id")
+	if err != nil {
+		return 0, err
+	}
+	return int64(value) + 1, nil
+}
+
+// CountTable 统计整张表的日志数量
+func (d *LogManagerDAO) CountTable(ctx context.Context, table string) (int, error) {
+	return d.db.Model(table).Ctx(ctx).Count()
+}
@@ -0,0 +1,89 @@
+package LogModule
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// FieldChange 字段变更，Field 为以 . 分隔的 JSON 字段路径，如 address.city
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// Diff 比较两个结构体(或 map)按 JSON 序列化后的字段差异，结果按字段路径排序
+// 嵌套对象逐字段比较，数组整体比较；before 为 nil 表示新建，after 为 nil 表示删除
+// 字段名取 json tag，json:"-" 的字段(如密码)不参与比较
+func Diff(before interface{}, after interface{}) (changes []*FieldChange, err error) {
+	beforeValue, err := toJSONValue(before)
+	if err != nil {
+		return nil, err
+	}
+	afterValue, err := toJSONValue(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes = make([]*FieldChange, 0)
+	diffValue("", beforeValue, afterValue, &changes)
+	return changes, nil
+}
+
+// toJSONValue 转换为 JSON 反序列化后的通用结构，数字保留原始精度
+func toJSONValue(in interface{}) (out interface{}, err error) {
+	if in == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err = decoder.Decode(&out)
+	return out, err
+}
+
+func diffValue(path string, before interface{}, after interface{}, changes *[]*FieldChange) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+
+	// 新建/删除时展开对象的各个字段
+	if beforeIsMap && after == nil {
+		afterMap, afterIsMap = map[string]interface{}{}, true
+	}
+	if afterIsMap && before == nil {
+		beforeMap, beforeIsMap = map[string]interface{}{}, true
+	}
+
+	if !beforeIsMap || !afterIsMap {
+		if !reflect.DeepEqual(before, after) {
+			*changes = append(*changes, &FieldChange{Field: path, Before: before, After: after})
+		}
+		return
+	}
+
+	keys := make([]string, 0, len(beforeMap)+len(afterMap))
+	for key := range beforeMap {
+		keys = append(keys, key)
+	}
+	for key := range afterMap {
+		if _, ok := beforeMap[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field := key
+		if path != "" {
+			field = path + "." + key
+		}
+		diffValue(field, beforeMap[key], afterMap[key], changes)
+	}
+}
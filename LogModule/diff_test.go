@@ -0,0 +1,45 @@
+package LogModule
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_Diff(t *testing.T) {
+	type address struct {
+		City   string `json:"city"`
+		Street string `json:"street"`
+	}
+	type company struct {
+		Name     string   `json:"name"`
+		Capital  int64    `json:"capital"`
+		Tags     []string `json:"tags"`
+		Address  address  `json:"address"`
+		Password string   `json:"-"`
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		before := &company{Name: "a", Capital: 100, Tags: []string{"x"}, Address: address{City: "bj", Street: "s1"}, Password: "p1"}
+		after := &company{Name: "a", Capital: 200, Tags: []string{"x", "y"}, Address: address{City: "sh", Street: "s1"}, Password: "p2"}
+
+		changes, err := Diff(before, after)
+		t.AssertNil(err)
+		t.Assert(len(changes), 3)
+		t.Assert(changes[0].Field, "address.city")
+		t.Assert(changes[1].Field, "capital")
+		t.Assert(changes[1].Before, json.Number("100"))
+		t.Assert(changes[1].After, json.Number("200"))
+		t.Assert(changes[2].Field, "tags")
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		changes, err := Diff(nil, &address{City: "bj"})
+		t.AssertNil(err)
+		t.Assert(len(changes), 2)
+		t.Assert(changes[0].Field, "city")
+		t.AssertNil(changes[0].Before)
+		t.Assert(changes[0].After, "bj")
+	})
+}
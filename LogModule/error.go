@@ -0,0 +1,21 @@
+package LogModule
+
+import "github.com/gogf/gf/v2/errors/gerror"
+
+var (
+	ErrWriterClosed = gerror.New("日志写入器已关闭")
+	ErrQueueFull    = gerror.New("日志写入队列已满")
+
+	ErrInvalidCursor = gerror.New("分页游标不合法")
+
+	ErrHashChainDisabled = gerror.New("未开启防篡改哈希链")
+
+	ErrUnsupportedExportFormat = gerror.New("不支持的日志导出格式")
+	ErrFileManagerRequired     = gerror.New("未配置文件管理器，无法导出为文件")
+
+	ErrInvalidDefinition   = gerror.New("模块/动作声明不合法，编码必须大于0且名称不能为空")
+	ErrDuplicateDefinition = gerror.New("模块/动作编码已注册")
+	ErrUnknownModule       = gerror.New("未注册的日志模块")
+	ErrUnknownAction       = gerror.New("未注册的日志动作")
+	ErrInvalidDetail       = gerror.New("日志详情不符合声明的结构")
+)
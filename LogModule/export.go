@@ -0,0 +1,247 @@
+package LogModule
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yyboo586/common/FileModule"
+)
+
+// ExportFormat 日志导出格式
+type ExportFormat string
+
+const (
+	ExportCSV    ExportFormat = "csv"    // CSV，首行为表头
+	ExportNDJSON ExportFormat = "ndjson" // 每行一个 JSON 对象
+)
+
+// exportBatchSize 导出时每次查询的日志条数
+const exportBatchSize = 500
+
+// exportColumns CSV 表头
+var exportColumns = []string{
+	"id", "create_time", "module", "module_name", "action", "action_name", "result", "message",
+	"operator_id", "ip", "target_type", "target_id", "request_id", "user_agent", "detail", "diff",
+}
+
+// ExportRecord 导出的一行日志，模块与动作的显示名称见 LogItem.ModuleName、LogItem.ActionName
+//
+// Deprecated: 使用 LogItem
+type ExportRecord = LogItem
+
+// ExportCallback 异步导出完成回调，info 为导出生成的文件
+type ExportCallback func(ctx context.Context, info *FileModule.FileInfo, err error)
+
+// Export 将满足条件的日志按 (create_time, id) 倒序流式写入 w，忽略分页参数，模块与动作名称按 filter.Lang 解析
+// 按游标分批查询，每批写出后立即刷新，不在内存中缓存全部日志；设置了 Cursor 时从游标之后开始导出
+func (m *LogManager) Export(ctx context.Context, filter *LogListFilter, format ExportFormat, w io.Writer) error {
+	encoder, err := newExportEncoder(format, w)
+	if err != nil {
+		return err
+	}
+
+	query := &LogListFilter{}
+	if filter != nil {
+		*query = *filter
+	}
+	query.Page = 1
+	query.Size = exportBatchSize
+
+	for {
+		entities, err := m.dao.List(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		for _, entity := range entities {
+			item := ConvertLogItem(entity)
+			if query.Lang != "" {
+				item.resolveNames(query.Lang)
+			}
+			err = encoder.Encode(item)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = encoder.Flush()
+		if err != nil {
+			return err
+		}
+
+		if len(entities) < exportBatchSize {
+			return nil
+		}
+		query.Cursor = ConvertLogItem(entities[len(entities)-1]).Cursor()
+	}
+}
+
+// ExportAsync 异步导出日志：后台通过 FileModule 上传导出文件，完成后调用 callback
+// meta 为导出文件的上传信息，未指定文件名时按导出时间生成；需要在 Config.FileManager 中配置文件管理器
+// 导出在后台运行，保留 ctx 中的值(如上传人)但不随 ctx 取消；Close 时等待进行中的导出完成，等待超时时取消导出
+func (m *LogManager) ExportAsync(ctx context.Context, filter *LogListFilter, format ExportFormat, meta *FileModule.UploadMeta, callback ExportCallback) error {
+	if m.config.FileManager == nil {
+		return ErrFileManagerRequired
+	}
+	if format != ExportCSV && format != ExportNDJSON {
+		return ErrUnsupportedExportFormat
+	}
+
+	upload := &FileModule.UploadMeta{}
+	if meta != nil {
+		*upload = *meta
+	}
+	if upload.FileName == "" {
+		upload.FileName = fmt.Sprintf("logs-%s.%s", time.Now().Format("20060102150405"), format)
+	}
+	if upload.ContentType == "" {
+		upload.ContentType = exportContentType(format)
+	}
+	upload.Size = 0
+
+	query := &LogListFilter{}
+	if filter != nil {
+		*query = *filter
+	}
+
+	if err := m.exportCtx.Err(); err != nil {
+		return err
+	}
+	exportCtx, cancel := context.WithCancel(m.exportCtx)
+	ctx = &exportContext{Context: exportCtx, values: ctx}
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+
+		reader, writer := io.Pipe()
+		done := make(chan error, 1)
+		go func() {
+			err := m.Export(ctx, query, format, writer)
+			writer.CloseWithError(err)
+			done <- err
+		}()
+
+		info, err := m.config.FileManager.Upload(ctx, reader, upload)
+		// 上传提前失败时结束导出
+		reader.CloseWithError(io.ErrClosedPipe)
+		if exportErr := <-done; exportErr != nil && err == nil {
+			err = exportErr
+		}
+		if err != nil {
+			m.logger.Errorf(ctx, "export logs to file %s failed: %v", upload.FileName, err)
+		}
+
+		if callback != nil {
+			callback(ctx, info, err)
+		}
+	}()
+
+	return nil
+}
+
+// exportContext 异步导出的上下文：取消跟随日志管理器，值取自发起导出的请求
+type exportContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c *exportContext) Value(key any) any {
+	return c.values.Value(key)
+}
+
+func exportContentType(format ExportFormat) string {
+	if format == ExportCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// exportEncoder 导出格式编码器
+type exportEncoder interface {
+	Encode(record *LogItem) error
+	Flush() error
+}
+
+func newExportEncoder(format ExportFormat, w io.Writer) (exportEncoder, error) {
+	switch format {
+	case ExportCSV:
+		writer := csv.NewWriter(w)
+		err := writer.Write(exportColumns)
+		if err != nil {
+			return nil, err
+		}
+		return &csvExportEncoder{writer: writer}, nil
+	case ExportNDJSON:
+		return &ndjsonExportEncoder{encoder: json.NewEncoder(w)}, nil
+	default:
+		return nil, ErrUnsupportedExportFormat
+	}
+}
+
+type csvExportEncoder struct {
+	writer *csv.Writer
+}
+
+func (e *csvExportEncoder) Encode(record *LogItem) error {
+	var detail, diff string
+	if record.Detail != nil {
+		raw, _ := json.Marshal(record.Detail)
+		detail = string(raw)
+	}
+	if len(record.Diff) > 0 {
+		raw, _ := json.Marshal(record.Diff)
+		diff = string(raw)
+	}
+
+	return e.writer.Write([]string{
+		strconv.FormatInt(record.ID, 10),
+		record.CreateTime.Format(time.RFC3339),
+		strconv.Itoa(int(record.Module)),
+		csvSafe(record.ModuleName),
+		strconv.Itoa(int(record.Action)),
+		csvSafe(record.ActionName),
+		strconv.Itoa(int(record.Result)),
+		csvSafe(record.Message),
+		csvSafe(record.OperatorID),
+		csvSafe(record.IP),
+		csvSafe(record.TargetType),
+		csvSafe(record.TargetID),
+		csvSafe(record.RequestID),
+		csvSafe(record.UserAgent),
+		csvSafe(detail),
+		csvSafe(diff),
+	})
+}
+
+func (e *csvExportEncoder) Flush() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+// csvSafe 以公式字符开头的单元格前加单引号，避免导出文件在表格软件中打开时被当作公式执行
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type ndjsonExportEncoder struct {
+	encoder *json.Encoder
+}
+
+func (e *ndjsonExportEncoder) Encode(record *LogItem) error {
+	return e.encoder.Encode(record)
+}
+
+func (e *ndjsonExportEncoder) Flush() error {
+	return nil
+}
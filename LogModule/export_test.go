@@ -0,0 +1,133 @@
+package LogModule
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/test/gtest"
+	"github.com/yyboo586/common/FileModule"
+)
+
+// FileModule 的文件管理器可直接作为导出文件的上传器
+var _ FileUploader = FileModule.IFileManager(nil)
+
+func Test_ExportEncoder(t *testing.T) {
+	createTime := time.Unix(1760000000, 0)
+	record := &LogItem{
+		ID: 7, Module: 1, Action: 2, Message: "=HYPERLINK(\"x\")", Detail: map[string]interface{}{"name": "a"},
+		OperatorID: "u1", Result: LogResultSuccess, CreateTime: createTime,
+		Diff:       []*FieldChange{{Field: "name", Before: "a", After: "b"}},
+		ModuleName: "用户",
+		ActionName: "修改",
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		buffer := &bytes.Buffer{}
+		encoder, err := newExportEncoder(ExportCSV, buffer)
+		t.AssertNil(err)
+		t.AssertNil(encoder.Encode(record))
+		t.AssertNil(encoder.Flush())
+
+		rows, err := csv.NewReader(buffer).ReadAll()
+		t.AssertNil(err)
+		t.Assert(len(rows), 2)
+		t.Assert(rows[0], exportColumns)
+		t.Assert(rows[1][0], "7")
+		t.Assert(rows[1][1], createTime.Format(time.RFC3339))
+		t.Assert(rows[1][3], "用户")
+		t.Assert(rows[1][5], "修改")
+		t.Assert(rows[1][6], "1")
+		t.Assert(rows[1][7], `'=HYPERLINK("x")`)
+		t.Assert(rows[1][14], `{"name":"a"}`)
+		t.Assert(strings.Contains(rows[1][15], `"field":"name"`), true)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		buffer := &bytes.Buffer{}
+		encoder, err := newExportEncoder(ExportNDJSON, buffer)
+		t.AssertNil(err)
+		t.AssertNil(encoder.Encode(record))
+		t.AssertNil(encoder.Encode(record))
+
+		lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+		t.Assert(len(lines), 2)
+		var decoded map[string]interface{}
+		t.AssertNil(json.Unmarshal([]byte(lines[0]), &decoded))
+		t.Assert(decoded["id"], 7)
+		t.Assert(decoded["module_name"], "用户")
+		t.Assert(decoded["action_name"], "修改")
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		_, err := newExportEncoder("xlsx", &bytes.Buffer{})
+		t.Assert(err, ErrUnsupportedExportFormat)
+	})
+}
+
+type exportCtxKey struct{}
+
+func Test_ExportContext(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		parent, cancelParent := context.WithCancel(context.WithValue(context.Background(), exportCtxKey{}, "u1"))
+		manager, cancelManager := context.WithCancel(context.Background())
+		ctx := &exportContext{Context: manager, values: parent}
+
+		// 值取自请求，请求结束不影响导出
+		t.Assert(ctx.Value(exportCtxKey{}), "u1")
+		cancelParent()
+		t.AssertNil(ctx.Err())
+
+		cancelManager()
+		t.Assert(ctx.Err(), context.Canceled)
+	})
+}
+
+func Test_CloseWaitsForExports(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		sink := &memorySink{}
+		writer, err := newAsyncWriter(&Config{QueueSize: 10, MaxBatch: 10, FlushInterval: time.Hour}, sink.sink, glog.New())
+		t.AssertNil(err)
+		m := &LogManager{writer: writer}
+		m.exportCtx, m.exportCancel = context.WithCancel(context.Background())
+
+		// 导出直到被取消才结束，Close 等待超时后取消导出，并且仍然停止写入器
+		exited := make(chan struct{})
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			<-m.exportCtx.Done()
+			close(exited)
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		t.Assert(m.Close(ctx), context.DeadlineExceeded)
+		t.Assert(writer.Write(context.Background(), newTestEntities(1)), ErrWriterClosed)
+
+		select {
+		case <-exited:
+		case <-time.After(5 * time.Second):
+			t.Fatal("export not cancelled after Close timed out")
+		}
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		m := &LogManager{}
+		m.exportCtx, m.exportCancel = context.WithCancel(context.Background())
+
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			time.Sleep(10 * time.Millisecond)
+		}()
+
+		t.AssertNil(m.Close(context.Background()))
+		t.Assert(m.exportCtx.Err(), context.Canceled)
+	})
+}
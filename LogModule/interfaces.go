@@ -2,30 +2,82 @@ package LogModule
 
 import (
 	"context"
+	"io"
 	"time"
+
+	"github.com/yyboo586/common/FileModule"
 )
 
+// FileUploader 上传导出文件，FileModule.IFileManager 满足该接口
+type FileUploader interface {
+	Upload(ctx context.Context, r io.Reader, meta *FileModule.UploadMeta) (out *FileModule.FileInfo, err error)
+}
+
 type ILogManager interface {
 	// EnsureTable 确保日志表存在
 	EnsureTable() error
 
-	// Write 写入单条日志
+	// BatchWrite 批量写入日志，开启 AsyncWrite 时入队后立即返回，队列已满且有日志被丢弃时返回 ErrQueueFull
+	// 注册了模块/动作时校验编码与详情结构，任意一条不合法时整批拒绝
 	BatchWrite(ctx context.Context, in []*LogItem) (err error)
 
-	// List 查询日志
+	// List 查询日志，按创建时间、ID倒序
 	List(ctx context.Context, filter *LogListFilter) (out []*LogItem, err error)
+
+	// Count 统计满足条件的日志数量(忽略分页与游标)
+	Count(ctx context.Context, filter *LogListFilter) (total int, err error)
+
+	// Export 将满足条件的日志流式导出为 CSV 或 NDJSON，忽略分页参数
+	Export(ctx context.Context, filter *LogListFilter, format ExportFormat, w io.Writer) (err error)
+
+	// ExportAsync 后台导出日志并通过 FileModule 上传为文件，完成后调用 callback
+	ExportAsync(ctx context.Context, filter *LogListFilter, format ExportFormat, meta *FileModule.UploadMeta, callback ExportCallback) (err error)
+
+	// RegisterModuleName 注册业务模块的显示名称
+	//
+	// Deprecated: 使用 RegisterModule 声明模块
+	RegisterModuleName(module LogModule, name string)
+
+	// RegisterActionName 注册业务动作的显示名称
+	//
+	// Deprecated: 使用 RegisterAction 声明动作
+	RegisterActionName(action LogAction, name string)
+
+	// Verify 校验日志ID在 [fromID, toID] 范围内的防篡改哈希链，toID 为 0 表示校验到链尾
+	Verify(ctx context.Context, fromID int64, toID int64) (result *VerifyResult, err error)
+
+	// Checkpoint 为当前链尾生成签名检查点
+	Checkpoint(ctx context.Context) (checkpoint *LogCheckpoint, err error)
+
+	// Purge 按保留策略清理过期日志，返回清理的日志条数
+	Purge(ctx context.Context) (total int, err error)
+
+	// Close 关闭日志管理器，异步写入时写完队列中的日志
+	Close(ctx context.Context) (err error)
 }
 
 // LogListFilter 日志查询条件
 // 支持按模块、动作、时间范围分页查询，分页采用 Size/Offset
+// 翻阅大量数据时使用游标分页：Cursor 设置为上一页最后一条日志的 LogItem.Cursor()，此时忽略 Page
 type LogListFilter struct {
 	Module     LogModule
 	Action     LogAction
 	OperatorID string
 
+	// TargetType/TargetID 操作对象，TargetID 通常与 TargetType 一起使用
+	TargetType string
+	TargetID   string
+	RequestID  string
+	Result     LogResult
+
 	StartTime time.Time
 	EndTime   time.Time
 
 	Page int
 	Size int
+
+	Cursor string
+
+	// Lang 返回结果中模块、动作显示名称的语言，为空时使用默认名称
+	Lang string
 }
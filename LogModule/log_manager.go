@@ -2,14 +2,33 @@ package LogModule
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
 
 	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/gctx"
+	"github.com/gogf/gf/v2/os/glog"
 )
 
 // LogManager 日志模块核心实现
 // 负责初始化 DAO、提供批量写入与查询能力
 type LogManager struct {
-	dao *LogManagerDAO
+	config *Config
+	logger *glog.Logger
+	dao    *LogManagerDAO
+
+	// writer 异步写入器，未开启 AsyncWrite 时为空
+	writer *asyncWriter
+
+	// cancel 停止后台任务(哈希链检查点、过期日志清理)
+	cancel context.CancelFunc
+	// exportCtx 异步导出的上下文，Close 等待超时后通过 exportCancel 取消进行中的导出
+	exportCtx    context.Context
+	exportCancel context.CancelFunc
+	// wg 后台任务与进行中的异步导出
+	wg sync.WaitGroup
 }
 
 // NewLogManager 创建日志管理器实例
@@ -21,17 +40,79 @@ func NewLogManager(config *Config) (ILogManager, error) {
 	if config.DSN == "" {
 		return nil, gerror.New("LogModule config DSN is required")
 	}
+	if config.EnableHashChain && config.CheckpointKey == "" {
+		return nil, gerror.New("LogModule config CheckpointKey is required when EnableHashChain is set")
+	}
+	if config.EnableHashChain && config.Partition {
+		return nil, gerror.New("LogModule config Partition cannot be used with EnableHashChain")
+	}
+	if config.EnableHashChain && len(config.Retention) > 0 {
+		// 哈希链只能从链头整体截断，无法按模块清理
+		return nil, gerror.New("LogModule config Retention cannot be used with EnableHashChain, use DefaultRetention instead")
+	}
 
 	dao, err := newLogManagerDAO(context.Background(), config)
 	if err != nil {
 		return nil, err
 	}
 
-	manager := &LogManager{dao: dao}
+	logger := glog.New()
+	if config.EnableDebug {
+		logger.SetLevel(glog.LEVEL_ALL)
+	} else {
+		logger.SetLevel(glog.LEVEL_ERRO)
+	}
+	logger.SetPrefix(fmt.Sprintf("[LogManager:%s]", config.TableName))
+	logger.SetTimeFormat(time.DateTime)
+	logger.SetWriter(os.Stdout)
+
+	manager := &LogManager{config: config, logger: logger, dao: dao}
 	if err = manager.EnsureTable(); err != nil {
 		return nil, err
 	}
 
+	if config.AsyncWrite {
+		if config.QueueSize <= 0 {
+			config.QueueSize = 10000
+		}
+		if config.FlushInterval <= 0 {
+			config.FlushInterval = time.Second
+		}
+
+		manager.writer, err = newAsyncWriter(config, dao.BatchCreate, logger)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	manager.cancel = cancel
+	manager.exportCtx, manager.exportCancel = context.WithCancel(context.Background())
+
+	if config.EnableHashChain {
+		if config.CheckpointInterval == 0 {
+			config.CheckpointInterval = time.Hour
+		}
+		if config.CheckpointInterval > 0 {
+			manager.startTask(ctx, config.CheckpointInterval, "create log checkpoint", func(ctx context.Context) error {
+				_, err := manager.Checkpoint(ctx)
+				return err
+			})
+		}
+	}
+
+	if config.DefaultRetention > 0 || len(config.Retention) > 0 {
+		if config.PurgeInterval == 0 {
+			config.PurgeInterval = time.Hour
+		}
+		if config.PurgeInterval > 0 {
+			manager.startTask(ctx, config.PurgeInterval, "purge expired logs", func(ctx context.Context) error {
+				_, err := manager.Purge(ctx)
+				return err
+			})
+		}
+	}
+
 	return manager, nil
 }
 
@@ -44,10 +125,26 @@ func (m *LogManager) BatchWrite(ctx context.Context, in []*LogItem) error {
 		return nil
 	}
 
+	// 任意一条日志校验失败时整批拒绝
+	for _, item := range in {
+		if item == nil {
+			continue
+		}
+		if err := validateLogItem(item); err != nil {
+			return err
+		}
+	}
+
+	// 未指定请求ID时使用上下文中的链路追踪ID
+	requestID := gctx.CtxId(ctx)
+
 	entities := make([]*LogEntity, 0, len(in))
 	for _, item := range in {
 		entity := NewLogEntityFromItem(item)
 		if entity != nil {
+			if entity.RequestID == "" {
+				entity.RequestID = truncateRunes(requestID, maxRequestIDLength)
+			}
 			entities = append(entities, entity)
 		}
 	}
@@ -56,9 +153,66 @@ func (m *LogManager) BatchWrite(ctx context.Context, in []*LogItem) error {
 		return nil
 	}
 
+	if m.writer != nil {
+		return m.writer.Write(ctx, entities)
+	}
 	return m.dao.BatchCreate(ctx, entities)
 }
 
+// startTask 启动后台任务，每隔 interval 执行一次，直到 ctx 结束
+func (m *LogManager) startTask(ctx context.Context, interval time.Duration, name string, run func(ctx context.Context) error) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := run(ctx)
+				if err != nil {
+					m.logger.Errorf(ctx, "%s failed: %v", name, err)
+				}
+			}
+		}
+	}()
+}
+
+// Close 关闭日志管理器：停止后台任务，等待进行中的异步导出完成，异步写入时等待队列中的日志写库
+// ctx 结束时不再等待，并取消进行中的导出
+func (m *LogManager) Close(ctx context.Context) error {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if m.exportCancel != nil {
+		defer m.exportCancel()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	// 等待超时时也停止写入器，不再接收日志，后台继续写完队列中的日志
+	if m.writer != nil {
+		if closeErr := m.writer.Close(ctx); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
 func (m *LogManager) List(ctx context.Context, filter *LogListFilter) ([]*LogItem, error) {
 	entities, err := m.dao.List(ctx, filter)
 	if err != nil {
@@ -68,9 +222,16 @@ func (m *LogManager) List(ctx context.Context, filter *LogListFilter) ([]*LogIte
 	items := make([]*LogItem, 0, len(entities))
 	for _, entity := range entities {
 		if item := ConvertLogItem(entity); item != nil {
+			if filter.Lang != "" {
+				item.resolveNames(filter.Lang)
+			}
 			items = append(items, item)
 		}
 	}
 
 	return items, nil
 }
+
+func (m *LogManager) Count(ctx context.Context, filter *LogListFilter) (int, error) {
+	return m.dao.Count(ctx, filter)
+}
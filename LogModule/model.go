@@ -1,21 +1,42 @@
 package LogModule
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"time"
 )
 
 // LogModule 日志所属业务模块
 // 使用 int 类型方便在数据库中直接存储
-// 业务可自行定义枚举常量
+// 业务可自行定义枚举常量，并通过 RegisterModule 声明显示名称
 type LogModule int
 
 // LogAction 日志所属业务动作
-// 不同的动作可以区分新增、修改、删除等操作
+// 不同的动作可以区分新增、修改、删除等操作，通过 RegisterAction 声明显示名称与详情结构
 type LogAction int
 
+// LogResult 操作结果
+type LogResult int
+
+const (
+	LogResultUnknown LogResult = iota // 未记录
+	LogResultSuccess                  // 成功
+	LogResultFailure                  // 失败
+)
+
+// 字符串字段长度(与建表语句一致)，写库前截断，避免整批写入因个别超长字段失败
+const (
+	maxMessageLength    = 255 // message
+	maxOperatorIDLength = 40  // operator_id
+	maxIPLength         = 64  // ip
+	maxUserAgentLength  = 255 // user_agent
+	maxTargetLength     = 64  // target_type、target_id
+	maxRequestIDLength  = 64  // request_id
+)
+
 // LogEntity 数据库实体
-// Detail 存储 JSON 字符串
+// Detail、Diff 存储 JSON 字符串
 type LogEntity struct {
 	ID      int64  `json:"id"`
 	Module  int    `json:"module"`
@@ -26,11 +47,22 @@ type LogEntity struct {
 	OperatorID string `json:"operator_id"`
 	IP         string `json:"ip"`
 
+	TargetType string `json:"target_type"`
+	TargetID   string `json:"target_id"`
+	RequestID  string `json:"request_id"`
+	UserAgent  string `json:"user_agent"`
+	Result     int    `json:"result"`
+	Diff       string `json:"diff"`
+
+	Hash     string `json:"hash"`
+	PrevHash string `json:"prev_hash"`
+
 	CreateTime int64 `json:"create_time"`
 }
 
 // LogItem 对外展示结构
 // Detail 保留 interface{} 便于业务直接使用反序列化后的结果
+// Diff 为操作对象变更前后的字段差异，可通过 Diff 函数计算
 type LogItem struct {
 	ID      int64       `json:"id"`
 	Module  LogModule   `json:"module"`
@@ -38,11 +70,46 @@ type LogItem struct {
 	Message string      `json:"message"`
 	Detail  interface{} `json:"detail"`
 
-	OperatorID string    `json:"operator_id"`
-	IP         string    `json:"ip"`
+	// ModuleName/ActionName 注册的显示名称，查询时填充，写入时忽略
+	ModuleName string `json:"module_name"`
+	ActionName string `json:"action_name"`
+
+	OperatorID string `json:"operator_id"`
+	IP         string `json:"ip"`
+
+	TargetType string         `json:"target_type"`
+	TargetID   string         `json:"target_id"`
+	RequestID  string         `json:"request_id"`
+	UserAgent  string         `json:"user_agent"`
+	Result     LogResult      `json:"result"`
+	Diff       []*FieldChange `json:"diff"`
+
+	// Hash 开启防篡改哈希链时的行哈希
+	Hash string `json:"hash,omitempty"`
+
 	CreateTime time.Time `json:"create_time"`
 }
 
+// Cursor 返回以该日志为上一页最后一条的分页游标，用于 LogListFilter.Cursor
+func (i *LogItem) Cursor() string {
+	raw := fmt.Sprintf("%d_%d", i.CreateTime.Unix(), i.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// parseCursor 解析分页游标，返回上一页最后一条日志的创建时间(秒)与ID
+func parseCursor(cursor string) (createTime int64, id int64, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+
+	_, err = fmt.Sscanf(string(raw), "%d_%d", &createTime, &id)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	return createTime, id, nil
+}
+
 // ConvertLogItem 将数据库实体转换为业务结构
 func ConvertLogItem(in *LogEntity) (out *LogItem) {
 	if in == nil {
@@ -56,16 +123,32 @@ func ConvertLogItem(in *LogEntity) (out *LogItem) {
 		Message:    in.Message,
 		OperatorID: in.OperatorID,
 		IP:         in.IP,
+		TargetType: in.TargetType,
+		TargetID:   in.TargetID,
+		RequestID:  in.RequestID,
+		UserAgent:  in.UserAgent,
+		Result:     LogResult(in.Result),
+		Hash:       in.Hash,
 		CreateTime: time.Unix(in.CreateTime, 0),
 	}
 
 	if in.Detail != "" {
 		_ = json.Unmarshal([]byte(in.Detail), &out.Detail)
 	}
+	if in.Diff != "" {
+		_ = json.Unmarshal([]byte(in.Diff), &out.Diff)
+	}
+	out.resolveNames("")
 
 	return out
 }
 
+// resolveNames 填充模块、动作在指定语言下的显示名称
+func (i *LogItem) resolveNames(lang string) {
+	i.ModuleName = i.Module.DisplayName(lang)
+	i.ActionName = i.Action.DisplayName(lang)
+}
+
 // NewLogEntityFromItem 根据业务结构构建数据库实体
 // Detail 会序列化为 JSON 字符串，CreateTime 默认为当前时间
 func NewLogEntityFromItem(in *LogItem) *LogEntity {
@@ -79,13 +162,78 @@ func NewLogEntityFromItem(in *LogItem) *LogEntity {
 		createTime = time.Now()
 	}
 
+	var diff string
+	if len(in.Diff) > 0 {
+		diffBytes, _ := json.Marshal(in.Diff)
+		diff = string(diffBytes)
+	}
+
 	return &LogEntity{
 		Module:     int(in.Module),
 		Action:     int(in.Action),
-		Message:    in.Message,
+		Message:    truncateRunes(in.Message, maxMessageLength),
 		Detail:     string(detailBytes),
-		OperatorID: in.OperatorID,
-		IP:         in.IP,
+		OperatorID: truncateRunes(in.OperatorID, maxOperatorIDLength),
+		IP:         truncateRunes(in.IP, maxIPLength),
+		TargetType: truncateRunes(in.TargetType, maxTargetLength),
+		TargetID:   truncateRunes(in.TargetID, maxTargetLength),
+		RequestID:  truncateRunes(in.RequestID, maxRequestIDLength),
+		UserAgent:  truncateRunes(in.UserAgent, maxUserAgentLength),
+		Result:     int(in.Result),
+		Diff:       diff,
 		CreateTime: createTime.Unix(),
 	}
 }
+
+// truncateRunes 按字符截断字符串，不会截断多字节字符
+func truncateRunes(s string, max int) string {
+	if runes := []rune(s); len(runes) > max {
+		return string(runes[:max])
+	}
+	return s
+}
+
+// LogCheckpointEntity 哈希链检查点实体
+type LogCheckpointEntity struct {
+	ID         int64  `json:"id"`
+	LogID      int64  `json:"log_id"`
+	Hash       string `json:"hash"`
+	Signature  string `json:"signature"`
+	CreateTime int64  `json:"create_time"`
+}
+
+// LogCheckpoint 哈希链检查点，记录某一时刻链尾的日志ID与哈希并签名
+// 用于发现链尾日志被删除，以及整条链被重新计算
+type LogCheckpoint struct {
+	ID         int64     `json:"id"`
+	LogID      int64     `json:"log_id"`
+	Hash       string    `json:"hash"`
+	Signature  string    `json:"signature"`
+	CreateTime time.Time `json:"create_time"`
+}
+
+func ConvertLogCheckpoint(in *LogCheckpointEntity) *LogCheckpoint {
+	return &LogCheckpoint{
+		ID:         in.ID,
+		LogID:      in.LogID,
+		Hash:       in.Hash,
+		Signature:  in.Signature,
+		CreateTime: time.Unix(in.CreateTime, 0),
+	}
+}
+
+// VerifyResult 哈希链校验结果
+type VerifyResult struct {
+	// Valid 是否校验通过
+	Valid bool `json:"valid"`
+	// Checked 校验的日志行数
+	Checked int `json:"checked"`
+	// Unchained 开启哈希链之前写入(未计算哈希)的日志行数
+	Unchained int `json:"unchained"`
+	// Checkpoints 校验的检查点数量
+	Checkpoints int `json:"checkpoints"`
+	// BrokenID 第一处断链的日志ID(检查点对应的日志不存在时为检查点记录的日志ID)
+	BrokenID int64 `json:"broken_id"`
+	// Reason 断链原因
+	Reason string `json:"reason"`
+}
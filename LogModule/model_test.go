@@ -0,0 +1,46 @@
+package LogModule
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_Cursor(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		item := &LogItem{ID: 42, CreateTime: time.Unix(1760000000, 0)}
+
+		createTime, id, err := parseCursor(item.Cursor())
+		t.AssertNil(err)
+		t.Assert(createTime, 1760000000)
+		t.Assert(id, 42)
+
+		_, _, err = parseCursor("not-a-cursor")
+		t.Assert(err, ErrInvalidCursor)
+	})
+}
+
+func Test_NewLogEntityFromItem_Truncate(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		long := strings.Repeat("日", 100)
+		entity := NewLogEntityFromItem(&LogItem{
+			Message: strings.Repeat("日", 300), OperatorID: long, IP: long,
+			TargetType: long, TargetID: long, RequestID: long, UserAgent: strings.Repeat("a", 300),
+		})
+
+		t.Assert(utf8.RuneCountInString(entity.Message), maxMessageLength)
+		t.Assert(utf8.RuneCountInString(entity.OperatorID), maxOperatorIDLength)
+		t.Assert(utf8.RuneCountInString(entity.IP), maxIPLength)
+
+		t.Assert(utf8.RuneCountInString(entity.TargetType), maxTargetLength)
+		t.Assert(utf8.RuneCountInString(entity.TargetID), maxTargetLength)
+		t.Assert(utf8.RuneCountInString(entity.RequestID), maxRequestIDLength)
+		t.Assert(len(entity.UserAgent), maxUserAgentLength)
+
+		entity = NewLogEntityFromItem(&LogItem{TargetID: "order-1"})
+		t.Assert(entity.TargetID, "order-1")
+	})
+}
@@ -0,0 +1,229 @@
+package LogModule
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+const purgeBatchSize = 1000
+
+// Purge 按保留策略清理过期日志，返回清理的日志条数
+// 配置了 ArchiveDir 时先将日志归档为 gzip 压缩的 NDJSON 文件再删除；
+// 按月分表时整张分表都已超过所有模块的保留期则直接删除分表
+func (m *LogManager) Purge(ctx context.Context) (total int, err error) {
+	now := time.Now()
+	rules, dropBefore := m.retentionRules(now)
+	if len(rules) == 0 {
+		return 0, nil
+	}
+
+	archive := newLogArchive(m.config.ArchiveDir, m.config.TableName, now)
+	defer func() {
+		if closeErr := archive.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	if m.config.EnableHashChain {
+		return m.purgeChain(ctx, dropBefore, archive)
+	}
+
+	tables, err := m.dao.queryTables(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, table := range tables {
+		month, ok := m.dao.partitionMonth(table)
+		if ok && dropBefore > 0 && month.AddDate(0, 1, 0).Unix() <= dropBefore {
+			count, err := m.dropPartition(ctx, table, archive)
+			total += count
+			if err != nil {
+				return total, err
+			}
+			continue
+		}
+
+		for _, rule := range rules {
+			count, err := m.purgeRows(ctx, table, rule, archive)
+			total += count
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// retentionRules 根据保留策略生成清理条件
+// dropBefore 为所有模块中最长保留期对应的时间点，早于该时间的日志全部可清理；存在永久保留的模块时为 0
+func (m *LogManager) retentionRules(now time.Time) (rules []*purgeRule, dropBefore int64) {
+	modules := make([]int, 0, len(m.config.Retention))
+	for module := range m.config.Retention {
+		modules = append(modules, int(module))
+	}
+	slices.Sort(modules)
+
+	keepForever := m.config.DefaultRetention <= 0
+	longest := m.config.DefaultRetention
+	for _, module := range modules {
+		retention := m.config.Retention[LogModule(module)]
+		if retention <= 0 {
+			keepForever = true
+			continue
+		}
+		longest = max(longest, retention)
+		rules = append(rules, &purgeRule{modules: []int{module}, before: now.Add(-retention).Unix()})
+	}
+
+	// 未单独配置的模块使用默认保留期
+	if m.config.DefaultRetention > 0 {
+		rules = append(rules, &purgeRule{modules: modules, exclude: true, before: now.Add(-m.config.DefaultRetention).Unix()})
+	}
+
+	if !keepForever {
+		dropBefore = now.Add(-longest).Unix()
+	}
+	return rules, dropBefore
+}
+
+// purgeChain 哈希链只能从链头整体截断：按 DefaultRetention 清理链头，并删除指向已清理日志的检查点
+func (m *LogManager) purgeChain(ctx context.Context, dropBefore int64, archive *logArchive) (int, error) {
+	if dropBefore <= 0 {
+		return 0, nil
+	}
+
+	boundary, err := m.dao.GetChainPurgeBoundary(ctx, dropBefore)
+	if err != nil {
+		return 0, err
+	}
+
+	total, err := m.purgeRows(ctx, m.config.TableName, &purgeRule{beforeID: boundary}, archive)
+	if err != nil {
+		return total, err
+	}
+	return total, m.dao.DeleteCheckpointsBefore(ctx, boundary)
+}
+
+// purgeRows 分批归档并删除满足清理条件的日志
+func (m *LogManager) purgeRows(ctx context.Context, table string, rule *purgeRule, archive *logArchive) (int, error) {
+	total := 0
+	for {
+		entities, err := m.dao.ListPurgeBatch(ctx, table, rule, purgeBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(entities) == 0 {
+			return total, nil
+		}
+
+		err = archive.Write(table, entities)
+		if err != nil {
+			return total, err
+		}
+
+		ids := make([]int64, 0, len(entities))
+		for _, entity := range entities {
+			ids = append(ids, entity.ID)
+		}
+		err = m.dao.DeleteByIDs(ctx, table, ids)
+		if err != nil {
+			return total, err
+		}
+		total += len(entities)
+
+		if len(entities) < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// dropPartition 归档整张分表后删除
+func (m *LogManager) dropPartition(ctx context.Context, table string, archive *logArchive) (count int, err error) {
+	if archive != nil {
+		count, err = m.purgeRows(ctx, table, &purgeRule{before: time.Now().Unix() + 1}, archive)
+	} else {
+		count, err = m.dao.CountTable(ctx, table)
+	}
+	if err != nil {
+		return count, err
+	}
+
+	m.logger.Infof(ctx, "drop expired log partition %s, %d rows", table, count)
+	return count, m.dao.DropTable(ctx, table)
+}
+
+// logArchive 清理前的日志归档文件，每次清理写入一个 gzip 压缩的 NDJSON 文件，首次写入时创建
+// 每行为一条 LogEntity，额外记录所在的表名
+type logArchive struct {
+	path    string
+	file    *os.File
+	gz      *gzip.Writer
+	encoder *json.Encoder
+}
+
+type archiveRecord struct {
+	Table string `json:"table"`
+	*LogEntity
+}
+
+// newLogArchive dir 为空时不归档，返回 nil
+func newLogArchive(dir string, name string, now time.Time) *logArchive {
+	if dir == "" {
+		return nil
+	}
+	return &logArchive{path: filepath.Join(dir, fmt.Sprintf("%s-%s.ndjson.gz", name, now.Format("20060102-150405")))}
+}
+
+// Write 写入一批日志并落盘，返回成功后才能删除对应的日志
+func (a *logArchive) Write(table string, entities []*LogEntity) error {
+	if a == nil {
+		return nil
+	}
+
+	if a.file == nil {
+		err := os.MkdirAll(filepath.Dir(a.path), 0o755)
+		if err != nil {
+			return fmt.Errorf("failed to create log archive dir: %w", err)
+		}
+
+		a.file, err = os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open log archive: %w", err)
+		}
+		a.gz = gzip.NewWriter(a.file)
+		a.encoder = json.NewEncoder(a.gz)
+	}
+
+	for _, entity := range entities {
+		err := a.encoder.Encode(&archiveRecord{Table: table, LogEntity: entity})
+		if err != nil {
+			return fmt.Errorf("failed to write log archive: %w", err)
+		}
+	}
+
+	err := a.gz.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush log archive: %w", err)
+	}
+	return a.file.Sync()
+}
+
+func (a *logArchive) Close() error {
+	if a == nil || a.file == nil {
+		return nil
+	}
+
+	err := a.gz.Close()
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package LogModule
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_NewLogManager_ChainRetention(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		// 哈希链无法按模块清理，按模块配置保留期时拒绝创建
+		_, err := NewLogManager(&Config{
+			DSN:             "mysql:root:@tcp(127.0.0.1:3306)/log",
+			EnableHashChain: true,
+			CheckpointKey:   "key",
+			Retention:       map[LogModule]time.Duration{1: time.Hour},
+		})
+		t.AssertNE(err, nil)
+	})
+}
+
+func Test_RetentionRules(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	day := 24 * time.Hour
+
+	gtest.C(t, func(t *gtest.T) {
+		m := &LogManager{config: &Config{
+			Retention:        map[LogModule]time.Duration{2: 3 * 365 * day, 1: 180 * day},
+			DefaultRetention: 365 * day,
+		}}
+		rules, dropBefore := m.retentionRules(now)
+		t.Assert(len(rules), 3)
+		t.Assert(rules[0].modules, []int{1})
+		t.Assert(rules[0].before, now.Add(-180*day).Unix())
+		t.Assert(rules[1].modules, []int{2})
+		t.Assert(rules[2].modules, []int{1, 2})
+		t.Assert(rules[2].exclude, true)
+		t.Assert(rules[2].before, now.Add(-365*day).Unix())
+		t.Assert(dropBefore, now.Add(-3*365*day).Unix())
+	})
+
+	// 存在永久保留的模块时不整表删除
+	gtest.C(t, func(t *gtest.T) {
+		m := &LogManager{config: &Config{Retention: map[LogModule]time.Duration{1: 180 * day}}}
+		rules, dropBefore := m.retentionRules(now)
+		t.Assert(len(rules), 1)
+		t.Assert(dropBefore, 0)
+
+		m = &LogManager{config: &Config{Retention: map[LogModule]time.Duration{1: 0}, DefaultRetention: day}}
+		rules, dropBefore = m.retentionRules(now)
+		t.Assert(len(rules), 1)
+		t.Assert(rules[0].modules, []int{1})
+		t.Assert(rules[0].exclude, true)
+		t.Assert(dropBefore, 0)
+	})
+}
+
+func Test_Partition(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		d := &LogManagerDAO{tableName: "t_log"}
+		createTime := time.Date(2026, 10, 18, 12, 0, 0, 0, time.Local).Unix()
+		t.Assert(d.partitionTable(createTime), "t_log_202610")
+
+		month, ok := d.partitionMonth("t_log_202610")
+		t.Assert(ok, true)
+		t.Assert(month.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.Local)), true)
+
+		_, ok = d.partitionMonth("t_log")
+		t.Assert(ok, false)
+		_, ok = d.partitionMonth("t_log_chain")
+		t.Assert(ok, false)
+		_, ok = d.partitionMonth("t_log_checkpoint")
+		t.Assert(ok, false)
+	})
+}
+
+func Test_LogArchive(t *testing.T) {
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(newLogArchive("", "t_log", time.Now()), nil)
+
+		dir := t.TempDir()
+		archive := newLogArchive(dir, "t_log", time.Unix(1760000000, 0))
+		t.AssertNil(archive.Write("t_log_202610", []*LogEntity{{ID: 1, Module: 1}, {ID: 2, Module: 2}}))
+		t.AssertNil(archive.Write("t_log", []*LogEntity{{ID: 3, Module: 1}}))
+		t.AssertNil(archive.Close())
+
+		file, err := os.Open(archive.path)
+		t.AssertNil(err)
+		defer file.Close()
+		reader, err := gzip.NewReader(file)
+		t.AssertNil(err)
+
+		var records []*archiveRecord
+		scanner := bufio.NewScanner(reader)
+		for scanner.Scan() {
+			record := &archiveRecord{}
+			t.AssertNil(json.Unmarshal(scanner.Bytes(), record))
+			records = append(records, record)
+		}
+		t.Assert(len(records), 3)
+		t.Assert(records[0].Table, "t_log_202610")
+		t.Assert(records[0].ID, 1)
+		t.Assert(records[2].Table, "t_log")
+		t.Assert(records[2].ID, 3)
+	})
+}
@@ -0,0 +1,290 @@
+package LogModule
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/gogf/gf/v2/errors/gerror"
+)
+
+// ModuleDefinition 业务模块声明
+type ModuleDefinition struct {
+	Code LogModule
+	// Name 默认显示名称
+	Name string
+	// I18n 各语言的显示名称，键为语言标识，如 "en"、"zh-CN"
+	I18n map[string]string
+
+	// nameOnly 通过 RegisterModuleName 只注册了显示名称，不开启写入校验
+	nameOnly bool
+}
+
+// ActionDefinition 业务动作声明
+type ActionDefinition struct {
+	Code LogAction
+	// Name 默认显示名称
+	Name string
+	// I18n 各语言的显示名称，键为语言标识，如 "en"、"zh-CN"
+	I18n map[string]string
+	// DetailSchema 该动作日志详情的结构约束，为空表示不校验
+	DetailSchema *DetailSchema
+
+	// nameOnly 通过 RegisterActionName 只注册了显示名称，不开启写入校验
+	nameOnly bool
+}
+
+// DetailFieldType 日志详情字段类型
+type DetailFieldType int
+
+const (
+	DetailAny    DetailFieldType = iota // 任意类型
+	DetailString                        // 字符串
+	DetailNumber                        // 数字
+	DetailBool                          // 布尔
+	DetailObject                        // 对象
+	DetailArray                         // 数组
+)
+
+// DetailSchema 日志详情结构约束，Detail 需为 JSON 对象
+// 值为 null 的字段视为不存在
+type DetailSchema struct {
+	// Fields 字段类型
+	Fields map[string]DetailFieldType
+	// Required 必填字段
+	Required []string
+	// AllowUnknown 是否允许 Fields 中未声明的字段
+	AllowUnknown bool
+}
+
+// registry 全局的模块、动作注册表，服务启动时声明
+// 通过 RegisterModule(RegisterAction) 声明了任意模块(动作)后，BatchWrite 拒绝未声明的模块(动作)编码
+var registry = struct {
+	sync.RWMutex
+	modules map[LogModule]*ModuleDefinition
+	actions map[LogAction]*ActionDefinition
+
+	// declaredModules/declaredActions 通过 RegisterModule/RegisterAction 声明的数量
+	declaredModules int
+	declaredActions int
+}{
+	modules: make(map[LogModule]*ModuleDefinition),
+	actions: make(map[LogAction]*ActionDefinition),
+}
+
+// resetRegistry 清空注册表，仅用于测试
+func resetRegistry() {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.modules = make(map[LogModule]*ModuleDefinition)
+	registry.actions = make(map[LogAction]*ActionDefinition)
+	registry.declaredModules = 0
+	registry.declaredActions = 0
+}
+
+// RegisterModule 声明业务模块，编码必须大于0且不能重复声明
+// 覆盖 RegisterModuleName 注册的显示名称
+func RegisterModule(definition *ModuleDefinition) error {
+	if definition == nil || definition.Code <= 0 || definition.Name == "" {
+		return ErrInvalidDefinition
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if existing, ok := registry.modules[definition.Code]; ok && !existing.nameOnly {
+		return gerror.Wrapf(ErrDuplicateDefinition, "module %d", definition.Code)
+	}
+	registry.modules[definition.Code] = definition
+	registry.declaredModules++
+	return nil
+}
+
+// RegisterAction 声明业务动作，编码必须大于0且不能重复声明
+// 覆盖 RegisterActionName 注册的显示名称
+func RegisterAction(definition *ActionDefinition) error {
+	if definition == nil || definition.Code <= 0 || definition.Name == "" {
+		return ErrInvalidDefinition
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if existing, ok := registry.actions[definition.Code]; ok && !existing.nameOnly {
+		return gerror.Wrapf(ErrDuplicateDefinition, "action %d", definition.Code)
+	}
+	registry.actions[definition.Code] = definition
+	registry.declaredActions++
+	return nil
+}
+
+// RegisterModuleName 注册业务模块的默认显示名称，重复注册时覆盖，不开启写入校验
+//
+// Deprecated: 使用 RegisterModule 声明模块
+func (m *LogManager) RegisterModuleName(module LogModule, name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	definition := &ModuleDefinition{Code: module, Name: name, nameOnly: true}
+	if existing, ok := registry.modules[module]; ok {
+		copied := *existing
+		copied.Name = name
+		definition = &copied
+	}
+	registry.modules[module] = definition
+}
+
+// RegisterActionName 注册业务动作的默认显示名称，重复注册时覆盖，不开启写入校验
+//
+// Deprecated: 使用 RegisterAction 声明动作
+func (m *LogManager) RegisterActionName(action LogAction, name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	definition := &ActionDefinition{Code: action, Name: name, nameOnly: true}
+	if existing, ok := registry.actions[action]; ok {
+		copied := *existing
+		copied.Name = name
+		definition = &copied
+	}
+	registry.actions[action] = definition
+}
+
+func lookupModule(module LogModule) *ModuleDefinition {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.modules[module]
+}
+
+func lookupAction(action LogAction) *ActionDefinition {
+	registry.RLock()
+	defer registry.RUnlock()
+	return registry.actions[action]
+}
+
+// displayName 返回指定语言的显示名称，未配置该语言时使用默认名称
+func displayName(name string, i18n map[string]string, lang string) string {
+	if localized, ok := i18n[lang]; ok && localized != "" {
+		return localized
+	}
+	return name
+}
+
+// Registered 模块是否已注册
+func (m LogModule) Registered() bool {
+	return lookupModule(m) != nil
+}
+
+// DisplayName 返回模块在指定语言下的显示名称，lang 为空时使用默认名称，未注册时为空
+func (m LogModule) DisplayName(lang string) string {
+	definition := lookupModule(m)
+	if definition == nil {
+		return ""
+	}
+	return displayName(definition.Name, definition.I18n, lang)
+}
+
+// Registered 动作是否已注册
+func (a LogAction) Registered() bool {
+	return lookupAction(a) != nil
+}
+
+// DisplayName 返回动作在指定语言下的显示名称，lang 为空时使用默认名称，未注册时为空
+func (a LogAction) DisplayName(lang string) string {
+	definition := lookupAction(a)
+	if definition == nil {
+		return ""
+	}
+	return displayName(definition.Name, definition.I18n, lang)
+}
+
+// validateLogItem 按注册表校验日志的模块、动作编码与详情结构
+func validateLogItem(item *LogItem) error {
+	registry.RLock()
+	checkModule := registry.declaredModules > 0
+	checkAction := registry.declaredActions > 0
+	registry.RUnlock()
+
+	if checkModule {
+		module := lookupModule(item.Module)
+		if module == nil || module.nameOnly {
+			return gerror.Wrapf(ErrUnknownModule, "module %d", item.Module)
+		}
+	}
+	if !checkAction {
+		return nil
+	}
+
+	action := lookupAction(item.Action)
+	if action == nil || action.nameOnly {
+		return gerror.Wrapf(ErrUnknownAction, "action %d", item.Action)
+	}
+	if action.DetailSchema != nil {
+		return action.DetailSchema.Validate(item.Detail)
+	}
+	return nil
+}
+
+// Validate 校验日志详情是否满足结构约束
+func (s *DetailSchema) Validate(detail interface{}) error {
+	raw, err := json.Marshal(detail)
+	if err != nil {
+		return gerror.Wrap(ErrInvalidDetail, err.Error())
+	}
+
+	fields := make(map[string]interface{})
+	if string(raw) != "null" {
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if err = decoder.Decode(&fields); err != nil {
+			return gerror.Wrap(ErrInvalidDetail, "detail is not a JSON object")
+		}
+	}
+
+	for _, name := range s.Required {
+		if fields[name] == nil {
+			return gerror.Wrapf(ErrInvalidDetail, "field %s is required", name)
+		}
+	}
+
+	for name, value := range fields {
+		if value == nil {
+			continue
+		}
+
+		typ, ok := s.Fields[name]
+		if !ok {
+			if s.AllowUnknown {
+				continue
+			}
+			return gerror.Wrapf(ErrInvalidDetail, "field %s is not declared", name)
+		}
+		if !detailTypeMatch(typ, value) {
+			return gerror.Wrapf(ErrInvalidDetail, "field %s type mismatch", name)
+		}
+	}
+	return nil
+}
+
+func detailTypeMatch(typ DetailFieldType, value interface{}) bool {
+	switch typ {
+	case DetailString:
+		_, ok := value.(string)
+		return ok
+	case DetailNumber:
+		_, ok := value.(json.Number)
+		return ok
+	case DetailBool:
+		_, ok := value.(bool)
+		return ok
+	case DetailObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	case DetailArray:
+		_, ok := value.([]interface{})
+		return ok
+	default:
+		return true
+	}
+}
@@ -0,0 +1,84 @@
+package LogModule
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_Registry(t *testing.T) {
+	resetRegistry()
+	t.Cleanup(resetRegistry)
+
+	gtest.C(t, func(t *gtest.T) {
+		t.Assert(RegisterModule(&ModuleDefinition{Code: 0, Name: "x"}), ErrInvalidDefinition)
+		t.Assert(RegisterModule(&ModuleDefinition{Code: 901}), ErrInvalidDefinition)
+
+		t.AssertNil(RegisterModule(&ModuleDefinition{Code: 901, Name: "登录", I18n: map[string]string{"en": "Login"}}))
+		t.Assert(errors.Is(RegisterModule(&ModuleDefinition{Code: 901, Name: "登录"}), ErrDuplicateDefinition), true)
+		t.AssertNil(RegisterAction(&ActionDefinition{
+			Code: 902,
+			Name: "修改",
+			DetailSchema: &DetailSchema{
+				Fields:   map[string]DetailFieldType{"name": DetailString, "age": DetailNumber, "tags": DetailArray},
+				Required: []string{"name"},
+			},
+		}))
+
+		t.Assert(LogModule(901).DisplayName(""), "登录")
+		t.Assert(LogModule(901).DisplayName("en"), "Login")
+		t.Assert(LogModule(901).DisplayName("ja"), "登录")
+		t.Assert(LogModule(903).DisplayName(""), "")
+		t.Assert(LogAction(902).Registered(), true)
+
+		item := ConvertLogItem(&LogEntity{Module: 901, Action: 902})
+		t.Assert(item.ModuleName, "登录")
+		t.Assert(item.ActionName, "修改")
+		item.resolveNames("en")
+		t.Assert(item.ModuleName, "Login")
+		t.Assert(item.ActionName, "修改")
+
+		// 注册后拒绝未注册的编码与不符合结构的详情
+		t.AssertNil(validateLogItem(&LogItem{Module: 901, Action: 902, Detail: map[string]interface{}{"name": "a", "age": 3}}))
+		t.Assert(errors.Is(validateLogItem(&LogItem{Module: 903, Action: 902}), ErrUnknownModule), true)
+		t.Assert(errors.Is(validateLogItem(&LogItem{Module: 901, Action: 904}), ErrUnknownAction), true)
+		t.Assert(errors.Is(validateLogItem(&LogItem{Module: 901, Action: 902, Detail: map[string]interface{}{"age": 3}}), ErrInvalidDetail), true)
+		t.Assert(errors.Is(validateLogItem(&LogItem{Module: 901, Action: 902, Detail: map[string]interface{}{"name": 1}}), ErrInvalidDetail), true)
+		t.Assert(errors.Is(validateLogItem(&LogItem{Module: 901, Action: 902, Detail: map[string]interface{}{"name": "a", "x": 1}}), ErrInvalidDetail), true)
+		t.Assert(errors.Is(validateLogItem(&LogItem{Module: 901, Action: 902, Detail: []int{1}}), ErrInvalidDetail), true)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		schema := &DetailSchema{AllowUnknown: true}
+		t.AssertNil(schema.Validate(nil))
+		t.AssertNil(schema.Validate(map[string]interface{}{"x": 1, "y": nil}))
+	})
+}
+
+func Test_RegisterNames(t *testing.T) {
+	resetRegistry()
+	t.Cleanup(resetRegistry)
+
+	gtest.C(t, func(t *gtest.T) {
+		m := &LogManager{}
+		m.RegisterModuleName(1, "用户")
+		m.RegisterActionName(2, "修改")
+		t.Assert(LogModule(1).DisplayName(""), "用户")
+		t.Assert(LogAction(2).DisplayName(""), "修改")
+
+		// 只注册显示名称时不校验编码
+		t.AssertNil(validateLogItem(&LogItem{Module: 3, Action: 4}))
+
+		// 声明覆盖只注册了显示名称的模块，之后按声明校验
+		t.AssertNil(RegisterModule(&ModuleDefinition{Code: 1, Name: "用户", I18n: map[string]string{"en": "User"}}))
+		t.AssertNil(validateLogItem(&LogItem{Module: 1, Action: 4}))
+		t.Assert(errors.Is(validateLogItem(&LogItem{Module: 3, Action: 4}), ErrUnknownModule), true)
+
+		// 修改已声明模块的显示名称时保留多语言名称
+		m.RegisterModuleName(1, "账号")
+		t.Assert(LogModule(1).DisplayName(""), "账号")
+		t.Assert(LogModule(1).DisplayName("en"), "User")
+		t.AssertNil(validateLogItem(&LogItem{Module: 1, Action: 4}))
+	})
+}
@@ -0,0 +1,260 @@
+package LogModule
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gogf/gf/v2/os/glog"
+)
+
+const (
+	spillFileExt     = ".ndjson"
+	spillFilePrefix  = "spill-"
+	deadLetterPrefix = "deadletter-"
+)
+
+// spillStore 溢出日志的落盘存储
+// 日志以 NDJSON 追加写入当前文件；重放时先切换到新文件，再按文件名(创建时间)顺序写库，写库成功后删除文件
+// 数据本身不合法(重试不会成功)的日志移入死信文件，不再重放，由人工处理
+type spillStore struct {
+	dir    string
+	logger *glog.Logger
+
+	mutex sync.Mutex
+	file  *os.File
+}
+
+func newSpillStore(dir string, logger *glog.Logger) (*spillStore, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spill dir: %w", err)
+	}
+	return &spillStore{dir: dir, logger: logger}, nil
+}
+
+// Append 追加写入日志
+func (s *spillStore) Append(entities []*LogEntity) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		file, err := s.openFile(spillFilePrefix)
+		if err != nil {
+			return err
+		}
+		s.file = file
+	}
+	return writeEntities(s.file, entities)
+}
+
+// DeadLetter 将写库被拒绝的日志写入新的死信文件
+func (s *spillStore) DeadLetter(entities []*LogEntity) error {
+	file, err := s.openFile(deadLetterPrefix)
+	if err != nil {
+		return err
+	}
+
+	err = writeEntities(file, entities)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// openFile 以创建时间命名新建文件，文件名按创建时间排序
+func (s *spillStore) openFile(prefix string) (*os.File, error) {
+	name := filepath.Join(s.dir, fmt.Sprintf("%s%d%s", prefix, time.Now().UnixNano(), spillFileExt))
+	return os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+}
+
+// writeEntities 以 NDJSON 格式追加写入日志并刷盘
+func writeEntities(file *os.File, entities []*LogEntity) error {
+	buf := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buf)
+	for _, entity := range entities {
+		if err := encoder.Encode(entity); err != nil {
+			return err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// rotate 关闭当前文件，返回可以重放的全部文件
+func (s *spillStore) rotate() ([]string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file != nil {
+		err := s.file.Close()
+		s.file = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.dir, spillFilePrefix+"*"+spillFileExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Replay 按写入顺序重新写库，遇到临时性的写库失败时停止，未写入的日志保留在文件中
+func (s *spillStore) Replay(ctx context.Context, sink batchSink, maxBatch int) (count int, err error) {
+	files, err := s.rotate()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, name := range files {
+		n, err := s.replayFile(ctx, name, sink, maxBatch)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (s *spillStore) replayFile(ctx context.Context, name string, sink batchSink, maxBatch int) (count int, err error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	// offset 为第一条尚未写库的日志在文件中的位置，ends 为当前批次中每条日志的结束位置
+	var offset int64
+	decoder := json.NewDecoder(file)
+	batch := make([]*LogEntity, 0, maxBatch)
+	ends := make([]int64, 0, maxBatch)
+	for {
+		var entity LogEntity
+		err = decoder.Decode(&entity)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// 进程异常退出可能导致最后一行不完整
+			s.logger.Warningf(ctx, "spill file %s corrupted after offset %d, remaining discarded: %v", name, decoder.InputOffset(), err)
+			break
+		}
+
+		batch = append(batch, &entity)
+		ends = append(ends, decoder.InputOffset())
+		if len(batch) < maxBatch {
+			continue
+		}
+
+		handled, n, err := s.replayBatch(ctx, name, batch, sink)
+		count += n
+		if err != nil {
+			if handled > 0 {
+				offset = ends[handled-1]
+			}
+			return count, s.truncate(name, file, offset, err)
+		}
+		offset = decoder.InputOffset()
+		batch, ends = batch[:0], ends[:0]
+	}
+
+	if len(batch) > 0 {
+		handled, n, err := s.replayBatch(ctx, name, batch, sink)
+		count += n
+		if err != nil {
+			if handled > 0 {
+				offset = ends[handled-1]
+			}
+			return count, s.truncate(name, file, offset, err)
+		}
+	}
+
+	file.Close()
+	return count, os.Remove(name)
+}
+
+// replayBatch 写库一批日志，返回已处理(写库或移入死信)的条数 handled 与写库的行数 count
+// 整批被数据库拒绝时逐条重试，只有不合法的日志移入死信文件；出错时 batch[handled:] 保留在文件中等待下次重放
+func (s *spillStore) replayBatch(ctx context.Context, name string, batch []*LogEntity, sink batchSink) (handled int, count int, err error) {
+	err = sink(ctx, batch)
+	if err == nil {
+		return len(batch), len(batch), nil
+	}
+	if !isPermanentWriteError(err) {
+		return 0, 0, err
+	}
+
+	cause := err
+	handled, rejected, err := writeEachRow(ctx, sink, batch)
+	count = handled - len(rejected)
+	if len(rejected) > 0 {
+		deadErr := s.DeadLetter(rejected)
+		if deadErr != nil {
+			// 保留第一条被拒绝的日志及之后的部分，其后已写库的日志在下次重放时会重复写入
+			for i, entity := range batch {
+				if entity == rejected[0] {
+					return i, count, fmt.Errorf("failed to move rejected logs to dead letter: %w", deadErr)
+				}
+			}
+		}
+		s.logger.Errorf(ctx, "%d spilled logs in %s rejected by database, moved to dead letter: %v", len(rejected), name, cause)
+	}
+	return handled, count, err
+}
+
+// truncate 去掉文件中已写库的部分，避免下次重放重复写入，返回写库错误 cause
+func (s *spillStore) truncate(name string, file *os.File, offset int64, cause error) error {
+	if offset == 0 {
+		return cause
+	}
+
+	_, err := file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	tmp := name + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, file)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	err = os.Rename(tmp, name)
+	if err != nil {
+		return err
+	}
+	return cause
+}
+
+// Close 关闭当前文件，未重放的日志在下次启动后重放
+func (s *spillStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
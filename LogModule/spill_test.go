@@ -0,0 +1,164 @@
+package LogModule
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/gogf/gf/v2/errors/gerror"
+	"github.com/gogf/gf/v2/os/glog"
+	"github.com/gogf/gf/v2/test/gtest"
+)
+
+func Test_IsPermanentWriteError(t *testing.T) {
+	cases := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{name: "data too long", err: &mysql.MySQLError{Number: 1406}, permanent: true},
+		{name: "wrapped duplicate entry", err: gerror.Wrap(&mysql.MySQLError{Number: 1062}, "insert failed"), permanent: true},
+		{name: "deadlock", err: &mysql.MySQLError{Number: 1213}},
+		{name: "connection lost", err: mysql.ErrInvalidConn},
+		{name: "timeout", err: context.DeadlineExceeded},
+		{name: "unknown", err: errors.New("db unavailable")},
+	}
+
+	gtest.C(t, func(t *gtest.T) {
+		for _, c := range cases {
+			if isPermanentWriteError(c.err) != c.permanent {
+				t.Fatalf("%s: permanent %v, want %v", c.name, !c.permanent, c.permanent)
+			}
+		}
+	})
+}
+
+// countLines 统计目录下匹配 pattern 的文件中的日志行数
+func countLines(t *gtest.T, dir, pattern string) (files, lines int) {
+	names, err := filepath.Glob(filepath.Join(dir, pattern))
+	t.AssertNil(err)
+	for _, name := range names {
+		file, err := os.Open(name)
+		t.AssertNil(err)
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if len(scanner.Bytes()) > 0 {
+				lines++
+			}
+		}
+		file.Close()
+	}
+	return len(names), lines
+}
+
+func Test_SpillReplay(t *testing.T) {
+	ctx := context.Background()
+
+	gtest.C(t, func(t *gtest.T) {
+		dir := t.TempDir()
+		spill, err := newSpillStore(dir, glog.New())
+		t.AssertNil(err)
+		t.AssertNil(spill.Append(newTestEntities(5)))
+
+		// 第二批(Action 2、3)因 Action 2 被数据库拒绝，逐条重试后只有 Action 2 移入死信文件，继续重放
+		var written []int
+		sink := func(ctx context.Context, entities []*LogEntity) error {
+			for _, entity := range entities {
+				if entity.Action == 2 {
+					return gerror.Wrap(&mysql.MySQLError{Number: 1406, Message: "Data too long"}, "insert failed")
+				}
+			}
+			for _, entity := range entities {
+				written = append(written, entity.Action)
+			}
+			return nil
+		}
+
+		count, err := spill.Replay(ctx, sink, 2)
+		t.AssertNil(err)
+		t.Assert(count, 4)
+		t.Assert(written, []int{0, 1, 3, 4})
+
+		files, _ := countLines(t, dir, spillFilePrefix+"*")
+		t.Assert(files, 0)
+		files, lines := countLines(t, dir, deadLetterPrefix+"*")
+		t.Assert(files, 1)
+		t.Assert(lines, 1)
+
+		// 死信文件不再重放
+		count, err = spill.Replay(ctx, sink, 2)
+		t.AssertNil(err)
+		t.Assert(count, 0)
+	})
+
+	gtest.C(t, func(t *gtest.T) {
+		dir := t.TempDir()
+		spill, err := newSpillStore(dir, glog.New())
+		t.AssertNil(err)
+		t.AssertNil(spill.Append(newTestEntities(5)))
+
+		// 临时性错误时停止重放，已写入的批次从文件中去掉
+		sink := &memorySink{}
+		calls := 0
+		failing := func(ctx context.Context, entities []*LogEntity) error {
+			calls++
+			if calls > 1 {
+				return errors.New("db unavailable")
+			}
+			return sink.sink(ctx, entities)
+		}
+
+		count, err := spill.Replay(ctx, failing, 2)
+		t.AssertNE(err, nil)
+		t.Assert(count, 2)
+		_, lines := countLines(t, dir, spillFilePrefix+"*")
+		t.Assert(lines, 3)
+		files, _ := countLines(t, dir, deadLetterPrefix+"*")
+		t.Assert(files, 0)
+
+		count, err = spill.Replay(ctx, sink.sink, 2)
+		t.AssertNil(err)
+		t.Assert(count, 3)
+		t.Assert(sink.count(), 5)
+	})
+	gtest.C(t, func(t *gtest.T) {
+		dir := t.TempDir()
+		spill, err := newSpillStore(dir, glog.New())
+		t.AssertNil(err)
+		t.AssertNil(spill.Append(newTestEntities(4)))
+
+		// 逐条重试时遇到临时性错误，已写库和移入死信的日志从文件中去掉，其余保留
+		sink := &memorySink{}
+		unavailable := false
+		failing := func(ctx context.Context, entities []*LogEntity) error {
+			if len(entities) > 1 {
+				return &mysql.MySQLError{Number: 1406, Message: "Data too long"}
+			}
+			switch {
+			case entities[0].Action == 1:
+				return &mysql.MySQLError{Number: 1406, Message: "Data too long"}
+			case entities[0].Action == 2 && !unavailable:
+				unavailable = true
+				return errors.New("db unavailable")
+			}
+			return sink.sink(ctx, entities)
+		}
+
+		count, err := spill.Replay(ctx, failing, 4)
+		t.AssertNE(err, nil)
+		t.Assert(count, 1)
+		_, lines := countLines(t, dir, spillFilePrefix+"*")
+		t.Assert(lines, 2)
+		_, lines = countLines(t, dir, deadLetterPrefix+"*")
+		t.Assert(lines, 1)
+
+		count, err = spill.Replay(ctx, failing, 4)
+		t.AssertNil(err)
+		t.Assert(count, 2)
+		t.Assert(sink.count(), 3)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"slices"
 	"strings"
+	"sync"
 
 	"github.com/gogf/gf/v2/errors/gerror"
 	"github.com/gogf/gf/v2/frame/g"
@@ -14,13 +15,17 @@ import (
 )
 
 var (
-	addr   string
-	client httpUtils.HTTPClient
+	addr     string
+	addrOnce sync.Once
+	client   = httpUtils.NewHTTPClient()
 )
 
-func init() {
-	addr = g.Cfg().MustGet(context.Background(), "gfToken.introspectAddr").String()
-	client = httpUtils.NewHTTPClient()
+// introspectAddr 首次校验令牌时读取令牌校验地址，仅导入本包(如使用上下文用户)时不要求存在配置文件
+func introspectAddr(ctx context.Context) string {
+	addrOnce.Do(func() {
+		addr = g.Cfg().MustGet(ctx, "gfToken.introspectAddr").String()
+	})
+	return addr
 }
 
 func Auth(r *ghttp.Request) {
@@ -58,7 +63,7 @@ func introspectToken(r *ghttp.Request) (out *ContextUser, err error) {
 		"Authorization": "Bearer " + tokenStr,
 	}
 
-	status, respBody, err := client.POST(r.Context(), addr, header, nil)
+	status, respBody, err := client.POST(r.Context(), introspectAddr(r.Context()), header, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,7 @@ module github.com/yyboo586/common
 go 1.23.0
 
 require (
+	github.com/DATA-DOG/go-sqlmock v1.5.2
 	github.com/dgraph-io/badger/v4 v4.8.0
 	github.com/gin-gonic/gin v1.10.0
 	github.com/go-jose/go-jose/v4 v4.0.4
@@ -14,6 +15,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/shopspring/decimal v1.4.0
 	github.com/tiger1103/gfast-token v1.0.10
+	golang.org/x/image v0.25.0
 )
 
 require (